@@ -0,0 +1,132 @@
+package assignment
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"siransbach/taskmanagementapi/tasks"
+)
+
+type DB struct {
+	pg tasks.Session
+}
+
+func NewDB(pg tasks.Session) *DB {
+	return &DB{pg}
+}
+
+// Candidates returns the active employees with their workload, only the
+// members of the project when projectID is set. Tasks due before
+// overdueBefore, or now when zero, count as overdue.
+func (db *DB) Candidates(ctx context.Context, projectID int, overdueBefore time.Time) ([]Candidate, error) {
+	var args []interface{}
+	before := "NOW()"
+	if !overdueBefore.IsZero() {
+		args = append(args, overdueBefore)
+		before = fmt.Sprintf("$%d", len(args))
+	}
+	query := `
+		SELECT users.id, users.username,
+			COUNT(tasks.id) FILTER (WHERE tasks.status <> 'COMPLETED'),
+			COUNT(tasks.id) FILTER (WHERE tasks.status <> 'COMPLETED' AND tasks.due_date < ` + before + `),
+			COALESCE((SELECT array_agg(skill ORDER BY skill) FROM api.user_skills WHERE user_id = users.id), '{}'),
+			(SELECT MAX(changed_at) FROM api.task_assignment_history WHERE user_id = users.id)
+		FROM auth.users
+		LEFT JOIN api.tasks ON tasks.assigned_user_id = users.id
+		WHERE users.role = 'EMPLOYEE' AND users.active`
+	if projectID > 0 {
+		args = append(args, projectID)
+		query += fmt.Sprintf(" AND users.id IN (SELECT user_id FROM api.project_members WHERE project_id = $%d)", len(args))
+	}
+	query += " GROUP BY users.id ORDER BY users.id"
+
+	rows, err := db.pg.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var candidates []Candidate
+	for rows.Next() {
+		var (
+			c              Candidate
+			lastAssignedAt sql.NullTime
+		)
+		if err := rows.Scan(&c.UserID, &c.Username, &c.OpenTasks, &c.OverdueTasks, pq.Array(&c.Skills), &lastAssignedAt); err != nil {
+			return nil, err
+		}
+		if lastAssignedAt.Valid {
+			c.LastAssignedAt = &lastAssignedAt.Time
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// Record keeps why the task went to its assignee.
+func (db *DB) Record(ctx context.Context, taskID int, choice Choice) error {
+	_, err := db.pg.ExecContext(ctx,
+		"INSERT INTO api.task_auto_assignments (task_id, user_id, strategy, reason) VALUES ($1, $2, $3, $4)",
+		taskID, choice.UserID, choice.Strategy, choice.Reason,
+	)
+	return err
+}
+
+// FindOne returns how the task was assigned automatically, sql.ErrNoRows if it
+// was not.
+func (db *DB) FindOne(ctx context.Context, taskID int) (Choice, error) {
+	var choice Choice
+	err := db.pg.QueryRowContext(ctx, `
+		SELECT a.strategy, a.user_id, users.username, a.reason
+		FROM api.task_auto_assignments a
+		JOIN auth.users ON users.id = a.user_id
+		WHERE a.task_id = $1`,
+		taskID,
+	).Scan(&choice.Strategy, &choice.UserID, &choice.Username, &choice.Reason)
+	return choice, err
+}
+
+func (db *DB) Skills(ctx context.Context, userID int) ([]string, error) {
+	skills := []string{}
+	err := db.pg.QueryRowContext(ctx,
+		"SELECT COALESCE(array_agg(skill ORDER BY skill), '{}') FROM api.user_skills WHERE user_id = $1", userID,
+	).Scan(pq.Array(&skills))
+	return skills, err
+}
+
+// SetSkills replaces the skills of the user. Skills are trimmed, and those
+// differing only by case are kept once.
+func (db *DB) SetSkills(ctx context.Context, userID int, skills []string) error {
+	if _, err := db.pg.ExecContext(ctx, "DELETE FROM api.user_skills WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	normalized := NormalizeSkills(skills)
+	if len(normalized) == 0 {
+		return nil
+	}
+	_, err := db.pg.ExecContext(ctx,
+		"INSERT INTO api.user_skills (user_id, skill) SELECT $1, unnest($2::TEXT[])",
+		userID, pq.Array(normalized),
+	)
+	return err
+}
+
+// NormalizeSkills trims the skills and drops empty ones and those repeating an
+// earlier one but for case.
+func NormalizeSkills(skills []string) []string {
+	normalized := []string{}
+	seen := make(map[string]bool, len(skills))
+	for _, skill := range skills {
+		skill = strings.TrimSpace(skill)
+		if skill == "" || seen[strings.ToLower(skill)] {
+			continue
+		}
+		seen[strings.ToLower(skill)] = true
+		normalized = append(normalized, skill)
+	}
+	return normalized
+}
@@ -0,0 +1,182 @@
+package assignment
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+type (
+	// Strategy picks the employee a new task goes to among the candidates.
+	Strategy interface {
+		Name() string
+		// Pick returns the chosen candidate and why it was chosen. It is only
+		// called with candidates.
+		Pick(task Task, candidates []Candidate) (Candidate, string)
+	}
+
+	// Task is what strategies know of the task being assigned.
+	Task struct {
+		ProjectID int
+		Labels    []string
+		DueDate   time.Time
+	}
+
+	// Candidate is an active employee who can be assigned the task, with their
+	// current workload.
+	Candidate struct {
+		UserID       int      `json:"userId"`
+		Username     string   `json:"username"`
+		OpenTasks    int      `json:"openTasks"`
+		OverdueTasks int      `json:"overdueTasks"`
+		Skills       []string `json:"skills"`
+		// LastAssignedAt is when the candidate was last given a task, nil if
+		// never
+		LastAssignedAt *time.Time `json:"lastAssignedAt,omitempty"`
+	}
+
+	// Choice is the outcome of an automatic assignment.
+	Choice struct {
+		Strategy string `json:"strategy"`
+		UserID   int    `json:"userId"`
+		Username string `json:"username"`
+		Reason   string `json:"reason"`
+	}
+
+	roundRobin   struct{}
+	leastOpen    struct{}
+	leastOverdue struct{}
+	skillMatch   struct{}
+)
+
+// ErrNoCandidate is returned when no employee can be assigned the task.
+var ErrNoCandidate = errors.New("no active employee can be assigned the task")
+
+// strategies are the available strategies by name.
+var strategies = map[string]Strategy{}
+
+func init() {
+	for _, s := range []Strategy{roundRobin{}, leastOpen{}, leastOverdue{}, skillMatch{}} {
+		Register(s)
+	}
+}
+
+// Register makes a strategy available by its name, replacing any strategy of
+// the same name.
+func Register(s Strategy) {
+	strategies[s.Name()] = s
+}
+
+func ParseStrategy(str string) (Strategy, error) {
+	for name, s := range strategies {
+		if strings.EqualFold(str, name) {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid strategy: %s", str)
+}
+
+// Pick chooses the employee among the candidates with the strategy.
+func Pick(s Strategy, task Task, candidates []Candidate) (Choice, error) {
+	if len(candidates) == 0 {
+		return Choice{}, ErrNoCandidate
+	}
+	c, reason := s.Pick(task, candidates)
+	return Choice{Strategy: s.Name(), UserID: c.UserID, Username: c.Username, Reason: reason}, nil
+}
+
+func (roundRobin) Name() string { return "roundRobin" }
+
+// Pick takes turns by choosing the candidate who has waited the longest since
+// their last assignment.
+func (roundRobin) Pick(_ Task, candidates []Candidate) (Candidate, string) {
+	c := best(candidates, func(a, b Candidate) bool {
+		if a.LastAssignedAt == nil || b.LastAssignedAt == nil {
+			return a.LastAssignedAt == nil && b.LastAssignedAt != nil
+		}
+		return a.LastAssignedAt.Before(*b.LastAssignedAt)
+	})
+	if c.LastAssignedAt == nil {
+		return c, "next in turn, never assigned a task before"
+	}
+	return c, "next in turn, last assigned a task at " + c.LastAssignedAt.UTC().Format(time.RFC3339)
+}
+
+func (leastOpen) Name() string { return "leastOpen" }
+
+func (leastOpen) Pick(_ Task, candidates []Candidate) (Candidate, string) {
+	c := best(candidates, func(a, b Candidate) bool {
+		if a.OpenTasks != b.OpenTasks {
+			return a.OpenTasks < b.OpenTasks
+		}
+		return a.OverdueTasks < b.OverdueTasks
+	})
+	return c, fmt.Sprintf("fewest open tasks (%d)", c.OpenTasks)
+}
+
+func (leastOverdue) Name() string { return "leastOverdue" }
+
+func (leastOverdue) Pick(_ Task, candidates []Candidate) (Candidate, string) {
+	c := best(candidates, func(a, b Candidate) bool {
+		if a.OverdueTasks != b.OverdueTasks {
+			return a.OverdueTasks < b.OverdueTasks
+		}
+		return a.OpenTasks < b.OpenTasks
+	})
+	return c, fmt.Sprintf("fewest overdue tasks (%d)", c.OverdueTasks)
+}
+
+func (skillMatch) Name() string { return "skillMatch" }
+
+// Pick chooses the candidate whose skills match the most labels of the task,
+// the least busy one among equals. Without any match, it falls back to the
+// candidate with the fewest open tasks.
+func (skillMatch) Pick(task Task, candidates []Candidate) (Candidate, string) {
+	matches := make(map[int][]string, len(candidates))
+	for _, c := range candidates {
+		matches[c.UserID] = matchingSkills(task.Labels, c.Skills)
+	}
+	c := best(candidates, func(a, b Candidate) bool {
+		if len(matches[a.UserID]) != len(matches[b.UserID]) {
+			return len(matches[a.UserID]) > len(matches[b.UserID])
+		}
+		return a.OpenTasks < b.OpenTasks
+	})
+	if len(matches[c.UserID]) == 0 {
+		c, reason := leastOpen{}.Pick(task, candidates)
+		return c, "no skills match the labels, " + reason
+	}
+	return c, fmt.Sprintf("skills match %s, %d open tasks", strings.Join(matches[c.UserID], ", "), c.OpenTasks)
+}
+
+// matchingSkills returns the labels that are among the skills, ignoring case.
+func matchingSkills(labels, skills []string) []string {
+	var matching []string
+	for _, label := range labels {
+		for _, skill := range skills {
+			if strings.EqualFold(label, skill) {
+				matching = append(matching, label)
+				break
+			}
+		}
+	}
+	return matching
+}
+
+// best returns the first candidate by less, the lowest user ID among equals so
+// that picks are deterministic.
+func best(candidates []Candidate, less func(a, b Candidate) bool) Candidate {
+	sorted := append([]Candidate(nil), candidates...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if less(sorted[i], sorted[j]) {
+			return true
+		}
+		if less(sorted[j], sorted[i]) {
+			return false
+		}
+		return sorted[i].UserID < sorted[j].UserID
+	})
+	return sorted[0]
+}
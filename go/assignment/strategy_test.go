@@ -0,0 +1,98 @@
+package assignment
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPick(t *testing.T) {
+	earlier := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	later := earlier.Add(time.Hour)
+	candidates := []Candidate{
+		{UserID: 3, Username: "carol", OpenTasks: 2, OverdueTasks: 0, Skills: []string{"Go"}, LastAssignedAt: &later},
+		{UserID: 1, Username: "alice", OpenTasks: 1, OverdueTasks: 1, Skills: []string{"sql", "go"}, LastAssignedAt: &earlier},
+		{UserID: 2, Username: "bob", OpenTasks: 1, OverdueTasks: 0},
+	}
+
+	cases := []struct {
+		name     string
+		strategy string
+		labels   []string
+		expected Choice
+	}{
+		{
+			name:     "round-robin",
+			strategy: "roundRobin",
+			expected: Choice{Strategy: "roundRobin", UserID: 2, Username: "bob", Reason: "next in turn, never assigned a task before"},
+		},
+		{
+			name:     "least open",
+			strategy: "leastopen",
+			expected: Choice{Strategy: "leastOpen", UserID: 2, Username: "bob", Reason: "fewest open tasks (1)"},
+		},
+		{
+			name:     "least overdue",
+			strategy: "leastOverdue",
+			expected: Choice{Strategy: "leastOverdue", UserID: 2, Username: "bob", Reason: "fewest overdue tasks (0)"},
+		},
+		{
+			name:     "skill match",
+			strategy: "skillMatch",
+			labels:   []string{"GO", "SQL"},
+			expected: Choice{Strategy: "skillMatch", UserID: 1, Username: "alice", Reason: "skills match GO, SQL, 1 open tasks"},
+		},
+		{
+			name:     "skill match tie",
+			strategy: "skillMatch",
+			labels:   []string{"go"},
+			expected: Choice{Strategy: "skillMatch", UserID: 1, Username: "alice", Reason: "skills match go, 1 open tasks"},
+		},
+		{
+			name:     "skill match fallback",
+			strategy: "skillMatch",
+			labels:   []string{"design"},
+			expected: Choice{Strategy: "skillMatch", UserID: 2, Username: "bob", Reason: "no skills match the labels, fewest open tasks (1)"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, err := ParseStrategy(c.strategy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			choice, err := Pick(s, Task{Labels: c.labels}, candidates)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if choice != c.expected {
+				t.Errorf("expected %+v, got %+v", c.expected, choice)
+			}
+		})
+	}
+
+	t.Run("round-robin turn", func(t *testing.T) {
+		choice, _ := Pick(roundRobin{}, Task{}, candidates[:2])
+		if choice.UserID != 1 {
+			t.Errorf("expected user 1, got %d", choice.UserID)
+		}
+	})
+	t.Run("no candidate", func(t *testing.T) {
+		if _, err := Pick(leastOpen{}, Task{}, nil); !errors.Is(err, ErrNoCandidate) {
+			t.Errorf("expected ErrNoCandidate, got %v", err)
+		}
+	})
+	t.Run("invalid strategy", func(t *testing.T) {
+		if _, err := ParseStrategy("random"); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+func TestNormalizeSkills(t *testing.T) {
+	skills := NormalizeSkills([]string{" Go ", "", "go", "SQL", "  "})
+	if expected := []string{"Go", "SQL"}; !reflect.DeepEqual(skills, expected) {
+		t.Errorf("expected %v, got %v", expected, skills)
+	}
+}
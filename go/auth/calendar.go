@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+// calendarTokenSize is the number of random bytes of a calendar token.
+const calendarTokenSize = 32
+
+// SetCalendarToken generates a new calendar token for the user, replacing the
+// previous one. Only a hash of the token is stored, it cannot be retrieved
+// later.
+func (db *DB) SetCalendarToken(ctx context.Context, userID int) (string, error) {
+	b := make([]byte, calendarTokenSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	_, err := db.session.ExecContext(ctx, `
+		INSERT INTO auth.calendar_tokens (user_id, token_hash) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, created_at = NOW()`,
+		userID, hashCalendarToken(token),
+	)
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// DeleteCalendarToken revokes the calendar token of the user, if any.
+func (db *DB) DeleteCalendarToken(ctx context.Context, userID int) error {
+	_, err := db.session.ExecContext(ctx, "DELETE FROM auth.calendar_tokens WHERE user_id = $1", userID)
+	return err
+}
+
+// FindByCalendarToken returns the active user of the calendar token, or
+// sql.ErrNoRows.
+func (db *DB) FindByCalendarToken(ctx context.Context, token string) (*User, error) {
+	var user User
+	err := db.session.QueryRowContext(ctx, "SELECT "+strings.Join(toColumnStrings(allCols), ",")+
+		" FROM auth.users JOIN auth.calendar_tokens ON calendar_tokens.user_id = users.id"+
+		" WHERE calendar_tokens.token_hash = $1 AND users.active",
+		hashCalendarToken(token),
+	).Scan(&user.ID, &user.Username, &user.EncryptedPassword, &user.CreatedAt, &user.Role, &user.Active)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func hashCalendarToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
@@ -17,9 +17,10 @@ type (
 	DBColumn string
 
 	FindOptions struct {
-		IDs      []int
-		Username string
-		Roles    []Role
+		IDs       []int
+		Username  string
+		Usernames []string
+		Roles     []Role
 	}
 )
 
@@ -29,9 +30,10 @@ const (
 	PasswordCol  DBColumn = "users.password"
 	CreatedAtCol DBColumn = "users.created_at"
 	RoleCol      DBColumn = "users.role"
+	ActiveCol    DBColumn = "users.active"
 )
 
-var allCols = []DBColumn{IDCol, UsernameCol, PasswordCol, CreatedAtCol, RoleCol}
+var allCols = []DBColumn{IDCol, UsernameCol, PasswordCol, CreatedAtCol, RoleCol, ActiveCol}
 
 func NewDB(session *sql.DB) *DB {
 	return &DB{session}
@@ -51,7 +53,7 @@ func (db *DB) FindOne(ctx context.Context, options FindOptions) (*User, error) {
 
 	var user User
 	err := db.session.QueryRowContext(ctx, query, args...).Scan(
-		&user.ID, &user.Username, &user.EncryptedPassword, &user.CreatedAt, &user.Role,
+		&user.ID, &user.Username, &user.EncryptedPassword, &user.CreatedAt, &user.Role, &user.Active,
 	)
 	if err != nil {
 		return nil, err
@@ -64,7 +66,7 @@ func (db *DB) scanRows(rows *sql.Rows) ([]*User, error) {
 	for rows.Next() {
 		var user User
 		if err := rows.Scan(
-			&user.ID, &user.Username, &user.EncryptedPassword, &user.CreatedAt, &user.Role,
+			&user.ID, &user.Username, &user.EncryptedPassword, &user.CreatedAt, &user.Role, &user.Active,
 		); err != nil {
 			return nil, err
 		}
@@ -87,6 +89,10 @@ func (opt FindOptions) buildQuery() (query string, args []interface{}) {
 		args = append(args, opt.Username)
 		whereConds = append(whereConds, fmt.Sprintf("username = $%d", len(args)))
 	}
+	if len(opt.Usernames) > 0 {
+		args = append(args, pq.Array(opt.Usernames))
+		whereConds = append(whereConds, fmt.Sprintf("username = ANY($%d)", len(args)))
+	}
 	if len(opt.Roles) > 0 {
 		args = append(args, pq.Array(opt.Roles))
 		whereConds = append(whereConds, fmt.Sprintf("role = ANY($%d)", len(args)))
@@ -111,3 +117,11 @@ func toColumnStrings(cols []DBColumn) []string {
 	}
 	return ret
 }
+
+// Activate lets an offboarded user sign in and be assigned tasks again.
+func (db *DB) Activate(ctx context.Context, id int) error {
+	var activatedID int
+	return db.session.QueryRowContext(ctx,
+		"UPDATE auth.users SET active = TRUE WHERE id = $1 RETURNING id", id,
+	).Scan(&activatedID)
+}
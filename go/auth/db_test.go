@@ -16,7 +16,7 @@ func TestFindOptions_BuildQuery(t *testing.T) {
 		{
 			name:   "no options",
 			option: FindOptions{},
-			query:  "SELECT users.id,users.username,users.password,users.created_at,users.role FROM auth.users",
+			query:  "SELECT users.id,users.username,users.password,users.created_at,users.role,users.active FROM auth.users",
 			args:   []interface{}{},
 		},
 		{
@@ -24,7 +24,7 @@ func TestFindOptions_BuildQuery(t *testing.T) {
 			option: FindOptions{
 				IDs: []int{1, 2},
 			},
-			query: "SELECT users.id,users.username,users.password,users.created_at,users.role FROM auth.users WHERE id = ANY($1)",
+			query: "SELECT users.id,users.username,users.password,users.created_at,users.role,users.active FROM auth.users WHERE id = ANY($1)",
 			args: []interface{}{
 				pq.Array([]int{1, 2}),
 			},
@@ -34,18 +34,28 @@ func TestFindOptions_BuildQuery(t *testing.T) {
 			option: FindOptions{
 				Roles: []Role{RoleEmployee},
 			},
-			query: "SELECT users.id,users.username,users.password,users.created_at,users.role FROM auth.users WHERE role = ANY($1)",
+			query: "SELECT users.id,users.username,users.password,users.created_at,users.role,users.active FROM auth.users WHERE role = ANY($1)",
 			args: []interface{}{
 				pq.Array([]Role{RoleEmployee}),
 			},
 		},
+		{
+			name: "with usernames",
+			option: FindOptions{
+				Usernames: []string{"jdoe", "asmith"},
+			},
+			query: "SELECT users.id,users.username,users.password,users.created_at,users.role,users.active FROM auth.users WHERE username = ANY($1)",
+			args: []interface{}{
+				pq.Array([]string{"jdoe", "asmith"}),
+			},
+		},
 		{
 			name: "with IDs and roles",
 			option: FindOptions{
 				IDs:   []int{1, 2},
 				Roles: []Role{RoleEmployee},
 			},
-			query: "SELECT users.id,users.username,users.password,users.created_at,users.role FROM auth.users WHERE id = ANY($1) AND role = ANY($2)",
+			query: "SELECT users.id,users.username,users.password,users.created_at,users.role,users.active FROM auth.users WHERE id = ANY($1) AND role = ANY($2)",
 			args: []interface{}{
 				pq.Array([]int{1, 2}),
 				pq.Array([]Role{RoleEmployee}),
@@ -14,8 +14,15 @@ import (
 	"siransbach/taskmanagementapi/fiberx"
 )
 
-func NewMiddleware(pg *sql.DB) fiber.Handler {
+// NewMiddleware authenticates the requests with Basic auth, except those whose
+// path starts with one of publicPaths, which authenticate by other means.
+func NewMiddleware(pg *sql.DB, publicPaths ...string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		for _, path := range publicPaths {
+			if strings.HasPrefix(c.Path(), path) {
+				return c.Next()
+			}
+		}
 		auth := c.Get(fiber.HeaderAuthorization)
 		if len(auth) == 0 {
 			log.Error().Msg("error authenticating user: no auth header")
@@ -63,6 +70,9 @@ func authenticate(ctx context.Context, auth string, pg *sql.DB) (*User, error) {
 					[]byte(user.EncryptedPassword), []byte(password)); err != nil {
 					return nil, err
 				}
+				if !user.Active {
+					return nil, errors.New("user is inactive")
+				}
 				return user, nil
 			}
 		}
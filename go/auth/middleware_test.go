@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestNewMiddleware_PublicPaths(t *testing.T) {
+	app := fiber.New()
+	// no database is needed as long as no credentials are checked
+	app.Use(NewMiddleware(nil, "/api/v1/feeds/"))
+	app.Get("/*", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	cases := map[string]int{
+		"/api/v1/feeds/token.ics": fiber.StatusOK,
+		"/api/v1/feeds":           fiber.StatusUnauthorized,
+		"/api/v1/employee/tasks":  fiber.StatusUnauthorized,
+	}
+	for path, status := range cases {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != status {
+			t.Errorf("%s: expected %d, got %d", path, status, resp.StatusCode)
+		}
+	}
+}
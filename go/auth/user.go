@@ -12,6 +12,8 @@ type (
 		EncryptedPassword string `json:"-"`
 		Role              Role   `json:"role"`
 		CreatedAt         string `json:"created_at"`
+		// Active is false once the user is offboarded
+		Active bool `json:"active"`
 	}
 
 	Role string
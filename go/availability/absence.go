@@ -0,0 +1,184 @@
+package availability
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"siransbach/taskmanagementapi/dates"
+)
+
+type (
+	// Absence is a period an employee is away, every day from From to To
+	// included, or only on the weekdays of a part-time absence.
+	Absence struct {
+		ID       int            `json:"id"`
+		UserID   int            `json:"userId"`
+		Kind     Kind           `json:"kind"`
+		From     dates.Date     `json:"from"`
+		To       dates.Date     `json:"to"`
+		Weekdays []time.Weekday `json:"weekdays,omitempty"`
+		// BackupUserID takes the tasks of the employee during the absence, 0 if
+		// nobody does
+		BackupUserID int    `json:"backupUserId,omitempty"`
+		Note         string `json:"note"`
+	}
+
+	Kind string
+
+	// Policy tells what becomes of a task assigned to an employee who is away
+	// on its due date.
+	Policy string
+
+	// Outcome is who a task goes to after applying a policy, and what the
+	// employer should know about it.
+	Outcome struct {
+		UserID int
+		// Absence covers the due date of the task, nil if the employee is
+		// available
+		Absence *Absence
+		Warning string
+	}
+)
+
+const (
+	KindVacation Kind = "VACATION"
+	KindSick     Kind = "SICK"
+	KindPartTime Kind = "PART_TIME"
+)
+
+var Kinds = []Kind{KindVacation, KindSick, KindPartTime}
+
+const (
+	// PolicyWarn assigns the task anyway and warns about the absence.
+	PolicyWarn Policy = "warn"
+	// PolicyRefuse does not assign the task.
+	PolicyRefuse Policy = "refuse"
+	// PolicyDelegate assigns the task to the backup of the absence instead,
+	// or warns when there is none.
+	PolicyDelegate Policy = "delegate"
+)
+
+var Policies = []Policy{PolicyWarn, PolicyRefuse, PolicyDelegate}
+
+// ErrAbsent is returned when refusing to assign a task to an employee who is
+// away on its due date.
+var ErrAbsent = errors.New("assignee is absent on the due date")
+
+func ParseKind(str string) (Kind, error) {
+	for _, k := range Kinds {
+		if strings.EqualFold(str, string(k)) {
+			return k, nil
+		}
+	}
+	return "", fmt.Errorf("invalid absence kind: %s", str)
+}
+
+// ParsePolicy returns the policy named by str, PolicyWarn when empty.
+func ParsePolicy(str string) (Policy, error) {
+	if str == "" {
+		return PolicyWarn, nil
+	}
+	for _, p := range Policies {
+		if strings.EqualFold(str, string(p)) {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("invalid absence policy: %s", str)
+}
+
+func (a Absence) Validate() error {
+	if _, err := ParseKind(string(a.Kind)); err != nil {
+		return err
+	}
+	if a.From.IsZero() || a.To.IsZero() {
+		return errors.New("from and to are required")
+	}
+	if a.To.Before(a.From.Time) {
+		return errors.New("to must not be before from")
+	}
+	if a.Kind == KindPartTime && len(a.Weekdays) == 0 {
+		return errors.New("missing weekdays of the part-time absence")
+	}
+	if a.Kind != KindPartTime && len(a.Weekdays) > 0 {
+		return errors.New("only part-time absences have weekdays")
+	}
+	for _, d := range a.Weekdays {
+		if d < time.Sunday || d > time.Saturday {
+			return fmt.Errorf("invalid weekday: %d", d)
+		}
+	}
+	if a.BackupUserID < 0 || (a.BackupUserID > 0 && a.BackupUserID == a.UserID) {
+		return errors.New("invalid backup user")
+	}
+	return nil
+}
+
+// Covers tells whether the employee is away at t, by the day of t in its
+// location, which callers set to the time zone of the default calendar.
+func (a Absence) Covers(t time.Time) bool {
+	y, m, d := t.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	if day.Before(a.From.Time) || day.After(a.To.Time) {
+		return false
+	}
+	if a.Kind != KindPartTime {
+		return true
+	}
+	for _, w := range a.Weekdays {
+		if w == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func (a Absence) String() string {
+	return fmt.Sprintf("%s from %s to %s", strings.ToLower(strings.ReplaceAll(string(a.Kind), "_", "-")), a.From, a.To)
+}
+
+// Covering returns the first of the absences covering t.
+func Covering(absences []Absence, t time.Time) (Absence, bool) {
+	for _, a := range absences {
+		if a.Covers(t) {
+			return a, true
+		}
+	}
+	return Absence{}, false
+}
+
+// Apply applies the policy to assigning a task due at dueDate to the user,
+// given their absences. A task without due date is always assigned.
+func (p Policy) Apply(userID int, dueDate time.Time, absences []Absence) (Outcome, error) {
+	outcome := Outcome{UserID: userID}
+	if dueDate.IsZero() {
+		return outcome, nil
+	}
+	absence, ok := Covering(absences, dueDate)
+	if !ok {
+		return outcome, nil
+	}
+	outcome.Absence = &absence
+	switch {
+	case p == PolicyRefuse:
+		return Outcome{}, fmt.Errorf("%w: user %d is away (%s)", ErrAbsent, userID, absence)
+	case p == PolicyDelegate && absence.BackupUserID > 0:
+		outcome.UserID = absence.BackupUserID
+		outcome.Warning = fmt.Sprintf("user %d is away (%s), delegated to user %d", userID, absence, absence.BackupUserID)
+	default:
+		outcome.Warning = fmt.Sprintf("user %d is away on the due date (%s)", userID, absence)
+	}
+	return outcome, nil
+}
+
+// DaysOff returns the days from from to to included that the absences cover.
+func DaysOff(absences []Absence, from, to dates.Date) []dates.Date {
+	days := []dates.Date{}
+	for day := from.Time; !day.After(to.Time); day = day.AddDate(0, 0, 1) {
+		if _, ok := Covering(absences, day); ok {
+			days = append(days, dates.Date{Time: day})
+		}
+	}
+	return days
+}
@@ -0,0 +1,139 @@
+package availability
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"siransbach/taskmanagementapi/dates"
+)
+
+func date(t *testing.T, s string) dates.Date {
+	t.Helper()
+	d, err := dates.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestAbsence_Validate(t *testing.T) {
+	valid := Absence{UserID: 1, Kind: KindVacation, From: date(t, "2024-05-06"), To: date(t, "2024-05-10")}
+	cases := []struct {
+		name    string
+		change  func(a *Absence)
+		invalid bool
+	}{
+		{name: "valid", change: func(a *Absence) {}},
+		{name: "invalid kind", change: func(a *Absence) { a.Kind = "LEAVE" }, invalid: true},
+		{name: "missing to", change: func(a *Absence) { a.To = dates.Date{} }, invalid: true},
+		{name: "reversed", change: func(a *Absence) { a.From, a.To = a.To, a.From }, invalid: true},
+		{name: "single day", change: func(a *Absence) { a.To = a.From }},
+		{name: "part-time without weekdays", change: func(a *Absence) { a.Kind = KindPartTime }, invalid: true},
+		{name: "part-time", change: func(a *Absence) { a.Kind, a.Weekdays = KindPartTime, []time.Weekday{time.Friday} }},
+		{name: "vacation with weekdays", change: func(a *Absence) { a.Weekdays = []time.Weekday{time.Friday} }, invalid: true},
+		{name: "invalid weekday", change: func(a *Absence) { a.Kind, a.Weekdays = KindPartTime, []time.Weekday{7} }, invalid: true},
+		{name: "backup", change: func(a *Absence) { a.BackupUserID = 2 }},
+		{name: "own backup", change: func(a *Absence) { a.BackupUserID = 1 }, invalid: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a := valid
+			c.change(&a)
+			if err := a.Validate(); (err != nil) != c.invalid {
+				t.Errorf("expected invalid %t, got %v", c.invalid, err)
+			}
+		})
+	}
+}
+
+func TestAbsence_Covers(t *testing.T) {
+	vacation := Absence{Kind: KindVacation, From: date(t, "2024-05-06"), To: date(t, "2024-05-10")}
+	// Fridays of May
+	partTime := Absence{Kind: KindPartTime, From: date(t, "2024-05-01"), To: date(t, "2024-05-31"), Weekdays: []time.Weekday{time.Friday}}
+	cases := []struct {
+		absence  Absence
+		at       string
+		expected bool
+	}{
+		{vacation, "2024-05-05T23:59:59Z", false},
+		{vacation, "2024-05-06T00:00:00Z", true},
+		{vacation, "2024-05-10T18:00:00Z", true},
+		{vacation, "2024-05-11T00:00:00Z", false},
+		// days are those of the location of the time rather than UTC ones
+		{vacation, "2024-05-11T01:00:00+02:00", false},
+		{vacation, "2024-05-05T23:00:00-02:00", false},
+		{vacation, "2024-05-06T01:00:00+02:00", true},
+		{partTime, "2024-05-10T12:00:00Z", true},
+		{partTime, "2024-05-09T12:00:00Z", false},
+		{partTime, "2024-06-07T12:00:00Z", false},
+	}
+	for _, c := range cases {
+		at, err := time.Parse(time.RFC3339, c.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if covers := c.absence.Covers(at); covers != c.expected {
+			t.Errorf("%s at %s: expected %t, got %t", c.absence, c.at, c.expected, covers)
+		}
+	}
+}
+
+func TestPolicy_Apply(t *testing.T) {
+	absences := []Absence{
+		{UserID: 1, Kind: KindSick, From: date(t, "2024-05-06"), To: date(t, "2024-05-07")},
+		{UserID: 1, Kind: KindVacation, From: date(t, "2024-05-08"), To: date(t, "2024-05-10"), BackupUserID: 2},
+	}
+	sick := time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC)
+	vacation := time.Date(2024, 5, 9, 12, 0, 0, 0, time.UTC)
+	available := time.Date(2024, 5, 13, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name     string
+		policy   Policy
+		dueDate  time.Time
+		userID   int
+		warning  string
+		absentID bool
+		err      error
+	}{
+		{name: "available", policy: PolicyRefuse, dueDate: available, userID: 1},
+		{name: "no due date", policy: PolicyRefuse, userID: 1},
+		{name: "warn", policy: PolicyWarn, dueDate: sick, userID: 1, warning: "user 1 is away on the due date (sick from 2024-05-06 to 2024-05-07)"},
+		{name: "refuse", policy: PolicyRefuse, dueDate: vacation, err: ErrAbsent},
+		{name: "delegate", policy: PolicyDelegate, dueDate: vacation, userID: 2, warning: "user 1 is away (vacation from 2024-05-08 to 2024-05-10), delegated to user 2"},
+		{name: "delegate without backup", policy: PolicyDelegate, dueDate: sick, userID: 1, warning: "user 1 is away on the due date (sick from 2024-05-06 to 2024-05-07)"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			outcome, err := c.policy.Apply(1, c.dueDate, absences)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("expected error %v, got %v", c.err, err)
+			}
+			if outcome.UserID != c.userID || outcome.Warning != c.warning {
+				t.Errorf("expected user %d and warning %q, got %+v", c.userID, c.warning, outcome)
+			}
+		})
+	}
+}
+
+func TestDaysOff(t *testing.T) {
+	absences := []Absence{
+		{Kind: KindVacation, From: date(t, "2024-05-06"), To: date(t, "2024-05-07")},
+		{Kind: KindPartTime, From: date(t, "2024-05-01"), To: date(t, "2024-05-31"), Weekdays: []time.Weekday{time.Friday}},
+	}
+	days := DaysOff(absences, date(t, "2024-05-05"), date(t, "2024-05-12"))
+	var got []string
+	for _, d := range days {
+		got = append(got, d.String())
+	}
+	expected := []string{"2024-05-06", "2024-05-07", "2024-05-10"}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, got)
+		}
+	}
+}
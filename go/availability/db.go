@@ -0,0 +1,136 @@
+package availability
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"siransbach/taskmanagementapi/tasks"
+)
+
+type (
+	DB struct {
+		pg tasks.Session
+	}
+
+	FindOptions struct {
+		IDs     []int
+		UserIDs []int
+		// From and To keep the absences overlapping the days between them,
+		// included, the days being those of their location
+		From time.Time
+		To   time.Time
+	}
+)
+
+const selectColumns = "id,user_id,kind,starts_on,ends_on,weekdays,backup_user_id,note"
+
+func NewDB(pg tasks.Session) *DB {
+	return &DB{pg}
+}
+
+func (db *DB) Find(ctx context.Context, options FindOptions) ([]Absence, error) {
+	query, args := options.buildQuery()
+	rows, err := db.pg.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	absences := []Absence{}
+	for rows.Next() {
+		var (
+			a        Absence
+			weekdays []int64
+			backupID sql.NullInt64
+		)
+		if err := rows.Scan(
+			&a.ID, &a.UserID, &a.Kind, &a.From.Time, &a.To.Time, pq.Array(&weekdays), &backupID, &a.Note,
+		); err != nil {
+			return nil, err
+		}
+		for _, d := range weekdays {
+			a.Weekdays = append(a.Weekdays, time.Weekday(d))
+		}
+		a.BackupUserID = int(backupID.Int64)
+		absences = append(absences, a)
+	}
+	return absences, rows.Err()
+}
+
+// ByUser returns the absences of the users overlapping the days from from to
+// to, by user.
+func (db *DB) ByUser(ctx context.Context, userIDs []int, from, to time.Time) (map[int][]Absence, error) {
+	byUser := make(map[int][]Absence)
+	if len(userIDs) == 0 {
+		return byUser, nil
+	}
+	absences, err := db.Find(ctx, FindOptions{UserIDs: userIDs, From: from, To: to})
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range absences {
+		byUser[a.UserID] = append(byUser[a.UserID], a)
+	}
+	return byUser, nil
+}
+
+func (db *DB) Insert(ctx context.Context, a Absence) (id int, err error) {
+	var backupID sql.NullInt64
+	if a.BackupUserID > 0 {
+		backupID = sql.NullInt64{Int64: int64(a.BackupUserID), Valid: true}
+	}
+	weekdays := make([]int64, 0, len(a.Weekdays))
+	for _, d := range a.Weekdays {
+		weekdays = append(weekdays, int64(d))
+	}
+	err = db.pg.QueryRowContext(ctx, `
+		INSERT INTO api.user_absences (user_id, kind, starts_on, ends_on, weekdays, backup_user_id, note)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`,
+		a.UserID, a.Kind, a.From.String(), a.To.String(), pq.Array(weekdays), backupID, a.Note,
+	).Scan(&id)
+	return id, err
+}
+
+// Delete removes an absence of the user, returning sql.ErrNoRows if the user
+// has no such absence.
+func (db *DB) Delete(ctx context.Context, userID, id int) error {
+	var deletedID int
+	return db.pg.QueryRowContext(ctx,
+		"DELETE FROM api.user_absences WHERE id = $1 AND user_id = $2 RETURNING id", id, userID,
+	).Scan(&deletedID)
+}
+
+func (opt FindOptions) buildQuery() (string, []interface{}) {
+	var (
+		clauses []string
+		args    []interface{}
+	)
+	if len(opt.IDs) > 0 {
+		args = append(args, pq.Array(opt.IDs))
+		clauses = append(clauses, fmt.Sprintf("id = ANY($%d)", len(args)))
+	}
+	if len(opt.UserIDs) > 0 {
+		args = append(args, pq.Array(opt.UserIDs))
+		clauses = append(clauses, fmt.Sprintf("user_id = ANY($%d)", len(args)))
+	}
+	if !opt.From.IsZero() {
+		args = append(args, opt.From.Format(time.DateOnly))
+		clauses = append(clauses, fmt.Sprintf("ends_on >= $%d", len(args)))
+	}
+	if !opt.To.IsZero() {
+		args = append(args, opt.To.Format(time.DateOnly))
+		clauses = append(clauses, fmt.Sprintf("starts_on <= $%d", len(args)))
+	}
+
+	query := "SELECT " + selectColumns + " FROM api.user_absences"
+	if len(clauses) > 0 {
+		query += " WHERE " + strings.Join(clauses, " AND ")
+	}
+	return query + " ORDER BY user_id, starts_on, id", args
+}
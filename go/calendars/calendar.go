@@ -0,0 +1,242 @@
+package calendars
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	// the time zones of calendars, which the runtime image lacks
+	_ "time/tzdata"
+
+	"siransbach/taskmanagementapi/dates"
+)
+
+type (
+	// Calendar is when work happens: the working hours of its workdays, in its
+	// time zone, but on holidays.
+	Calendar struct {
+		ID        int            `json:"id"`
+		Name      string         `json:"name"`
+		TimeZone  string         `json:"timeZone"`
+		Workdays  []time.Weekday `json:"workdays"`
+		WorkStart Clock          `json:"workStart"`
+		WorkEnd   Clock          `json:"workEnd"`
+		// Default is the calendar of due dates not naming one and of overdue
+		// tasks
+		Default  bool      `json:"default"`
+		Holidays []Holiday `json:"holidays"`
+	}
+
+	Holiday struct {
+		Day  dates.Date `json:"day"`
+		Name string     `json:"name"`
+	}
+
+	// Clock is a time of day in minutes from midnight, formatted as HH:MM.
+	Clock int
+
+	// Offset is a due date relative to now, either a number of business days,
+	// due by the end of the working hours of the last one, or of business
+	// hours.
+	Offset struct {
+		BusinessDays  int     `json:"businessDays"`
+		BusinessHours float64 `json:"businessHours"`
+		// Calendar names the calendar to count with, the default one when
+		// empty
+		Calendar string `json:"calendar"`
+	}
+)
+
+// Always is the calendar used when none is the default: every hour of every
+// day is working time, so that tasks are overdue as soon as they are due.
+var Always = Calendar{
+	Name:     "always",
+	TimeZone: "UTC",
+	Workdays: []time.Weekday{
+		time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday,
+	},
+	WorkStart: 0,
+	WorkEnd:   24 * 60,
+	Holidays:  []Holiday{},
+}
+
+// maxSearchDays bounds the days looked through for working time, in case
+// holidays leave none.
+const maxSearchDays = 3660
+
+func ParseClock(str string) (Clock, error) {
+	var h, m int
+	if _, err := fmt.Sscanf(str, "%d:%d", &h, &m); err != nil || len(str) != len("15:04") ||
+		h < 0 || m < 0 || m > 59 || h*60+m > 24*60 {
+		return 0, fmt.Errorf("invalid time of day: %s", str)
+	}
+	return Clock(h*60 + m), nil
+}
+
+func (c Clock) String() string {
+	return fmt.Sprintf("%02d:%02d", int(c)/60, int(c)%60)
+}
+
+func (c Clock) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + c.String() + `"`), nil
+}
+
+func (c *Clock) UnmarshalJSON(b []byte) error {
+	parsed, err := ParseClock(strings.Trim(string(b), `"`))
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+func (cal Calendar) Validate() error {
+	if strings.TrimSpace(cal.Name) == "" {
+		return errors.New("missing name")
+	}
+	if _, err := time.LoadLocation(cal.TimeZone); err != nil || cal.TimeZone == "" {
+		return fmt.Errorf("invalid time zone: %s", cal.TimeZone)
+	}
+	if len(cal.Workdays) == 0 {
+		return errors.New("missing workdays")
+	}
+	for _, d := range cal.Workdays {
+		if d < time.Sunday || d > time.Saturday {
+			return fmt.Errorf("invalid weekday: %d", d)
+		}
+	}
+	if cal.WorkStart < 0 || cal.WorkEnd > 24*60 || cal.WorkStart >= cal.WorkEnd {
+		return errors.New("working hours must end after they start")
+	}
+	days := make(map[string]bool, len(cal.Holidays))
+	for _, h := range cal.Holidays {
+		if h.Day.IsZero() {
+			return errors.New("missing holiday day")
+		}
+		if days[h.Day.String()] {
+			return fmt.Errorf("duplicate holiday: %s", h.Day)
+		}
+		days[h.Day.String()] = true
+	}
+	return nil
+}
+
+func (o Offset) Validate() error {
+	if o.BusinessDays < 0 || o.BusinessHours < 0 || (o.BusinessDays > 0) == (o.BusinessHours > 0) {
+		return errors.New("expected either a positive businessDays or businessHours")
+	}
+	return nil
+}
+
+// Due returns the due date the offset gives from the time from.
+func (cal Calendar) Due(from time.Time, o Offset) time.Time {
+	if o.BusinessDays > 0 {
+		return cal.AddBusinessDays(from, o.BusinessDays)
+	}
+	return cal.AddBusinessHours(from, time.Duration(o.BusinessHours*float64(time.Hour)))
+}
+
+// AddBusinessDays returns the end of the working hours of the n-th workday
+// after the day of from.
+func (cal Calendar) AddBusinessDays(from time.Time, n int) time.Time {
+	holidays := cal.holidaySet()
+	day := from.In(cal.Location())
+	for added, i := 0, 0; added < n && i < maxSearchDays; i++ {
+		day = addDays(day, 1)
+		if cal.isWorkday(day, holidays) {
+			added++
+		}
+	}
+	_, end := cal.workingHours(day)
+	return end
+}
+
+// AddBusinessHours returns the time at which d of working time has passed
+// since from.
+func (cal Calendar) AddBusinessHours(from time.Time, d time.Duration) time.Time {
+	holidays := cal.holidaySet()
+	t := from.In(cal.Location())
+	for i := 0; i < maxSearchDays; i++ {
+		start, end := cal.workingHours(t)
+		if cal.isWorkday(t, holidays) && t.Before(end) {
+			if t.Before(start) {
+				t = start
+			}
+			if left := end.Sub(t); d <= left {
+				return t.Add(d)
+			}
+			d -= end.Sub(t)
+		}
+		t = addDays(t, 1)
+	}
+	return t
+}
+
+// LastWorkingTime returns the latest time up to t that is working time: t
+// during working hours, the end of the last working hours otherwise. A task due
+// before it is overdue, time off not counting as late.
+func (cal Calendar) LastWorkingTime(t time.Time) time.Time {
+	holidays := cal.holidaySet()
+	t = t.In(cal.Location())
+	day := t
+	for i := 0; i < maxSearchDays; i++ {
+		start, end := cal.workingHours(day)
+		if cal.isWorkday(day, holidays) && !t.Before(start) {
+			if t.Before(end) {
+				return t
+			}
+			return end
+		}
+		day = addDays(day, -1)
+	}
+	return t
+}
+
+// IsWorkday tells whether the day of t, in the time zone of the calendar, is a
+// workday.
+func (cal Calendar) IsWorkday(t time.Time) bool {
+	return cal.isWorkday(t.In(cal.Location()), cal.holidaySet())
+}
+
+func (cal Calendar) isWorkday(day time.Time, holidays map[string]bool) bool {
+	if holidays[day.Format(time.DateOnly)] {
+		return false
+	}
+	for _, w := range cal.Workdays {
+		if w == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// workingHours returns when the working hours of the day of t start and end,
+// whether it is a workday or not.
+func (cal Calendar) workingHours(t time.Time) (start, end time.Time) {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, int(cal.WorkStart), 0, 0, t.Location()),
+		time.Date(y, m, d, 0, int(cal.WorkEnd), 0, 0, t.Location())
+}
+
+func (cal Calendar) holidaySet() map[string]bool {
+	days := make(map[string]bool, len(cal.Holidays))
+	for _, h := range cal.Holidays {
+		days[h.Day.String()] = true
+	}
+	return days
+}
+
+// Location returns the time zone of the calendar, UTC when it is unknown.
+func (cal Calendar) Location() *time.Location {
+	loc, err := time.LoadLocation(cal.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// addDays returns the start of the day n days after the day of t.
+func addDays(t time.Time, n int) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d+n, 0, 0, 0, 0, t.Location())
+}
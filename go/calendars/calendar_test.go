@@ -0,0 +1,173 @@
+package calendars
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"siransbach/taskmanagementapi/dates"
+)
+
+// office works from 9:00 to 17:00 in Paris on weekdays, May 8 2024 (a
+// Wednesday) off.
+func office(t *testing.T) Calendar {
+	t.Helper()
+	day, err := dates.Parse("2024-05-08")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Calendar{
+		Name:      "office",
+		TimeZone:  "Europe/Paris",
+		Workdays:  []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		WorkStart: 9 * 60,
+		WorkEnd:   17 * 60,
+		Holidays:  []Holiday{{Day: day, Name: "Victory Day"}},
+	}
+}
+
+func parse(t *testing.T, s string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestCalendar_AddBusinessDays(t *testing.T) {
+	cal := office(t)
+	cases := []struct {
+		from     string
+		n        int
+		expected string
+	}{
+		// Monday to Tuesday
+		{"2024-05-06T10:00:00+02:00", 1, "2024-05-07T17:00:00+02:00"},
+		// over the holiday
+		{"2024-05-07T10:00:00+02:00", 1, "2024-05-09T17:00:00+02:00"},
+		// Thursday over the weekend
+		{"2024-05-09T10:00:00+02:00", 3, "2024-05-14T17:00:00+02:00"},
+		// from a Saturday
+		{"2024-05-11T10:00:00+02:00", 1, "2024-05-13T17:00:00+02:00"},
+		// still Monday in Paris
+		{"2024-05-06T21:30:00Z", 1, "2024-05-07T17:00:00+02:00"},
+	}
+	for _, c := range cases {
+		if due := cal.AddBusinessDays(parse(t, c.from), c.n); !due.Equal(parse(t, c.expected)) {
+			t.Errorf("%s + %d days: expected %s, got %s", c.from, c.n, c.expected, due)
+		}
+	}
+}
+
+func TestCalendar_AddBusinessHours(t *testing.T) {
+	cal := office(t)
+	cases := []struct {
+		from     string
+		hours    float64
+		expected string
+	}{
+		{"2024-05-06T10:00:00+02:00", 3, "2024-05-06T13:00:00+02:00"},
+		// up to the end of the working hours
+		{"2024-05-06T10:00:00+02:00", 7, "2024-05-06T17:00:00+02:00"},
+		{"2024-05-06T16:00:00+02:00", 2, "2024-05-07T10:00:00+02:00"},
+		// before the working hours
+		{"2024-05-06T07:00:00+02:00", 1.5, "2024-05-06T10:30:00+02:00"},
+		// Tuesday evening over the holiday
+		{"2024-05-07T18:00:00+02:00", 1, "2024-05-09T10:00:00+02:00"},
+		// Friday over the weekend
+		{"2024-05-10T15:00:00+02:00", 4, "2024-05-13T11:00:00+02:00"},
+	}
+	for _, c := range cases {
+		d := time.Duration(c.hours * float64(time.Hour))
+		if due := cal.AddBusinessHours(parse(t, c.from), d); !due.Equal(parse(t, c.expected)) {
+			t.Errorf("%s + %gh: expected %s, got %s", c.from, c.hours, c.expected, due)
+		}
+	}
+}
+
+func TestCalendar_LastWorkingTime(t *testing.T) {
+	cal := office(t)
+	cases := []struct {
+		at       string
+		expected string
+	}{
+		{"2024-05-06T10:00:00+02:00", "2024-05-06T10:00:00+02:00"},
+		{"2024-05-06T20:00:00+02:00", "2024-05-06T17:00:00+02:00"},
+		{"2024-05-07T08:00:00+02:00", "2024-05-06T17:00:00+02:00"},
+		// holiday
+		{"2024-05-08T12:00:00+02:00", "2024-05-07T17:00:00+02:00"},
+		// weekend
+		{"2024-05-12T12:00:00+02:00", "2024-05-10T17:00:00+02:00"},
+		{"2024-05-13T09:00:00+02:00", "2024-05-13T09:00:00+02:00"},
+	}
+	for _, c := range cases {
+		if last := cal.LastWorkingTime(parse(t, c.at)); !last.Equal(parse(t, c.expected)) {
+			t.Errorf("%s: expected %s, got %s", c.at, c.expected, last)
+		}
+	}
+
+	now := time.Now()
+	if last := Always.LastWorkingTime(now); !last.Equal(now) {
+		t.Errorf("expected always to be working time, got %s", last)
+	}
+}
+
+func TestCalendar_Validate(t *testing.T) {
+	cases := []struct {
+		name    string
+		change  func(cal *Calendar)
+		invalid bool
+	}{
+		{name: "valid", change: func(cal *Calendar) {}},
+		{name: "always", change: func(cal *Calendar) { *cal = Always }},
+		{name: "missing name", change: func(cal *Calendar) { cal.Name = " " }, invalid: true},
+		{name: "invalid time zone", change: func(cal *Calendar) { cal.TimeZone = "Mars/Olympus" }, invalid: true},
+		{name: "missing workdays", change: func(cal *Calendar) { cal.Workdays = nil }, invalid: true},
+		{name: "reversed hours", change: func(cal *Calendar) { cal.WorkStart, cal.WorkEnd = cal.WorkEnd, cal.WorkStart }, invalid: true},
+		{name: "duplicate holiday", change: func(cal *Calendar) { cal.Holidays = append(cal.Holidays, cal.Holidays[0]) }, invalid: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cal := office(t)
+			c.change(&cal)
+			if err := cal.Validate(); (err != nil) != c.invalid {
+				t.Errorf("expected invalid %t, got %v", c.invalid, err)
+			}
+		})
+	}
+}
+
+func TestOffset_Validate(t *testing.T) {
+	for _, o := range []Offset{{}, {BusinessDays: 1, BusinessHours: 2}, {BusinessDays: -1}} {
+		if err := o.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", o)
+		}
+	}
+	for _, o := range []Offset{{BusinessDays: 3}, {BusinessHours: 0.5, Calendar: "office"}} {
+		if err := o.Validate(); err != nil {
+			t.Errorf("expected %+v to be valid, got %v", o, err)
+		}
+	}
+}
+
+func TestClock_JSON(t *testing.T) {
+	var hours struct {
+		Start Clock `json:"start"`
+		End   Clock `json:"end"`
+	}
+	if err := json.Unmarshal([]byte(`{"start": "08:30", "end": "24:00"}`), &hours); err != nil {
+		t.Fatal(err)
+	}
+	if hours.Start != 8*60+30 || hours.End != 24*60 {
+		t.Errorf("unexpected %+v", hours)
+	}
+	if b, _ := json.Marshal(hours); string(b) != `{"start":"08:30","end":"24:00"}` {
+		t.Errorf("unexpected %s", b)
+	}
+	for _, s := range []string{"8:30", "24:01", "12:60", "noon"} {
+		if _, err := ParseClock(s); err == nil {
+			t.Errorf("expected %s to be invalid", s)
+		}
+	}
+}
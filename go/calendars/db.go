@@ -0,0 +1,259 @@
+package calendars
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"siransbach/taskmanagementapi/tasks"
+)
+
+type (
+	DB struct {
+		pg tasks.Session
+	}
+
+	FindOptions struct {
+		IDs   []int
+		Names []string
+		// Default only matches the default calendar
+		Default bool
+	}
+)
+
+// ErrCalendarExists is returned when another calendar has the name.
+var ErrCalendarExists = errors.New("calendar already exists")
+
+const selectColumns = "id,name,time_zone,workdays," +
+	"EXTRACT(EPOCH FROM work_start)::INT / 60,EXTRACT(EPOCH FROM work_end)::INT / 60,is_default"
+
+func NewDB(pg tasks.Session) *DB {
+	return &DB{pg}
+}
+
+func (db *DB) Find(ctx context.Context, options FindOptions) ([]Calendar, error) {
+	query, args := options.buildQuery()
+	rows, err := db.pg.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	calendars := []Calendar{}
+	for rows.Next() {
+		var (
+			cal      Calendar
+			workdays []int64
+		)
+		if err := rows.Scan(
+			&cal.ID, &cal.Name, &cal.TimeZone, pq.Array(&workdays), &cal.WorkStart, &cal.WorkEnd, &cal.Default,
+		); err != nil {
+			return nil, err
+		}
+		for _, d := range workdays {
+			cal.Workdays = append(cal.Workdays, time.Weekday(d))
+		}
+		calendars = append(calendars, cal)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return calendars, db.attachHolidays(ctx, calendars)
+}
+
+func (db *DB) FindOne(ctx context.Context, options FindOptions) (Calendar, error) {
+	calendars, err := db.Find(ctx, options)
+	if err != nil {
+		return Calendar{}, err
+	}
+	if len(calendars) == 0 {
+		return Calendar{}, sql.ErrNoRows
+	}
+	return calendars[0], nil
+}
+
+// Default returns the default calendar, Always when there is none.
+func (db *DB) Default(ctx context.Context) (Calendar, error) {
+	cal, err := db.FindOne(ctx, FindOptions{Default: true})
+	if errors.Is(err, sql.ErrNoRows) {
+		return Always, nil
+	}
+	return cal, err
+}
+
+// Named returns the calendar of the name, the default one when empty.
+func (db *DB) Named(ctx context.Context, name string) (Calendar, error) {
+	if name == "" {
+		return db.Default(ctx)
+	}
+	return db.FindOne(ctx, FindOptions{Names: []string{name}})
+}
+
+// Insert creates the calendar along with its holidays. A default calendar
+// replaces the previous one as default.
+func (db *DB) Insert(ctx context.Context, cal Calendar) (id int, err error) {
+	if err := cal.Validate(); err != nil {
+		return 0, fmt.Errorf("invalid calendar: %w", err)
+	}
+	if err := db.clearDefault(ctx, cal); err != nil {
+		return 0, err
+	}
+	if err = db.pg.QueryRowContext(ctx, `
+		INSERT INTO api.work_calendars (name, time_zone, workdays, work_start, work_end, is_default)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`,
+		cal.Name, cal.TimeZone, pq.Array(weekdays(cal.Workdays)), cal.WorkStart.String(), cal.WorkEnd.String(), cal.Default,
+	).Scan(&id); err != nil {
+		return 0, nameTaken(err)
+	}
+	return id, db.insertHolidays(ctx, id, cal.Holidays)
+}
+
+// Update replaces the calendar and its holidays, returning sql.ErrNoRows if
+// it does not exist.
+func (db *DB) Update(ctx context.Context, cal Calendar) error {
+	if err := cal.Validate(); err != nil {
+		return fmt.Errorf("invalid calendar: %w", err)
+	}
+	if err := db.clearDefault(ctx, cal); err != nil {
+		return err
+	}
+	var updatedID int
+	if err := db.pg.QueryRowContext(ctx, `
+		UPDATE api.work_calendars
+		SET name = $1, time_zone = $2, workdays = $3, work_start = $4, work_end = $5, is_default = $6
+		WHERE id = $7
+		RETURNING id`,
+		cal.Name, cal.TimeZone, pq.Array(weekdays(cal.Workdays)), cal.WorkStart.String(), cal.WorkEnd.String(), cal.Default,
+		cal.ID,
+	).Scan(&updatedID); err != nil {
+		return nameTaken(err)
+	}
+	if _, err := db.pg.ExecContext(ctx, "DELETE FROM api.holidays WHERE calendar_id = $1", cal.ID); err != nil {
+		return err
+	}
+	return db.insertHolidays(ctx, cal.ID, cal.Holidays)
+}
+
+func (db *DB) Delete(ctx context.Context, id int) error {
+	var deletedID int
+	return db.pg.QueryRowContext(ctx,
+		"DELETE FROM api.work_calendars WHERE id = $1 RETURNING id", id,
+	).Scan(&deletedID)
+}
+
+// clearDefault makes the other calendars not default when cal is.
+func (db *DB) clearDefault(ctx context.Context, cal Calendar) error {
+	if !cal.Default {
+		return nil
+	}
+	_, err := db.pg.ExecContext(ctx,
+		"UPDATE api.work_calendars SET is_default = FALSE WHERE is_default AND id <> $1", cal.ID,
+	)
+	return err
+}
+
+func (db *DB) insertHolidays(ctx context.Context, calendarID int, holidays []Holiday) error {
+	if len(holidays) == 0 {
+		return nil
+	}
+	days := make([]string, 0, len(holidays))
+	names := make([]string, 0, len(holidays))
+	for _, h := range holidays {
+		days = append(days, h.Day.String())
+		names = append(names, h.Name)
+	}
+	_, err := db.pg.ExecContext(ctx, `
+		INSERT INTO api.holidays (calendar_id, day, name)
+		SELECT $1, day, name FROM unnest($2::DATE[], $3::TEXT[]) AS h (day, name)`,
+		calendarID, pq.Array(days), pq.Array(names),
+	)
+	return err
+}
+
+func (db *DB) attachHolidays(ctx context.Context, calendars []Calendar) error {
+	if len(calendars) == 0 {
+		return nil
+	}
+	ids := make([]int, 0, len(calendars))
+	for _, cal := range calendars {
+		ids = append(ids, cal.ID)
+	}
+	rows, err := db.pg.QueryContext(ctx,
+		"SELECT calendar_id, day, name FROM api.holidays WHERE calendar_id = ANY($1) ORDER BY calendar_id, day",
+		pq.Array(ids),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	holidays := make(map[int][]Holiday)
+	for rows.Next() {
+		var (
+			calendarID int
+			h          Holiday
+		)
+		if err := rows.Scan(&calendarID, &h.Day.Time, &h.Name); err != nil {
+			return err
+		}
+		holidays[calendarID] = append(holidays[calendarID], h)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i := range calendars {
+		calendars[i].Holidays = holidays[calendars[i].ID]
+		if calendars[i].Holidays == nil {
+			calendars[i].Holidays = []Holiday{}
+		}
+	}
+	return nil
+}
+
+func (opt FindOptions) buildQuery() (string, []interface{}) {
+	var (
+		clauses []string
+		args    []interface{}
+	)
+	if len(opt.IDs) > 0 {
+		args = append(args, pq.Array(opt.IDs))
+		clauses = append(clauses, fmt.Sprintf("id = ANY($%d)", len(args)))
+	}
+	if len(opt.Names) > 0 {
+		args = append(args, pq.Array(opt.Names))
+		clauses = append(clauses, fmt.Sprintf("name = ANY($%d)", len(args)))
+	}
+	if opt.Default {
+		clauses = append(clauses, "is_default")
+	}
+
+	query := "SELECT " + selectColumns + " FROM api.work_calendars"
+	if len(clauses) > 0 {
+		query += " WHERE " + strings.Join(clauses, " AND ")
+	}
+	return query + " ORDER BY id", args
+}
+
+// nameTaken returns ErrCalendarExists for a violation of the unique name, err
+// otherwise.
+func nameTaken(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrCalendarExists
+	}
+	return err
+}
+
+func weekdays(days []time.Weekday) []int64 {
+	ints := make([]int64, 0, len(days))
+	for _, d := range days {
+		ints = append(ints, int64(d))
+	}
+	return ints
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"time"
 
 	"github.com/rs/zerolog"
 )
@@ -19,3 +20,34 @@ func HostPort() string {
 	}
 	return ":8000"
 }
+
+// RecurrenceInterval is how often the recurring task scheduler runs.
+func RecurrenceInterval() time.Duration {
+	return durationEnv("RECURRENCE_INTERVAL", time.Minute)
+}
+
+// RecurrenceHorizon is how far ahead recurring task occurrences are
+// materialized.
+func RecurrenceHorizon() time.Duration {
+	return durationEnv("RECURRENCE_HORIZON", 14*24*time.Hour)
+}
+
+// SnapshotInterval is how often the task status snapshot of the day is taken
+// again.
+func SnapshotInterval() time.Duration {
+	return durationEnv("SNAPSHOT_INTERVAL", time.Hour)
+}
+
+// ExportTimeout is how long a task export may take before it is cut short.
+func ExportTimeout() time.Duration {
+	return durationEnv("EXPORT_TIMEOUT", 5*time.Minute)
+}
+
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,43 @@
+package dates
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Date is a day without time, formatted as YYYY-MM-DD.
+type Date struct {
+	time.Time
+}
+
+func Parse(str string) (Date, error) {
+	t, err := time.Parse(time.DateOnly, str)
+	if err != nil {
+		return Date{}, fmt.Errorf("invalid date: %s", str)
+	}
+	return Date{t}, nil
+}
+
+func (d Date) String() string {
+	return d.Format(time.DateOnly)
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.String() + `"`), nil
+}
+
+func (d *Date) UnmarshalJSON(b []byte) error {
+	parsed, err := Parse(strings.Trim(string(b), `"`))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// WeekStart returns the Monday of the week of d.
+func (d Date) WeekStart() Date {
+	offset := (int(d.Weekday()) + 6) % 7
+	return Date{d.AddDate(0, 0, -offset)}
+}
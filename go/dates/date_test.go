@@ -0,0 +1,47 @@
+package dates
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func mustDate(t *testing.T, str string) Date {
+	t.Helper()
+	d, err := Parse(str)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestDate_WeekStart(t *testing.T) {
+	cases := map[string]string{
+		"2024-03-04": "2024-03-04", // Monday
+		"2024-03-06": "2024-03-04",
+		"2024-03-10": "2024-03-04", // Sunday
+		"2024-01-01": "2024-01-01",
+		"2023-12-31": "2023-12-25",
+	}
+	for day, expected := range cases {
+		if got := mustDate(t, day).WeekStart().String(); got != expected {
+			t.Errorf("%s: expected week start %s, got %s", day, expected, got)
+		}
+	}
+}
+
+func TestDate_JSON(t *testing.T) {
+	var d Date
+	if err := json.Unmarshal([]byte(`"2024-03-06"`), &d); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `"2024-03-06"` {
+		t.Errorf("unexpected JSON %s", b)
+	}
+	if err := json.Unmarshal([]byte(`"06/03/2024"`), &d); err == nil {
+		t.Error("Expected error for invalid date, got nil")
+	}
+}
@@ -0,0 +1,170 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"siransbach/taskmanagementapi/tasks"
+)
+
+type (
+	Format string
+
+	// Writer writes tasks one at a time, so that exports never hold more than a
+	// page of tasks in memory.
+	Writer interface {
+		Write(entry tasks.Entry) error
+		// Close writes what follows the tasks and flushes the writer, leaving
+		// the underlying writer open.
+		Close() error
+	}
+
+	column struct {
+		name   string
+		number bool
+		value  func(e tasks.Entry) string
+	}
+)
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+	FormatXLSX   Format = "xlsx"
+	FormatICal   Format = "ics"
+)
+
+var Formats = []Format{FormatCSV, FormatNDJSON, FormatXLSX, FormatICal}
+
+func ParseFormat(str string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(str, string(f)) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("invalid format: %s", str)
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatICal:
+		return "text/calendar"
+	}
+	return "application/octet-stream"
+}
+
+// NewWriter returns the writer of the format. iCalendar exports list the tasks
+// as to-dos, see NewICalWriter for events.
+func NewWriter(format Format, w io.Writer) Writer {
+	switch format {
+	case FormatNDJSON:
+		return &ndjsonWriter{enc: json.NewEncoder(w)}
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	case FormatICal:
+		return NewICalWriter(w, ComponentTodo, "")
+	}
+	return newCSVWriter(w)
+}
+
+// columns are the columns of the spreadsheet formats.
+var columns = []column{
+	{name: "id", number: true, value: func(e tasks.Entry) string { return optionalInt(e.ID) }},
+	{name: "title", value: func(e tasks.Entry) string { return e.Title }},
+	{name: "description", value: func(e tasks.Entry) string { return e.Description }},
+	{name: "status", value: func(e tasks.Entry) string { return string(e.Status) }},
+	{name: "priority", value: func(e tasks.Entry) string { return string(e.Priority) }},
+	{name: "labels", value: func(e tasks.Entry) string { return strings.Join(e.Labels, ",") }},
+	{name: "assigned_user_id", number: true, value: func(e tasks.Entry) string { return optionalInt(e.AssignedUserID) }},
+	{name: "assigned_username", value: func(e tasks.Entry) string { return e.AssignedUsername }},
+	{name: "project_id", number: true, value: func(e tasks.Entry) string { return optionalInt(e.ProjectID) }},
+	{name: "parent_task_id", number: true, value: func(e tasks.Entry) string { return optionalInt(e.ParentTaskID) }},
+	{name: "created_at", value: func(e tasks.Entry) string { return optionalTime(e.CreatedAt) }},
+	{name: "due_date", value: func(e tasks.Entry) string { return optionalTime(e.DueDate) }},
+	{name: "original_estimate", number: true, value: func(e tasks.Entry) string { return optionalMinutes(e.OriginalEstimate) }},
+	{name: "remaining_estimate", number: true, value: func(e tasks.Entry) string { return optionalMinutes(e.RemainingEstimate) }},
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	c := &csvWriter{w: csv.NewWriter(w)}
+	header := make([]string, 0, len(columns))
+	for _, col := range columns {
+		header = append(header, col.name)
+	}
+	// errors are kept by the csv writer until flushed
+	_ = c.w.Write(header)
+	return c
+}
+
+func (c *csvWriter) Write(entry tasks.Entry) error {
+	record := make([]string, 0, len(columns))
+	for _, col := range columns {
+		value := col.value(entry)
+		if !col.number {
+			value = EscapeFormula(value)
+		}
+		record = append(record, value)
+	}
+	return c.w.Write(record)
+}
+
+// EscapeFormula prefixes the text a spreadsheet would take for a formula with
+// a quote, so that opening a CSV file never runs what a task contains.
+func EscapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(entry tasks.Entry) error {
+	return w.enc.Encode(entry)
+}
+
+func (w *ndjsonWriter) Close() error {
+	return nil
+}
+
+func optionalInt(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func optionalMinutes(minutes *int) string {
+	if minutes == nil {
+		return ""
+	}
+	return strconv.Itoa(*minutes)
+}
+
+func optionalTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
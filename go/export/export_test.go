@@ -0,0 +1,214 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"siransbach/taskmanagementapi/tasks"
+)
+
+func testEntries() []tasks.Entry {
+	estimate := 90
+	return []tasks.Entry{
+		{
+			ID:               1,
+			Title:            "Write report",
+			Description:      "Quarterly, with \"figures\"",
+			Status:           tasks.StatusInProgress,
+			Priority:         tasks.PriorityHigh,
+			Labels:           []string{"finance", "q1"},
+			AssignedUserID:   2,
+			AssignedUsername: "jdoe",
+			CreatedAt:        time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC),
+			DueDate:          time.Date(2024, 3, 8, 17, 30, 0, 0, time.UTC),
+			OriginalEstimate: &estimate,
+		},
+		{
+			ID:        2,
+			Title:     "No due date",
+			Status:    tasks.StatusPending,
+			Priority:  tasks.PriorityLow,
+			CreatedAt: time.Date(2024, 3, 2, 9, 0, 0, 0, time.UTC),
+		},
+	}
+}
+
+func write(t *testing.T, w Writer, entries []tasks.Entry) {
+	t.Helper()
+	for _, entry := range entries {
+		if err := w.Write(entry); err != nil {
+			t.Fatalf("could not write entry: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("could not close writer: %v", err)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat("XLSX"); err != nil || f != FormatXLSX {
+		t.Errorf("expected %s, got %s (%v)", FormatXLSX, f, err)
+	}
+	if _, err := ParseFormat("pdf"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	write(t, NewWriter(FormatCSV, &buf), testEntries())
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	expected := []string{
+		"id,title,description,status,priority,labels,assigned_user_id,assigned_username,project_id,parent_task_id,created_at,due_date,original_estimate,remaining_estimate",
+		`1,Write report,"Quarterly, with ""figures""",IN_PROGRESS,HIGH,"finance,q1",2,jdoe,,,2024-03-01T09:00:00Z,2024-03-08T17:30:00Z,90,`,
+		"2,No due date,,PENDING,LOW,,,,,,2024-03-02T09:00:00Z,,,",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got %d: %q", len(expected), len(lines), lines)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("line %d: expected %q, got %q", i, expected[i], lines[i])
+		}
+	}
+}
+
+func TestCSVWriter_Formulas(t *testing.T) {
+	var buf bytes.Buffer
+	write(t, NewWriter(FormatCSV, &buf), []tasks.Entry{{
+		ID:          3,
+		Title:       "=HYPERLINK(\"http://example.com\")",
+		Description: "@SUM(A1:A2)",
+		Labels:      []string{"+1", "-x"},
+		Status:      tasks.StatusPending,
+		Priority:    tasks.PriorityLow,
+	}})
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	expected := `3,"'=HYPERLINK(""http://example.com"")",'@SUM(A1:A2),PENDING,LOW,"'+1,-x",,,,,,,,`
+	if len(lines) != 2 || lines[1] != expected {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	var buf bytes.Buffer
+	write(t, NewWriter(FormatNDJSON, &buf), testEntries())
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	if !strings.HasPrefix(lines[0], `{"id":1,`) || !strings.HasPrefix(lines[1], `{"id":2,`) {
+		t.Errorf("expected a task per line, got %q", lines)
+	}
+}
+
+func TestXLSXWriter(t *testing.T) {
+	var buf bytes.Buffer
+	write(t, NewWriter(FormatXLSX, &buf), testEntries())
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("expected a zip archive: %v", err)
+	}
+	var sheet string
+	for _, f := range r.File {
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sheet = string(b)
+	}
+	if sheet == "" {
+		t.Fatal("expected a sheet")
+	}
+	if n := strings.Count(sheet, "<row>"); n != 3 {
+		t.Errorf("expected a header and 2 rows, got %d rows", n)
+	}
+	for _, s := range []string{
+		"<c><v>1</v></c>",
+		`<t xml:space="preserve">Quarterly, with &#34;figures&#34;</t>`,
+		"<c><v>90</v></c>",
+	} {
+		if !strings.Contains(sheet, s) {
+			t.Errorf("expected the sheet to contain %q", s)
+		}
+	}
+}
+
+func TestICalWriter(t *testing.T) {
+	var buf bytes.Buffer
+	write(t, NewICalWriter(&buf, ComponentTodo, "Tasks; mine"), testEntries())
+	out := buf.String()
+
+	for _, s := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-CALNAME:Tasks\\; mine\r\n",
+		"BEGIN:VTODO\r\nUID:task-1@taskmanagementapi\r\n",
+		"CREATED:20240301T090000Z\r\n",
+		"DUE:20240308T173000Z\r\n",
+		"STATUS:IN-PROCESS\r\n",
+		"SUMMARY:Write report\r\n",
+		"DESCRIPTION:Quarterly\\, with \"figures\"\r\n",
+		"PRIORITY:3\r\n",
+		"CATEGORIES:finance,q1\r\n",
+		"END:VTODO\r\nEND:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected the calendar to contain %q, got:\n%s", s, out)
+		}
+	}
+	if strings.Contains(out, "task-2@") {
+		t.Error("expected the task without a due date to be left out")
+	}
+
+	buf.Reset()
+	write(t, NewICalWriter(&buf, ComponentEvent, ""), testEntries())
+	out = buf.String()
+	if !strings.Contains(out, "BEGIN:VEVENT\r\n") || !strings.Contains(out, "DTSTART:20240308T173000Z\r\n") {
+		t.Errorf("expected an event starting when the task is due, got:\n%s", out)
+	}
+	if strings.Contains(out, "X-WR-CALNAME") || strings.Contains(out, "STATUS:") {
+		t.Errorf("expected no calendar name nor status, got:\n%s", out)
+	}
+}
+
+func TestICalWriter_Folding(t *testing.T) {
+	var buf bytes.Buffer
+	entry := testEntries()[0]
+	entry.Title = strings.Repeat("é", 100)
+	write(t, NewICalWriter(&buf, ComponentTodo, ""), []tasks.Entry{entry})
+
+	var unfolded strings.Builder
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		if len(line) > maxLineLength {
+			t.Errorf("expected lines of at most %d octets, got %d", maxLineLength, len(line))
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("expected folding to keep characters whole, got %q", line)
+		}
+		if strings.HasPrefix(line, " ") {
+			unfolded.WriteString(line[1:])
+			continue
+		}
+		unfolded.WriteString("\n" + line)
+	}
+	if !strings.Contains(unfolded.String(), "\nSUMMARY:"+entry.Title+"\n") {
+		t.Errorf("expected the folded summary to unfold to the title, got %q", unfolded.String())
+	}
+}
@@ -0,0 +1,147 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"siransbach/taskmanagementapi/tasks"
+)
+
+// Component is the iCalendar component tasks are listed as.
+type Component string
+
+const (
+	ComponentTodo  Component = "VTODO"
+	ComponentEvent Component = "VEVENT"
+)
+
+// maxLineLength is the length in octets beyond which iCalendar content lines
+// are folded.
+const maxLineLength = 75
+
+const icalTimeFormat = "20060102T150405Z"
+
+func ParseComponent(str string) (Component, error) {
+	for _, c := range []Component{ComponentTodo, ComponentEvent} {
+		if strings.EqualFold(str, string(c)) {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("invalid component: %s", str)
+}
+
+// icalWriter writes the tasks with a due date as to-dos due then, or as events
+// taking place then. Tasks without a due date are left out.
+type icalWriter struct {
+	w         *bufio.Writer
+	component Component
+	stamp     string
+	err       error
+}
+
+// NewICalWriter returns a writer of an iCalendar, named name when not empty,
+// listing the tasks as component.
+func NewICalWriter(w io.Writer, component Component, name string) Writer {
+	c := &icalWriter{w: bufio.NewWriter(w), component: component, stamp: time.Now().UTC().Format(icalTimeFormat)}
+	c.writeLine("BEGIN:VCALENDAR")
+	c.writeLine("VERSION:2.0")
+	c.writeLine("PRODID:-//taskmanagementapi//tasks//EN")
+	c.writeLine("CALSCALE:GREGORIAN")
+	c.writeLine("METHOD:PUBLISH")
+	if name != "" {
+		c.writeLine("X-WR-CALNAME:" + escapeText(name))
+	}
+	return c
+}
+
+func (c *icalWriter) Write(entry tasks.Entry) error {
+	if entry.DueDate.IsZero() {
+		return c.err
+	}
+	due := entry.DueDate.UTC().Format(icalTimeFormat)
+	c.writeLine("BEGIN:" + string(c.component))
+	c.writeLine(fmt.Sprintf("UID:task-%d@taskmanagementapi", entry.ID))
+	c.writeLine("DTSTAMP:" + c.stamp)
+	if !entry.CreatedAt.IsZero() {
+		c.writeLine("CREATED:" + entry.CreatedAt.UTC().Format(icalTimeFormat))
+	}
+	if c.component == ComponentTodo {
+		c.writeLine("DUE:" + due)
+		if status, ok := todoStatuses[entry.Status]; ok {
+			c.writeLine("STATUS:" + status)
+		}
+	} else {
+		// without an end, the event ends when it starts
+		c.writeLine("DTSTART:" + due)
+	}
+	c.writeLine("SUMMARY:" + escapeText(entry.Title))
+	if entry.Description != "" {
+		c.writeLine("DESCRIPTION:" + escapeText(entry.Description))
+	}
+	if priority, ok := priorities[entry.Priority]; ok {
+		c.writeLine(fmt.Sprintf("PRIORITY:%d", priority))
+	}
+	if len(entry.Labels) > 0 {
+		labels := make([]string, 0, len(entry.Labels))
+		for _, label := range entry.Labels {
+			labels = append(labels, escapeText(label))
+		}
+		c.writeLine("CATEGORIES:" + strings.Join(labels, ","))
+	}
+	c.writeLine("END:" + string(c.component))
+	return c.err
+}
+
+func (c *icalWriter) Close() error {
+	c.writeLine("END:VCALENDAR")
+	if c.err != nil {
+		return c.err
+	}
+	return c.w.Flush()
+}
+
+var todoStatuses = map[tasks.Status]string{
+	tasks.StatusPending:    "NEEDS-ACTION",
+	tasks.StatusInProgress: "IN-PROCESS",
+	tasks.StatusCompleted:  "COMPLETED",
+}
+
+// priorities map to the high (1-4), medium (5) and low (6-9) ranges of
+// iCalendar.
+var priorities = map[tasks.Priority]int{
+	tasks.PriorityUrgent: 1,
+	tasks.PriorityHigh:   3,
+	tasks.PriorityMedium: 5,
+	tasks.PriorityLow:    9,
+}
+
+// writeLine writes a content line, folded every maxLineLength octets without
+// splitting characters.
+func (c *icalWriter) writeLine(line string) {
+	if c.err != nil {
+		return
+	}
+	var b strings.Builder
+	for width := maxLineLength; len(line) > width; width = maxLineLength - 1 {
+		cut := width
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	_, c.err = c.w.WriteString(b.String())
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
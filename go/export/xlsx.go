@@ -0,0 +1,109 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"io"
+
+	"siransbach/taskmanagementapi/tasks"
+)
+
+// the parts of a workbook with a single sheet, besides the sheet itself
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", xml.Header +
+		`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", xml.Header +
+		`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Tasks" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+// xlsxWriter streams the rows of the sheet into the zip archive of the
+// workbook, the other parts being written up front. Strings are inlined
+// rather than shared, which would take holding all of them until the end.
+type xlsxWriter struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+	err   error
+}
+
+func newXLSXWriter(w io.Writer) *xlsxWriter {
+	x := &xlsxWriter{zip: zip.NewWriter(w)}
+	for _, part := range xlsxParts {
+		var pw io.Writer
+		if pw, x.err = x.zip.Create(part.name); x.err != nil {
+			return x
+		}
+		if _, x.err = io.WriteString(pw, part.content); x.err != nil {
+			return x
+		}
+	}
+	sheet, err := x.zip.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		x.err = err
+		return x
+	}
+	x.sheet = bufio.NewWriter(sheet)
+	x.writeString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	x.writeString("<row>")
+	for _, col := range columns {
+		x.writeCell(col.name, false)
+	}
+	x.writeString("</row>")
+	return x
+}
+
+func (x *xlsxWriter) Write(entry tasks.Entry) error {
+	x.writeString("<row>")
+	for _, col := range columns {
+		x.writeCell(col.value(entry), col.number)
+	}
+	x.writeString("</row>")
+	return x.err
+}
+
+func (x *xlsxWriter) Close() error {
+	x.writeString("</sheetData></worksheet>")
+	if x.err != nil {
+		return x.err
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.zip.Close()
+}
+
+func (x *xlsxWriter) writeCell(value string, number bool) {
+	switch {
+	case value == "":
+		x.writeString("<c/>")
+	case number:
+		x.writeString("<c><v>" + value + "</v></c>")
+	default:
+		x.writeString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+		if x.err == nil {
+			x.err = xml.EscapeText(x.sheet, []byte(value))
+		}
+		x.writeString("</t></is></c>")
+	}
+}
+
+func (x *xlsxWriter) writeString(s string) {
+	if x.err == nil {
+		_, x.err = x.sheet.WriteString(s)
+	}
+}
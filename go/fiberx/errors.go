@@ -7,6 +7,7 @@ const (
 	ErrMsg401 = "the server could not verify that you are authorized to access the requested resource"
 	ErrMsg403 = "you do not have to sufficient role to access the requested resource"
 	ErrMsg404 = "the server could not find the requested resource"
+	ErrMsg409 = "the request conflicts with the current state of the requested resource"
 	ErrMsg500 = "the server encountered an error and could not complete your request"
 )
 
@@ -16,11 +17,12 @@ var lookup = map[int]string{
 	fiber.StatusUnauthorized:        ErrMsg401,
 	fiber.StatusForbidden:           ErrMsg403,
 	fiber.StatusNotFound:            ErrMsg404,
+	fiber.StatusConflict:            ErrMsg409,
 }
 
 func Err(c *fiber.Ctx, code int, customMsg ...string) error {
 	msg := lookup[code]
-	if len(customMsg) > 0 {
+	if len(customMsg) > 0 && customMsg[0] != "" {
 		msg = customMsg[0]
 	}
 	return c.Status(code).JSON(fiber.Map{
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+
+	"siransbach/taskmanagementapi/fiberx"
+	"siransbach/taskmanagementapi/snapshots"
+	"siransbach/taskmanagementapi/tasks"
+)
+
+func (h *handlers) employerGetTaskAnalytics(c *fiber.Ctx) error {
+	var (
+		opts tasks.AnalyticsOptions
+		err  error
+	)
+	if v := c.Query("from"); v != "" {
+		opts.From, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			log.Err(err).Msg("could not parse from")
+			return fiberx.Err(c, fiber.StatusBadRequest)
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		opts.To, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			log.Err(err).Msg("could not parse to")
+			return fiberx.Err(c, fiber.StatusBadRequest)
+		}
+	}
+	if !opts.From.IsZero() && !opts.To.IsZero() && !opts.From.Before(opts.To) {
+		return fiberx.Err(c, fiber.StatusBadRequest, "from must be before to")
+	}
+	if opts.AnyAssigneeIDs, err = queryIDs(c, "assignedUserId"); err != nil {
+		log.Err(err).Msg("could not parse assignedUserId")
+		return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+	}
+	for _, v := range queryValues(c, "status") {
+		status, err := tasks.ParseStatus(v)
+		if err != nil {
+			log.Err(err).Msg("could not parse status")
+			return fiberx.Err(c, fiber.StatusBadRequest, "invalid status: "+v)
+		}
+		opts.Statuses = append(opts.Statuses, status)
+	}
+
+	var ferr *fiber.Error
+	if opts.OverdueBefore, ferr = overdueBefore(c.Context(), h.pg); ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+
+	analytics, err := tasks.NewDB(h.pg).Analyze(c.Context(), opts)
+	if err != nil {
+		log.Err(err).Msg("could not analyze tasks")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(analytics)
+}
+
+// employerGetCumulativeFlow returns the number of tasks of every status over
+// time, from the daily snapshots.
+func (h *handlers) employerGetCumulativeFlow(c *fiber.Ctx) error {
+	opts, ferr := parseHistoryOptions(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	points, err := snapshots.NewDB(h.pg).History(c.Context(), opts)
+	if err != nil {
+		log.Err(err).Msg("could not get task status history")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"points": points,
+	})
+}
+
+// employerGetBurndown returns the number of tasks left and completed over
+// time, from the daily snapshots.
+func (h *handlers) employerGetBurndown(c *fiber.Ctx) error {
+	opts, ferr := parseHistoryOptions(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	points, err := snapshots.NewDB(h.pg).History(c.Context(), opts)
+	if err != nil {
+		log.Err(err).Msg("could not get task status history")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"points": snapshots.Burndown(points),
+	})
+}
+
+// parseHistoryOptions reads the range, granularity and assignees of the task
+// status history, the last 30 days by day by default.
+func parseHistoryOptions(c *fiber.Ctx) (snapshots.HistoryOptions, *fiber.Error) {
+	var (
+		to   = time.Now()
+		opts = snapshots.HistoryOptions{From: to.AddDate(0, 0, -30), To: to, Granularity: tasks.GranularityDay}
+		err  error
+	)
+	if v := c.Query("from"); v != "" {
+		opts.From, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			log.Err(err).Msg("could not parse from")
+			return opts, &fiber.Error{Code: fiber.StatusBadRequest}
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		opts.To, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			log.Err(err).Msg("could not parse to")
+			return opts, &fiber.Error{Code: fiber.StatusBadRequest}
+		}
+	}
+	if v := c.Query("granularity"); v != "" {
+		opts.Granularity, err = tasks.ParseGranularity(v)
+		if err != nil {
+			log.Err(err).Msg("could not parse granularity")
+			return opts, &fiber.Error{Code: fiber.StatusBadRequest}
+		}
+	}
+	if !opts.From.Before(opts.To) {
+		return opts, &fiber.Error{Code: fiber.StatusBadRequest, Message: "from must be before to"}
+	}
+	if opts.UserIDs, err = queryIDs(c, "assignedUserId"); err != nil {
+		log.Err(err).Msg("could not parse assignedUserId")
+		return opts, &fiber.Error{Code: fiber.StatusBadRequest, Message: err.Error()}
+	}
+	return opts, nil
+}
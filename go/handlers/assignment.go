@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+
+	"siransbach/taskmanagementapi/assignment"
+	"siransbach/taskmanagementapi/auth"
+	"siransbach/taskmanagementapi/availability"
+	"siransbach/taskmanagementapi/fiberx"
+	"siransbach/taskmanagementapi/tasks"
+)
+
+// autoAssign picks the employee the task goes to with the strategy, among the
+// members of its project if it has one and leaving out those away on its due
+// date, and returns the error to respond with if none can be picked.
+func autoAssign(ctx context.Context, pg tasks.Session, strategy assignment.Strategy, task assignment.Task) (assignment.Choice, []assignment.Candidate, *fiber.Error) {
+	before, ferr := overdueBefore(ctx, pg)
+	if ferr != nil {
+		return assignment.Choice{}, nil, ferr
+	}
+	candidates, err := assignment.NewDB(pg).Candidates(ctx, task.ProjectID, before)
+	if err != nil {
+		log.Err(err).Msg("could not find assignment candidates")
+		return assignment.Choice{}, nil, &fiber.Error{Code: fiber.StatusInternalServerError}
+	}
+	if !task.DueDate.IsZero() {
+		dueDate, ferr := inCalendarZone(ctx, pg, task.DueDate)
+		if ferr != nil {
+			return assignment.Choice{}, nil, ferr
+		}
+		userIDs := make([]int, 0, len(candidates))
+		for _, c := range candidates {
+			userIDs = append(userIDs, c.UserID)
+		}
+		absences, err := availability.NewDB(pg).ByUser(ctx, userIDs, dueDate, dueDate)
+		if err != nil {
+			log.Err(err).Msg("could not find absences")
+			return assignment.Choice{}, nil, &fiber.Error{Code: fiber.StatusInternalServerError}
+		}
+		available := candidates[:0]
+		for _, c := range candidates {
+			if _, away := availability.Covering(absences[c.UserID], dueDate); !away {
+				available = append(available, c)
+			}
+		}
+		candidates = available
+	}
+	choice, err := assignment.Pick(strategy, task, candidates)
+	if err != nil {
+		log.Err(err).Msg("could not assign task automatically")
+		return assignment.Choice{}, nil, &fiber.Error{Code: fiber.StatusBadRequest, Message: err.Error()}
+	}
+	return choice, candidates, nil
+}
+
+// employerPreviewAssignment tells who a task would be assigned to with the
+// strategy, without creating it.
+func (h *handlers) employerPreviewAssignment(c *fiber.Ctx) error {
+	var request struct {
+		Strategy  string    `json:"strategy"`
+		ProjectID int       `json:"projectId"`
+		Labels    []string  `json:"labels"`
+		DueDate   time.Time `json:"dueDate"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		log.Err(err).Msg("could not parse assignment preview request")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	strategy, err := assignment.ParseStrategy(request.Strategy)
+	if err != nil {
+		log.Err(err).Msg("could not parse strategy")
+		return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+	}
+	if request.ProjectID > 0 {
+		if ferr := h.checkProjectAssignee(c.Context(), request.ProjectID, 0); ferr != nil {
+			return fiberx.Err(c, ferr.Code, ferr.Message)
+		}
+	}
+
+	choice, candidates, ferr := autoAssign(c.Context(), h.pg, strategy, assignment.Task{
+		ProjectID: request.ProjectID,
+		Labels:    request.Labels,
+		DueDate:   request.DueDate,
+	})
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	return c.JSON(fiber.Map{
+		"choice":     choice,
+		"candidates": candidates,
+	})
+}
+
+func (h *handlers) employerGetAutoAssignment(c *fiber.Ctx) error {
+	taskID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse task id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	choice, err := assignment.NewDB(h.pg).FindOne(c.Context(), taskID)
+	if err != nil {
+		log.Err(err).Msg("could not find automatic assignment")
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(choice)
+}
+
+func (h *handlers) employerGetUserSkills(c *fiber.Ctx) error {
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse user id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	skills, err := assignment.NewDB(h.pg).Skills(c.Context(), userID)
+	if err != nil {
+		log.Err(err).Msg("could not get skills")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"skills": skills,
+	})
+}
+
+// employerSetUserSkills replaces the skills of an employee, which the
+// skillMatch strategy matches against task labels.
+func (h *handlers) employerSetUserSkills(c *fiber.Ctx) error {
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse user id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	var request struct {
+		Skills []string `json:"skills"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		log.Err(err).Msg("could not parse skills request")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	user, err := auth.NewDB(h.pg).FindOne(c.Context(), auth.FindOptions{IDs: []int{userID}})
+	if err != nil {
+		log.Err(err).Msg("could not find user")
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	if !user.IsEmployee() {
+		log.Error().Msg(fmt.Sprintf("user %d is not an employee", userID))
+		return fiberx.Err(c, fiber.StatusBadRequest, "only employees have skills")
+	}
+
+	tx, err := h.pg.BeginTx(c.Context(), nil)
+	if err != nil {
+		log.Err(err).Msg("could not begin transaction")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err := assignment.NewDB(tx).SetSkills(c.Context(), userID, request.Skills); err != nil {
+		log.Err(err).Msg("could not set skills")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Err(err).Msg("could not commit skills")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"skills": assignment.NormalizeSkills(request.Skills),
+	})
+}
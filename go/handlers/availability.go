@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+
+	"siransbach/taskmanagementapi/assignment"
+	"siransbach/taskmanagementapi/auth"
+	"siransbach/taskmanagementapi/availability"
+	"siransbach/taskmanagementapi/dates"
+	"siransbach/taskmanagementapi/fiberx"
+	"siransbach/taskmanagementapi/tasks"
+)
+
+// maxAvailabilityDays is the longest range of team availability.
+const maxAvailabilityDays = 92
+
+type teamMemberAvailability struct {
+	UserID   int                    `json:"userId"`
+	Username string                 `json:"username"`
+	Absences []availability.Absence `json:"absences"`
+	DaysOff  []dates.Date           `json:"daysOff"`
+}
+
+func (h *handlers) employeeGetAbsences(c *fiber.Ctx) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	return h.sendAbsences(c, currentUser.ID)
+}
+
+func (h *handlers) employeeCreateAbsence(c *fiber.Ctx) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	return h.createAbsence(c, currentUser.ID)
+}
+
+func (h *handlers) employeeDeleteAbsence(c *fiber.Ctx) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	return h.deleteAbsence(c, currentUser.ID)
+}
+
+func (h *handlers) employerGetAbsences(c *fiber.Ctx) error {
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse user id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	return h.sendAbsences(c, userID)
+}
+
+func (h *handlers) employerCreateAbsence(c *fiber.Ctx) error {
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse user id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	user, err := auth.NewDB(h.pg).FindOne(c.Context(), auth.FindOptions{IDs: []int{userID}})
+	if err != nil {
+		log.Err(err).Msg("could not find user")
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	if !user.IsEmployee() {
+		log.Error().Msg(fmt.Sprintf("user %d is not an employee", userID))
+		return fiberx.Err(c, fiber.StatusBadRequest, "only employees have absences")
+	}
+	return h.createAbsence(c, userID)
+}
+
+func (h *handlers) employerDeleteAbsence(c *fiber.Ctx) error {
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse user id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	return h.deleteAbsence(c, userID)
+}
+
+// employerGetAvailability lists the days off of the active employees, only the
+// members of the project when projectId is given, between from and to.
+func (h *handlers) employerGetAvailability(c *fiber.Ctx) error {
+	from, to, ferr := parseDayRange(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	projectID := 0
+	if v := c.Query("projectId"); v != "" {
+		var err error
+		if projectID, err = strconv.Atoi(v); err != nil {
+			log.Err(err).Msg("could not parse project id")
+			return fiberx.Err(c, fiber.StatusBadRequest)
+		}
+	}
+
+	employees, err := assignment.NewDB(h.pg).Candidates(c.Context(), projectID, time.Time{})
+	if err != nil {
+		log.Err(err).Msg("could not find employees")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	userIDs := make([]int, 0, len(employees))
+	for _, e := range employees {
+		userIDs = append(userIDs, e.UserID)
+	}
+	absences, err := availability.NewDB(h.pg).ByUser(c.Context(), userIDs, from.Time, to.Time)
+	if err != nil {
+		log.Err(err).Msg("could not find absences")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+
+	team := make([]teamMemberAvailability, 0, len(employees))
+	for _, e := range employees {
+		member := teamMemberAvailability{
+			UserID:   e.UserID,
+			Username: e.Username,
+			Absences: absences[e.UserID],
+			DaysOff:  availability.DaysOff(absences[e.UserID], from, to),
+		}
+		if member.Absences == nil {
+			member.Absences = []availability.Absence{}
+		}
+		team = append(team, member)
+	}
+	return c.JSON(fiber.Map{
+		"from":  from,
+		"to":    to,
+		"users": team,
+	})
+}
+
+func (h *handlers) sendAbsences(c *fiber.Ctx, userID int) error {
+	from, to, err := parseRange(c)
+	if err != nil {
+		log.Err(err).Msg("could not parse range")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	absences, err := availability.NewDB(h.pg).Find(c.Context(), availability.FindOptions{
+		UserIDs: []int{userID},
+		From:    from,
+		To:      to,
+	})
+	if err != nil {
+		log.Err(err).Msg("could not find absences")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"absences": absences,
+	})
+}
+
+func (h *handlers) createAbsence(c *fiber.Ctx, userID int) error {
+	var absence availability.Absence
+	if err := c.BodyParser(&absence); err != nil {
+		log.Err(err).Msg("could not parse absence request")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	absence.UserID = userID
+	if kind, err := availability.ParseKind(string(absence.Kind)); err == nil {
+		absence.Kind = kind
+	}
+	if err := absence.Validate(); err != nil {
+		log.Err(err).Msg("invalid absence")
+		return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+	}
+	if absence.BackupUserID > 0 {
+		if ferr := h.checkAssignee(c.Context(), absence.BackupUserID); ferr != nil {
+			if ferr.Code == fiber.StatusBadRequest {
+				ferr.Message = "backupUserId must be an active employee"
+			}
+			return fiberx.Err(c, ferr.Code, ferr.Message)
+		}
+	}
+
+	id, err := availability.NewDB(h.pg).Insert(c.Context(), absence)
+	if err != nil {
+		log.Err(err).Msg("could not create absence")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"absenceId": id,
+	})
+}
+
+func (h *handlers) deleteAbsence(c *fiber.Ctx, userID int) error {
+	id, err := strconv.Atoi(c.Params("absenceId"))
+	if err != nil {
+		log.Err(err).Msg("could not parse absence id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	if err := availability.NewDB(h.pg).Delete(c.Context(), userID, id); err != nil {
+		log.Err(err).Msg("could not delete absence")
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// applyAbsencePolicy applies the policy to assigning a task of the project, 0
+// for none, due at dueDate to the user, and returns the error to respond with
+// when the policy refuses it. A task is only delegated to a backup who can be
+// assigned it; it stays with the user otherwise, with a warning telling why.
+func (h *handlers) applyAbsencePolicy(ctx context.Context, pg tasks.Session, policy availability.Policy,
+	userID, projectID int, dueDate time.Time) (availability.Outcome, *fiber.Error) {
+	outcome := availability.Outcome{UserID: userID}
+	if userID == 0 || dueDate.IsZero() {
+		return outcome, nil
+	}
+	dueDate, ferr := inCalendarZone(ctx, pg, dueDate)
+	if ferr != nil {
+		return outcome, ferr
+	}
+	db := availability.NewDB(pg)
+	absences, err := db.ByUser(ctx, []int{userID}, dueDate, dueDate)
+	if err != nil {
+		log.Err(err).Msg("could not find absences")
+		return outcome, &fiber.Error{Code: fiber.StatusInternalServerError}
+	}
+	outcome, err = policy.Apply(userID, dueDate, absences[userID])
+	if err != nil {
+		log.Err(err).Msg("assignee is absent")
+		return outcome, &fiber.Error{Code: fiber.StatusConflict, Message: err.Error()}
+	}
+	if outcome.UserID == userID {
+		return outcome, nil
+	}
+
+	backupID := outcome.UserID
+	ferr = h.checkAssignee(ctx, backupID)
+	if ferr == nil && projectID > 0 {
+		ferr = h.checkProjectAssignee(ctx, projectID, backupID)
+	}
+	if ferr != nil {
+		if ferr.Code == fiber.StatusInternalServerError {
+			return outcome, ferr
+		}
+		outcome.UserID = userID
+		outcome.Warning = fmt.Sprintf("user %d is away on the due date (%s), backup user %d cannot take the task",
+			userID, outcome.Absence, backupID)
+		return outcome, nil
+	}
+	backupAbsences, err := db.ByUser(ctx, []int{backupID}, dueDate, dueDate)
+	if err != nil {
+		log.Err(err).Msg("could not find absences")
+		return outcome, &fiber.Error{Code: fiber.StatusInternalServerError}
+	}
+	if _, away := availability.Covering(backupAbsences[backupID], dueDate); away {
+		outcome.Warning += ", who is away on the due date too"
+	}
+	return outcome, nil
+}
+
+// parseDayRange parses the from and to days of the query, both included, from
+// today for two weeks by default.
+func parseDayRange(c *fiber.Ctx) (from, to dates.Date, ferr *fiber.Error) {
+	y, m, d := time.Now().UTC().Date()
+	from = dates.Date{Time: time.Date(y, m, d, 0, 0, 0, 0, time.UTC)}
+	var err error
+	if v := c.Query("from"); v != "" {
+		if from, err = dates.Parse(v); err != nil {
+			return from, to, &fiber.Error{Code: fiber.StatusBadRequest, Message: err.Error()}
+		}
+	}
+	to = dates.Date{Time: from.AddDate(0, 0, 13)}
+	if v := c.Query("to"); v != "" {
+		if to, err = dates.Parse(v); err != nil {
+			return from, to, &fiber.Error{Code: fiber.StatusBadRequest, Message: err.Error()}
+		}
+	}
+	if to.Before(from.Time) {
+		return from, to, &fiber.Error{Code: fiber.StatusBadRequest, Message: "to must not be before from"}
+	}
+	if to.Sub(from.Time) >= maxAvailabilityDays*24*time.Hour {
+		return from, to, &fiber.Error{Code: fiber.StatusBadRequest, Message: fmt.Sprintf("at most %d days at once", maxAvailabilityDays)}
+	}
+	return from, to, nil
+}
@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+
+	"siransbach/taskmanagementapi/auth"
+	"siransbach/taskmanagementapi/availability"
+	"siransbach/taskmanagementapi/calendars"
+	"siransbach/taskmanagementapi/fiberx"
+	"siransbach/taskmanagementapi/tasks"
+)
+
+// maxBatchSize is the largest number of tasks changed by a batch.
+const maxBatchSize = 1000
+
+const (
+	batchStatus   = "status"
+	batchReassign = "reassign"
+	batchDueDate  = "dueDate"
+	batchDelete   = "delete"
+)
+
+type (
+	batchRequest struct {
+		Operation string `json:"operation"`
+		// the tasks are either listed or matched by a filter expression
+		TaskIDs []int  `json:"taskIds"`
+		Filter  string `json:"filter"`
+
+		Status tasks.Status `json:"status"`
+		// 0 moves the tasks to the backlog
+		AssignedUserID *int       `json:"assignedUserId"`
+		DueDate        *time.Time `json:"dueDate"`
+		// DueIn sets the due date in business days or hours from now, instead
+		// of DueDate
+		DueIn *calendars.Offset `json:"dueIn"`
+		// AbsencePolicy applies to reassigned tasks due while the assignee is
+		// away
+		AbsencePolicy availability.Policy `json:"absencePolicy"`
+	}
+
+	// batchState is what the tasks of a batch learn from the ones before them.
+	batchState struct {
+		// the project of several tasks is only checked once
+		projectErrors map[int]*fiber.Error
+		// deleted holds the tasks deleted so far, subtasks included
+		deleted map[int]bool
+	}
+
+	batchResult struct {
+		TaskID int    `json:"taskId"`
+		Error  string `json:"error,omitempty"`
+		// AssignedUserID is who a reassigned task went to, when delegated
+		AssignedUserID int    `json:"assignedUserId,omitempty"`
+		Warning        string `json:"warning,omitempty"`
+	}
+)
+
+// employerBatchTasks changes the status, assignee or due date of many tasks at
+// once, or deletes them.
+func (h *handlers) employerBatchTasks(c *fiber.Ctx) error {
+	return h.batchTasks(c, batchStatus, batchReassign, batchDueDate, batchDelete)
+}
+
+// employeeBatchTasks changes the status of many of the employee's tasks at
+// once.
+func (h *handlers) employeeBatchTasks(c *fiber.Ctx) error {
+	return h.batchTasks(c, batchStatus)
+}
+
+// batchTasks applies the operation of the request to each of its tasks in a
+// single transaction, with the same checks as the handlers changing a task at
+// a time. Either every task is changed or, when any of them cannot be, none.
+// Employees only reach the tasks they are an assignee of.
+func (h *handlers) batchTasks(c *fiber.Ctx, operations ...string) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	var request batchRequest
+	if err := c.BodyParser(&request); err != nil {
+		log.Err(err).Msg("could not parse batch request")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	if ferr := request.validate(operations); ferr != nil {
+		log.Error().Msg("invalid batch request: " + ferr.Message)
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	if request.Operation == batchDueDate && request.DueIn != nil {
+		dueDate, ferr := dueIn(c.Context(), h.pg, *request.DueIn)
+		if ferr != nil {
+			return fiberx.Err(c, ferr.Code, ferr.Message)
+		}
+		request.DueDate = &dueDate
+	}
+	if request.Operation == batchReassign && *request.AssignedUserID > 0 {
+		if ferr := h.checkAssignee(c.Context(), *request.AssignedUserID); ferr != nil {
+			return fiberx.Err(c, ferr.Code, ferr.Message)
+		}
+	}
+
+	opts, err := request.findOptions(currentUser)
+	if err != nil {
+		log.Err(err).Msg("could not parse filter")
+		return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	tx, err := h.pg.BeginTx(c.Context(), nil)
+	if err != nil {
+		log.Err(err).Msg("could not begin transaction")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	defer func() { _ = tx.Rollback() }()
+	db := tasks.NewDB(tx)
+	found, err := db.Find(c.Context(), opts)
+	if err != nil {
+		log.Err(err).Msg("could not find tasks")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	targets, missing, ferr := request.targets(found)
+	if ferr != nil {
+		log.Error().Msg("too many tasks in batch")
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+
+	results := make([]batchResult, 0, len(targets))
+	state := batchState{projectErrors: make(map[int]*fiber.Error), deleted: make(map[int]bool)}
+	failed := false
+	for _, task := range targets {
+		result := batchResult{TaskID: task.ID}
+		switch {
+		case state.deleted[task.ID]:
+			// the task went along with its parent earlier in the batch
+		case missing[task.ID]:
+			result.Error = "task not found"
+		default:
+			if err = h.applyBatch(c, db, request, currentUser, task, state, &result); err != nil {
+				log.Err(err).Msg(fmt.Sprintf("could not apply %s to task %d", request.Operation, task.ID))
+				return fiberx.Err(c, fiber.StatusInternalServerError)
+			}
+		}
+		failed = failed || result.Error != ""
+		results = append(results, result)
+	}
+
+	if failed {
+		log.Error().Msg("batch rolled back")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "some tasks could not be changed, nothing was applied",
+			"code":    fiber.StatusBadRequest,
+			"results": results,
+		})
+	}
+	if err := tx.Commit(); err != nil {
+		log.Err(err).Msg("could not commit batch")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"results": results,
+	})
+}
+
+// applyBatch applies the operation of the request to a task, setting the error
+// of the result when the task is at fault.
+func (h *handlers) applyBatch(c *fiber.Ctx, db *tasks.DB, request batchRequest, currentUser *auth.User,
+	task tasks.Entry, state batchState, result *batchResult) error {
+	var err error
+	switch request.Operation {
+	case batchStatus:
+		if currentUser.IsEmployer() {
+			err = db.SetStatus(c.Context(), task.ID, request.Status)
+		} else {
+			err = db.UpdateStatus(c.Context(), task.ID, currentUser.ID, request.Status)
+		}
+	case batchReassign:
+		userID := *request.AssignedUserID
+		if userID > 0 && task.ProjectID > 0 {
+			ferr, checked := state.projectErrors[task.ProjectID]
+			if !checked {
+				ferr = h.checkProjectAssignee(c.Context(), task.ProjectID, userID)
+				state.projectErrors[task.ProjectID] = ferr
+			}
+			switch {
+			case ferr == nil:
+			case ferr.Code == fiber.StatusInternalServerError:
+				return errors.New("could not check project")
+			case ferr.Message == "":
+				result.Error = fmt.Sprintf("project %d does not exist", task.ProjectID)
+				return nil
+			default:
+				result.Error = ferr.Message
+				return nil
+			}
+		}
+		outcome, ferr := h.applyAbsencePolicy(c.Context(), h.pg, request.AbsencePolicy, userID, task.ProjectID, task.DueDate)
+		switch {
+		case ferr == nil:
+		case ferr.Code == fiber.StatusInternalServerError:
+			return errors.New("could not check absences")
+		default:
+			result.Error = ferr.Message
+			return nil
+		}
+		if outcome.UserID != userID {
+			result.AssignedUserID = outcome.UserID
+		}
+		result.Warning = outcome.Warning
+		err = db.Reassign(c.Context(), task.ID, outcome.UserID)
+	case batchDueDate:
+		err = db.SetDueDate(c.Context(), task.ID, *request.DueDate)
+	case batchDelete:
+		var ids []int
+		ids, err = db.DeleteTree(c.Context(), task.ID)
+		for _, id := range ids {
+			state.deleted[id] = true
+		}
+	}
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// deleted since the batch found it
+		result.Error = "task not found"
+		return nil
+	case errors.Is(err, tasks.ErrTimeLogged), errors.Is(err, tasks.ErrDueDateExpired):
+		result.Error = err.Error()
+		return nil
+	}
+	return err
+}
+
+// findOptions finds the tasks of the request, the listed ones or the ones
+// matching its filter. Employees only reach the tasks they are an assignee of.
+func (r batchRequest) findOptions(currentUser *auth.User) (tasks.FindOptions, error) {
+	opts := tasks.FindOptions{
+		IDs:    r.TaskIDs,
+		Fields: []string{"projectId", "dueDate"},
+		// one more to tell when a filter matches too many tasks
+		Limit: maxBatchSize + 1,
+	}
+	if r.Filter != "" {
+		filter, err := tasks.ParseFilter(r.Filter)
+		if err != nil {
+			return opts, err
+		}
+		opts.Filter = filter
+	}
+	if !currentUser.IsEmployer() {
+		opts.AnyAssigneeIDs = []int{currentUser.ID}
+	}
+	return opts, nil
+}
+
+// targets returns the tasks to apply the request to among the ones found.
+// Listed tasks keep their order, the ones that were not found included and
+// marked missing.
+func (r batchRequest) targets(found []tasks.Entry) (targets []tasks.Entry, missing map[int]bool, ferr *fiber.Error) {
+	if len(found) > maxBatchSize {
+		return nil, nil, &fiber.Error{Code: fiber.StatusBadRequest, Message: fmt.Sprintf("the filter matches more than %d tasks", maxBatchSize)}
+	}
+	missing = make(map[int]bool)
+	if len(r.TaskIDs) == 0 {
+		return found, missing, nil
+	}
+	byID := make(map[int]tasks.Entry, len(found))
+	for _, task := range found {
+		byID[task.ID] = task
+	}
+	targets = make([]tasks.Entry, 0, len(r.TaskIDs))
+	seen := make(map[int]bool, len(r.TaskIDs))
+	for _, id := range r.TaskIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		task, ok := byID[id]
+		if !ok {
+			task.ID, missing[id] = id, true
+		}
+		targets = append(targets, task)
+	}
+	return targets, missing, nil
+}
+
+// validate makes sure that the request has one of the operations along with
+// its argument, and either task IDs or a filter.
+func (r *batchRequest) validate(operations []string) *fiber.Error {
+	allowed := false
+	for _, op := range operations {
+		allowed = allowed || r.Operation == op
+	}
+	if !allowed {
+		return &fiber.Error{Code: fiber.StatusBadRequest, Message: "invalid operation: " + r.Operation}
+	}
+	if (len(r.TaskIDs) > 0) == (r.Filter != "") {
+		return &fiber.Error{Code: fiber.StatusBadRequest, Message: "expected either taskIds or a filter"}
+	}
+	if len(r.TaskIDs) > maxBatchSize {
+		return &fiber.Error{Code: fiber.StatusBadRequest, Message: fmt.Sprintf("at most %d tasks can be changed at once", maxBatchSize)}
+	}
+	switch r.Operation {
+	case batchStatus:
+		status, err := tasks.ParseStatus(string(r.Status))
+		if err != nil {
+			return &fiber.Error{Code: fiber.StatusBadRequest, Message: err.Error()}
+		}
+		r.Status = status
+	case batchReassign:
+		if r.AssignedUserID == nil || *r.AssignedUserID < 0 {
+			return &fiber.Error{Code: fiber.StatusBadRequest, Message: "missing assignedUserId"}
+		}
+		policy, err := availability.ParsePolicy(string(r.AbsencePolicy))
+		if err != nil {
+			return &fiber.Error{Code: fiber.StatusBadRequest, Message: err.Error()}
+		}
+		r.AbsencePolicy = policy
+	case batchDueDate:
+		if (r.DueDate == nil) == (r.DueIn == nil) {
+			return &fiber.Error{Code: fiber.StatusBadRequest, Message: "expected either dueDate or dueIn"}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"siransbach/taskmanagementapi/auth"
+	"siransbach/taskmanagementapi/postgres"
+	"siransbach/taskmanagementapi/tasks"
+)
+
+var (
+	testEmployee = &auth.User{ID: 1, Username: "Radahn", Role: auth.RoleEmployee, Active: true}
+	testEmployer = &auth.User{ID: 3, Username: "Tarnished", Role: auth.RoleEmployer, Active: true}
+)
+
+func TestBatchRequest_FindOptions(t *testing.T) {
+	request := batchRequest{Filter: "status = PENDING"}
+	opts, err := request.findOptions(testEmployee)
+	if err != nil {
+		t.Fatalf("could not find options: %v", err)
+	}
+	if !reflect.DeepEqual(opts.AnyAssigneeIDs, []int{testEmployee.ID}) {
+		t.Errorf("expected employees to reach their tasks only, got %v", opts.AnyAssigneeIDs)
+	}
+	if opts.Filter == nil || opts.Limit != maxBatchSize+1 {
+		t.Errorf("expected the filter and a limit of %d, got %+v", maxBatchSize+1, opts)
+	}
+
+	if opts, err = request.findOptions(testEmployer); err != nil {
+		t.Fatalf("could not find options: %v", err)
+	}
+	if opts.AnyAssigneeIDs != nil {
+		t.Errorf("expected employers to reach every task, got %v", opts.AnyAssigneeIDs)
+	}
+
+	if _, err := (batchRequest{Filter: "status ="}).findOptions(testEmployer); err == nil {
+		t.Error("expected an invalid filter to be rejected")
+	}
+}
+
+func TestBatchRequest_Targets(t *testing.T) {
+	found := []tasks.Entry{{ID: 1}, {ID: 2}, {ID: 3}}
+
+	targets, missing, ferr := batchRequest{TaskIDs: []int{3, 4, 1, 3}}.targets(found)
+	if ferr != nil {
+		t.Fatalf("unexpected error: %v", ferr)
+	}
+	var ids []int
+	for _, task := range targets {
+		ids = append(ids, task.ID)
+	}
+	if !reflect.DeepEqual(ids, []int{3, 4, 1}) {
+		t.Errorf("expected the listed tasks in order once, got %v", ids)
+	}
+	if !reflect.DeepEqual(missing, map[int]bool{4: true}) {
+		t.Errorf("expected task 4 to be missing, got %v", missing)
+	}
+
+	if targets, _, _ = (batchRequest{Filter: "status = PENDING"}).targets(found); len(targets) != len(found) {
+		t.Errorf("expected every task found by a filter, got %v", targets)
+	}
+
+	tooMany := make([]tasks.Entry, maxBatchSize+1)
+	if _, _, ferr := (batchRequest{Filter: "status = PENDING"}).targets(tooMany); ferr == nil ||
+		ferr.Code != fiber.StatusBadRequest {
+		t.Errorf("expected a filter matching more than %d tasks to be rejected, got %v", maxBatchSize, ferr)
+	}
+}
+
+// batchApp serves the batch endpoints to user without authentication.
+func batchApp(t *testing.T, user *auth.User) *fiber.App {
+	t.Helper()
+	pg, err := postgres.Connect(postgres.ConnectionString())
+	if err != nil {
+		panic(err)
+	}
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", user)
+		return c.Next()
+	})
+	h := handlers{pg: pg}
+	app.Post("/employee/tasks/batch", h.employeeBatchTasks)
+	app.Post("/employer/tasks/batch", h.employerBatchTasks)
+	return app
+}
+
+func postBatch(t *testing.T, app *fiber.App, path, body string) (int, []batchResult) {
+	t.Helper()
+	req := httptest.NewRequest(fiber.MethodPost, path, strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		panic(err)
+	}
+	defer resp.Body.Close()
+	var response struct {
+		Results []batchResult `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		panic(err)
+	}
+	return resp.StatusCode, response.Results
+}
+
+func insertTestTask(t *testing.T, db *tasks.DB, entry tasks.Entry) int {
+	t.Helper()
+	entry.DueDate = time.Now().Add(24 * time.Hour)
+	id, err := db.Insert(context.Background(), entry)
+	if err != nil {
+		panic(err)
+	}
+	t.Cleanup(func() { _ = db.Delete(context.Background(), id) })
+	return id
+}
+
+func TestDB_BatchTasks_Scope(t *testing.T) {
+	pg, err := postgres.Connect(postgres.ConnectionString())
+	if err != nil {
+		panic(err)
+	}
+	db := tasks.NewDB(pg)
+	own := insertTestTask(t, db, tasks.Entry{Title: "Own task", AssignedUserID: testEmployee.ID})
+	other := insertTestTask(t, db, tasks.Entry{Title: "Other task", AssignedUserID: 2})
+	body := `{"operation": "status", "status": "IN_PROGRESS", "taskIds": [` +
+		strconv.Itoa(own) + `, ` + strconv.Itoa(other) + `]}`
+
+	status, results := postBatch(t, batchApp(t, testEmployee), "/employee/tasks/batch", body)
+	if status != fiber.StatusBadRequest || len(results) != 2 || results[0].Error != "" ||
+		results[1].Error != "task not found" {
+		t.Errorf("expected the task of another employee to be out of reach, got %d %+v", status, results)
+	}
+
+	status, results = postBatch(t, batchApp(t, testEmployer), "/employer/tasks/batch", body)
+	if status != fiber.StatusOK || len(results) != 2 {
+		t.Errorf("expected the employer to reach both tasks, got %d %+v", status, results)
+	}
+}
+
+func TestDB_BatchTasks_Rollback(t *testing.T) {
+	pg, err := postgres.Connect(postgres.ConnectionString())
+	if err != nil {
+		panic(err)
+	}
+	db := tasks.NewDB(pg)
+	id := insertTestTask(t, db, tasks.Entry{Title: "Rolled back", AssignedUserID: testEmployee.ID})
+
+	status, results := postBatch(t, batchApp(t, testEmployer), "/employer/tasks/batch",
+		`{"operation": "status", "status": "COMPLETED", "taskIds": [`+strconv.Itoa(id)+`, 2147483647]}`)
+	if status != fiber.StatusBadRequest || len(results) != 2 || results[1].Error != "task not found" {
+		t.Errorf("expected the batch to fail on the missing task, got %d %+v", status, results)
+	}
+	task, err := db.FindOne(context.Background(), tasks.FindOptions{IDs: []int{id}})
+	if err != nil {
+		panic(err)
+	}
+	if task.Status != tasks.StatusPending {
+		t.Errorf("expected the batch to be rolled back, got status %s", task.Status)
+	}
+}
+
+func TestDB_BatchTasks_DeleteSubtasks(t *testing.T) {
+	pg, err := postgres.Connect(postgres.ConnectionString())
+	if err != nil {
+		panic(err)
+	}
+	db := tasks.NewDB(pg)
+	parent := insertTestTask(t, db, tasks.Entry{Title: "Parent"})
+	child := insertTestTask(t, db, tasks.Entry{Title: "Child", ParentTaskID: parent})
+
+	status, results := postBatch(t, batchApp(t, testEmployer), "/employer/tasks/batch",
+		`{"operation": "delete", "taskIds": [`+strconv.Itoa(parent)+`, `+strconv.Itoa(child)+`]}`)
+	if status != fiber.StatusOK || len(results) != 2 || results[0].Error != "" || results[1].Error != "" {
+		t.Errorf("expected the parent and its subtask to be deleted, got %d %+v", status, results)
+	}
+	found, err := db.Find(context.Background(), tasks.FindOptions{IDs: []int{parent, child}})
+	if err != nil {
+		panic(err)
+	}
+	if len(found) != 0 {
+		t.Errorf("expected no task left, got %+v", found)
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+
+	"siransbach/taskmanagementapi/auth"
+	"siransbach/taskmanagementapi/export"
+	"siransbach/taskmanagementapi/fiberx"
+	"siransbach/taskmanagementapi/tasks"
+)
+
+// CalendarFeedPath is where calendar feeds are served. Calendar apps cannot
+// send credentials, so the token in the path authenticates the requests
+// instead of Basic auth.
+const CalendarFeedPath = "/api/" + apiVersion + "/feeds/"
+
+// createCalendarToken generates the secret token of the current user's
+// calendar feed, revoking the previous one.
+func (h *handlers) createCalendarToken(c *fiber.Ctx) error {
+	user, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	token, err := auth.NewDB(h.pg).SetCalendarToken(c.Context(), user.ID)
+	if err != nil {
+		log.Err(err).Msg("could not set calendar token")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"token": token,
+		"url":   c.BaseURL() + CalendarFeedPath + token + ".ics",
+	})
+}
+
+func (h *handlers) deleteCalendarToken(c *fiber.Ctx) error {
+	user, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	if err := auth.NewDB(h.pg).DeleteCalendarToken(c.Context(), user.ID); err != nil {
+		log.Err(err).Msg("could not delete calendar token")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// getCalendarFeed serves the calendar of the tasks of the token's user: the
+// tasks they are an assignee of for employees, the tasks of the whole team for
+// employers.
+func (h *handlers) getCalendarFeed(c *fiber.Ctx) error {
+	token := strings.TrimSuffix(c.Params("token"), ".ics")
+	user, err := auth.NewDB(h.pg).FindByCalendarToken(c.Context(), token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Error().Msg("unknown calendar token")
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		log.Err(err).Msg("could not find calendar token")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	component := export.ComponentTodo
+	if v := c.Query("component"); v != "" {
+		if component, err = export.ParseComponent(v); err != nil {
+			log.Err(err).Msg("could not parse component")
+			return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+		}
+	}
+
+	var opts tasks.FindOptions
+	if opts.ProjectIDs, err = queryIDs(c, "projectId"); err != nil {
+		log.Err(err).Msg("could not parse projectId")
+		return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+	}
+	name := "Tasks of " + user.Username
+	if user.IsEmployer() {
+		if opts.AnyAssigneeIDs, err = queryIDs(c, "assignedUserId"); err != nil {
+			log.Err(err).Msg("could not parse assignedUserId")
+			return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+		}
+		name = "Team tasks"
+	} else {
+		opts.AnyAssigneeIDs = []int{user.ID}
+	}
+
+	// calendar apps poll the feed, which must always be current
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	h.streamTasks(c, export.FormatICal, opts, func(w io.Writer) export.Writer {
+		return export.NewICalWriter(w, component, name)
+	})
+	return nil
+}
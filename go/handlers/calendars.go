@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+
+	"siransbach/taskmanagementapi/calendars"
+	"siransbach/taskmanagementapi/fiberx"
+	"siransbach/taskmanagementapi/tasks"
+)
+
+func (h *handlers) employerGetCalendars(c *fiber.Ctx) error {
+	found, err := calendars.NewDB(h.pg).Find(c.Context(), calendars.FindOptions{})
+	if err != nil {
+		log.Err(err).Msg("could not get calendars")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"calendars": found,
+	})
+}
+
+func (h *handlers) employerCreateCalendar(c *fiber.Ctx) error {
+	var cal calendars.Calendar
+	if err := c.BodyParser(&cal); err != nil {
+		log.Err(err).Msg("could not parse calendar request")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	if err := cal.Validate(); err != nil {
+		log.Err(err).Msg("invalid calendar")
+		return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	tx, err := h.pg.BeginTx(c.Context(), nil)
+	if err != nil {
+		log.Err(err).Msg("could not begin transaction")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	defer func() { _ = tx.Rollback() }()
+	id, err := calendars.NewDB(tx).Insert(c.Context(), cal)
+	if err != nil {
+		log.Err(err).Msg("could not create calendar")
+		if errors.Is(err, calendars.ErrCalendarExists) {
+			return fiberx.Err(c, fiber.StatusConflict, err.Error())
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Err(err).Msg("could not commit calendar")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"calendarId": id,
+	})
+}
+
+// employerUpdateCalendar replaces a calendar, its holidays included.
+func (h *handlers) employerUpdateCalendar(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse calendar id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	var cal calendars.Calendar
+	if err := c.BodyParser(&cal); err != nil {
+		log.Err(err).Msg("could not parse calendar request")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	cal.ID = id
+	if err := cal.Validate(); err != nil {
+		log.Err(err).Msg("invalid calendar")
+		return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	tx, err := h.pg.BeginTx(c.Context(), nil)
+	if err != nil {
+		log.Err(err).Msg("could not begin transaction")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err := calendars.NewDB(tx).Update(c.Context(), cal); err != nil {
+		log.Err(err).Msg("could not update calendar")
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return fiberx.Err(c, fiber.StatusNotFound)
+		case errors.Is(err, calendars.ErrCalendarExists):
+			return fiberx.Err(c, fiber.StatusConflict, err.Error())
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Err(err).Msg("could not commit calendar")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (h *handlers) employerDeleteCalendar(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse calendar id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	if err := calendars.NewDB(h.pg).Delete(c.Context(), id); err != nil {
+		log.Err(err).Msg("could not delete calendar")
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// dueIn returns the due date the offset gives from now, and the error to
+// respond with when its calendar does not exist.
+func dueIn(ctx context.Context, pg tasks.Session, offset calendars.Offset) (time.Time, *fiber.Error) {
+	if err := offset.Validate(); err != nil {
+		log.Err(err).Msg("invalid dueIn")
+		return time.Time{}, &fiber.Error{Code: fiber.StatusBadRequest, Message: err.Error()}
+	}
+	cal, err := calendars.NewDB(pg).Named(ctx, offset.Calendar)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Error().Msg("unknown calendar: " + offset.Calendar)
+			return time.Time{}, &fiber.Error{Code: fiber.StatusBadRequest, Message: "unknown calendar: " + offset.Calendar}
+		}
+		log.Err(err).Msg("could not find calendar")
+		return time.Time{}, &fiber.Error{Code: fiber.StatusInternalServerError}
+	}
+	return cal.Due(time.Now(), offset), nil
+}
+
+// inCalendarZone returns t in the time zone of the default calendar, whose days
+// absences cover.
+func inCalendarZone(ctx context.Context, pg tasks.Session, t time.Time) (time.Time, *fiber.Error) {
+	cal, err := calendars.NewDB(pg).Default(ctx)
+	if err != nil {
+		log.Err(err).Msg("could not find default calendar")
+		return time.Time{}, &fiber.Error{Code: fiber.StatusInternalServerError}
+	}
+	return t.In(cal.Location()), nil
+}
+
+// overdueBefore returns when tasks due before are overdue: now during the
+// working hours of the default calendar, the end of the last ones otherwise.
+func overdueBefore(ctx context.Context, pg tasks.Session) (time.Time, *fiber.Error) {
+	cal, err := calendars.NewDB(pg).Default(ctx)
+	if err != nil {
+		log.Err(err).Msg("could not find default calendar")
+		return time.Time{}, &fiber.Error{Code: fiber.StatusInternalServerError}
+	}
+	return cal.LastWorkingTime(time.Now()), nil
+}
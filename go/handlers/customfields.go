@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+
+	"siransbach/taskmanagementapi/fiberx"
+	"siransbach/taskmanagementapi/projects"
+	"siransbach/taskmanagementapi/tasks"
+)
+
+func (h *handlers) employerGetCustomFields(c *fiber.Ctx) error {
+	p, ferr := h.findProject(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	fields, err := projects.NewDB(h.pg).FindCustomFields(c.Context(), projects.CustomFieldFindOptions{ProjectIDs: []int{p.ID}})
+	if err != nil {
+		log.Err(err).Msg("could not find custom fields")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	if fields == nil {
+		fields = []projects.CustomField{}
+	}
+	return c.JSON(fiber.Map{
+		"customFields": fields,
+	})
+}
+
+func (h *handlers) employerCreateCustomField(c *fiber.Ctx) error {
+	p, ferr := h.findProject(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	var request struct {
+		Key      string   `json:"key"`
+		Name     string   `json:"name"`
+		Type     string   `json:"type"`
+		Options  []string `json:"options"`
+		Required bool     `json:"required"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		log.Err(err).Msg("could not parse custom field request")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	fieldType, err := tasks.ParseFieldType(request.Type)
+	if err != nil {
+		log.Err(err).Msg("could not parse custom field type")
+		return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+	}
+	f := projects.CustomField{
+		ProjectID: p.ID,
+		Key:       request.Key,
+		Name:      request.Name,
+		Type:      fieldType,
+		Options:   request.Options,
+		Required:  request.Required,
+	}
+	if err := f.Validate(); err != nil {
+		log.Err(err).Msg("invalid custom field")
+		return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+	}
+	id, err := projects.NewDB(h.pg).InsertCustomField(c.Context(), f)
+	if err != nil {
+		log.Err(err).Msg("could not create custom field")
+		if errors.Is(err, projects.ErrCustomFieldExists) {
+			return fiberx.Err(c, fiber.StatusConflict, err.Error())
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"customFieldId": id,
+	})
+}
+
+func (h *handlers) employerUpdateCustomField(c *fiber.Ctx) error {
+	f, ferr := h.findCustomField(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	var request struct {
+		Name     *string  `json:"name"`
+		Options  []string `json:"options"`
+		Required *bool    `json:"required"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		log.Err(err).Msg("could not parse custom field request")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	if request.Name != nil {
+		f.Name = *request.Name
+	}
+	if request.Options != nil {
+		f.Options = request.Options
+	}
+	if request.Required != nil {
+		f.Required = *request.Required
+	}
+	if err := f.Validate(); err != nil {
+		log.Err(err).Msg("invalid custom field")
+		return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+	}
+	if err := projects.NewDB(h.pg).UpdateCustomField(c.Context(), f); err != nil {
+		log.Err(err).Msg("could not update custom field")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (h *handlers) employerDeleteCustomField(c *fiber.Ctx) error {
+	f, ferr := h.findCustomField(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	if err := projects.NewDB(h.pg).DeleteCustomField(c.Context(), f.ProjectID, f.ID); err != nil {
+		log.Err(err).Msg("could not delete custom field")
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// employerSetCustomFieldValues sets the values of some of the custom fields of
+// a task, keyed by field key. A null value clears the field.
+func (h *handlers) employerSetCustomFieldValues(c *fiber.Ctx) error {
+	taskID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse task id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	var request map[string]json.RawMessage
+	if err := c.BodyParser(&request); err != nil {
+		log.Err(err).Msg("could not parse custom field values")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	db := tasks.NewDB(h.pg)
+	task, err := db.FindOne(c.Context(), tasks.FindOptions{IDs: []int{taskID}})
+	if err != nil {
+		log.Err(err).Msg("could not find task")
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	values, ferr := h.customFieldValues(c.Context(), task.ProjectID, request, false)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	if err := db.SetCustomFieldValues(c.Context(), task.ID, values); err != nil {
+		log.Err(err).Msg("could not set custom field values")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// customFieldValues validates values, keyed by field key, against the custom
+// fields of a project and returns them keyed by field ID. When creating a
+// task, all the required fields must be given.
+func (h *handlers) customFieldValues(ctx context.Context, projectID int, values map[string]json.RawMessage, creating bool) (map[int]json.RawMessage, *fiber.Error) {
+	if len(values) == 0 && !creating {
+		return nil, nil
+	}
+	if projectID == 0 {
+		if len(values) == 0 {
+			return nil, nil
+		}
+		return nil, &fiber.Error{Code: fiber.StatusBadRequest, Message: "custom fields are only available on project tasks"}
+	}
+	fields, err := projects.NewDB(h.pg).FindCustomFields(ctx, projects.CustomFieldFindOptions{ProjectIDs: []int{projectID}})
+	if err != nil {
+		log.Err(err).Msg("could not find custom fields")
+		return nil, &fiber.Error{Code: fiber.StatusInternalServerError}
+	}
+
+	byKey := make(map[string]projects.CustomField, len(fields))
+	for _, f := range fields {
+		byKey[f.Key] = f
+		if _, ok := values[f.Key]; creating && f.Required && !ok {
+			return nil, &fiber.Error{Code: fiber.StatusBadRequest, Message: fmt.Sprintf("%s: value is required", f.Key)}
+		}
+	}
+	normalized := make(map[int]json.RawMessage, len(values))
+	for key, value := range values {
+		f, ok := byKey[key]
+		if !ok {
+			return nil, &fiber.Error{Code: fiber.StatusBadRequest, Message: fmt.Sprintf("unknown custom field: %s", key)}
+		}
+		v, err := f.NormalizeValue(value)
+		if err != nil {
+			log.Err(err).Msg("invalid custom field value")
+			return nil, &fiber.Error{Code: fiber.StatusBadRequest, Message: err.Error()}
+		}
+		if f.Type == tasks.FieldTypeUser && string(v) != "null" {
+			userID, _ := strconv.Atoi(string(v))
+			if ferr := h.checkProjectAssignee(ctx, projectID, userID); ferr != nil {
+				return nil, ferr
+			}
+		}
+		normalized[f.ID] = v
+	}
+	return normalized, nil
+}
+
+// customFieldQuery parses the cf.<key> filters and the cf.<key> sortBy of a
+// task search. The type of each field is looked up among the given projects,
+// or all of them, and must be the same wherever the key is defined.
+func (h *handlers) customFieldQuery(c *fiber.Ctx, projectIDs []int) ([]tasks.CustomFieldFilter, *tasks.CustomFieldSort, *fiber.Error) {
+	exprs := make(map[string]string)
+	for param, value := range c.Queries() {
+		if key, ok := tasks.ParseCustomFieldKey(param); ok {
+			exprs[key] = value
+		}
+	}
+	sortKey, sorted := tasks.ParseCustomFieldKey(c.Query("sortBy"))
+	if len(exprs) == 0 && !sorted {
+		return nil, nil, nil
+	}
+
+	keys := make([]string, 0, len(exprs)+1)
+	for key := range exprs {
+		keys = append(keys, key)
+	}
+	if sorted {
+		keys = append(keys, sortKey)
+	}
+	fields, err := projects.NewDB(h.pg).FindCustomFields(c.Context(), projects.CustomFieldFindOptions{
+		ProjectIDs: projectIDs,
+		Keys:       keys,
+	})
+	if err != nil {
+		log.Err(err).Msg("could not find custom fields")
+		return nil, nil, &fiber.Error{Code: fiber.StatusInternalServerError}
+	}
+	types := make(map[string]tasks.FieldType)
+	for _, f := range fields {
+		if t, ok := types[f.Key]; ok && t != f.Type {
+			return nil, nil, &fiber.Error{
+				Code:    fiber.StatusBadRequest,
+				Message: fmt.Sprintf("custom field %s has different types across projects, filter by projectId", f.Key),
+			}
+		}
+		types[f.Key] = f.Type
+	}
+	for _, key := range keys {
+		if _, ok := types[key]; !ok {
+			return nil, nil, &fiber.Error{Code: fiber.StatusBadRequest, Message: fmt.Sprintf("unknown custom field: %s", key)}
+		}
+	}
+
+	// sorted so that the generated query is stable
+	sort.Strings(keys[:len(exprs)])
+	var filters []tasks.CustomFieldFilter
+	for _, key := range keys[:len(exprs)] {
+		filter, err := tasks.ParseCustomFieldFilter(key, types[key], exprs[key])
+		if err != nil {
+			log.Err(err).Msg("could not parse custom field filter")
+			return nil, nil, &fiber.Error{Code: fiber.StatusBadRequest, Message: err.Error()}
+		}
+		filters = append(filters, filter)
+	}
+	var sortBy *tasks.CustomFieldSort
+	if sorted {
+		sortBy = &tasks.CustomFieldSort{Key: sortKey, Type: types[sortKey]}
+	}
+	return filters, sortBy, nil
+}
+
+func (h *handlers) findCustomField(c *fiber.Ctx) (projects.CustomField, *fiber.Error) {
+	p, ferr := h.findProject(c)
+	if ferr != nil {
+		return projects.CustomField{}, ferr
+	}
+	fieldID, err := strconv.Atoi(c.Params("fieldId"))
+	if err != nil {
+		log.Err(err).Msg("could not parse custom field id")
+		return projects.CustomField{}, &fiber.Error{Code: fiber.StatusBadRequest}
+	}
+	f, err := projects.NewDB(h.pg).FindCustomField(c.Context(), projects.CustomFieldFindOptions{
+		IDs:        []int{fieldID},
+		ProjectIDs: []int{p.ID},
+	})
+	if err != nil {
+		log.Err(err).Msg("could not find custom field")
+		if errors.Is(err, sql.ErrNoRows) {
+			return projects.CustomField{}, &fiber.Error{Code: fiber.StatusNotFound}
+		}
+		return projects.CustomField{}, &fiber.Error{Code: fiber.StatusInternalServerError}
+	}
+	return f, nil
+}
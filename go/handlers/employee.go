@@ -19,17 +19,34 @@ func (h *handlers) employeeGetTasks(c *fiber.Ctx) error {
 		log.Err(err).Msg("could not get current user")
 		return fiberx.Err(c, fiber.StatusUnauthorized)
 	}
-	entries, err := tasks.NewDB(h.pg).Find(c.Context(),
-		tasks.FindOptions{
-			AssignedUserIDs: []int{user.ID},
-		},
-	)
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		log.Err(err).Msg("could not find tasks")
-		return fiberx.Err(c, fiber.StatusInternalServerError)
+	opts := tasks.FindOptions{
+		AnyAssigneeIDs: []int{user.ID},
+	}
+	if err := parseTaskFilters(c, &opts); err != nil {
+		log.Err(err).Msg("could not parse task filters")
+		return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+	}
+	if opts.Overdue {
+		var ferr *fiber.Error
+		if opts.OverdueBefore, ferr = overdueBefore(c.Context(), h.pg); ferr != nil {
+			return fiberx.Err(c, ferr.Code, ferr.Message)
+		}
+	}
+	opts, ferr := h.applyDefaultView(c, user.ID, opts)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	return h.sendTaskPage(c, opts)
+}
+
+func (h *handlers) employeeGetWatchedTasks(c *fiber.Ctx) error {
+	user, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
 	}
-	return c.JSON(fiber.Map{
-		"tasks": entries,
+	return h.sendTaskPage(c, tasks.FindOptions{
+		WatcherIDs: []int{user.ID},
 	})
 }
 
@@ -49,7 +66,7 @@ func (h *handlers) employeeUpdateTaskStatus(c *fiber.Ctx) error {
 		log.Err(err).Msg("could not parse task status")
 		return fiberx.Err(c, fiber.StatusBadRequest)
 	}
-	if err := tasks.NewDB(h.pg).UpdateStatus(c.Context(), currentUser.ID, taskID, status); err != nil {
+	if err := tasks.NewDB(h.pg).UpdateStatus(c.Context(), taskID, currentUser.ID, status); err != nil {
 		log.Err(err).Msg("could not update task status")
 		if errors.Is(err, sql.ErrNoRows) {
 			return fiberx.Err(c, fiber.StatusNotFound)
@@ -59,3 +76,79 @@ func (h *handlers) employeeUpdateTaskStatus(c *fiber.Ctx) error {
 
 	return c.SendStatus(fiber.StatusOK)
 }
+
+func (h *handlers) employeeUpdateChecklistItem(c *fiber.Ctx) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	taskID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse task id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	itemID, err := strconv.Atoi(c.Params("itemId"))
+	if err != nil {
+		log.Err(err).Msg("could not parse checklist item id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	var request struct {
+		Done bool `json:"done"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		log.Err(err).Msg("could not parse checklist item request")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	if err := tasks.NewDB(h.pg).SetChecklistItemDone(c.Context(), taskID, itemID, currentUser.ID, request.Done); err != nil {
+		log.Err(err).Msg("could not update checklist item")
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// employeeGetBacklog lists the unassigned tasks the employee can claim, those
+// of the projects they are not a member of left out.
+func (h *handlers) employeeGetBacklog(c *fiber.Ctx) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	return h.sendTaskPage(c, tasks.FindOptions{
+		Unassigned:      true,
+		ProjectMemberID: currentUser.ID,
+		Statuses:        []tasks.Status{tasks.StatusPending, tasks.StatusInProgress},
+		SortBy:          tasks.DueDateCol,
+	})
+}
+
+func (h *handlers) employeeClaimTask(c *fiber.Ctx) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	taskID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse task id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	if err := tasks.NewDB(h.pg).Claim(c.Context(), taskID, currentUser.ID); err != nil {
+		log.Err(err).Msg("could not claim task")
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		if errors.Is(err, tasks.ErrAlreadyClaimed) {
+			return fiberx.Err(c, fiber.StatusConflict, err.Error())
+		}
+		if errors.Is(err, tasks.ErrNotProjectMember) {
+			return fiberx.Err(c, fiber.StatusForbidden, err.Error())
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -1,142 +1,403 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/rs/zerolog/log"
 
+	"siransbach/taskmanagementapi/assignment"
 	"siransbach/taskmanagementapi/auth"
+	"siransbach/taskmanagementapi/availability"
+	"siransbach/taskmanagementapi/calendars"
 	"siransbach/taskmanagementapi/fiberx"
 	"siransbach/taskmanagementapi/tasks"
 )
 
 func (h *handlers) employerCreateTask(c *fiber.Ctx) error {
 	var taskRequest struct {
-		Title          string    `json:"title"`
-		Description    string    `json:"description"`
-		AssignedUserID int       `json:"assignedUserID"`
-		DueDate        time.Time `json:"dueDate"`
+		Title          string         `json:"title"`
+		Description    string         `json:"description"`
+		AssignedUserID int            `json:"assignedUserID"`
+		DueDate        time.Time      `json:"dueDate"`
+		Priority       tasks.Priority `json:"priority"`
+		Labels         []string       `json:"labels"`
+		Checklist      []string       `json:"checklist"`
+		ProjectID      int            `json:"projectId"`
+		// CustomFields holds values of the project's custom fields, by key
+		CustomFields map[string]json.RawMessage `json:"customFields"`
+		// in minutes
+		OriginalEstimate  *int `json:"originalEstimate"`
+		RemainingEstimate *int `json:"remainingEstimate"`
+		// AutoAssign names the strategy picking the assignee, instead of
+		// AssignedUserID
+		AutoAssign string `json:"autoAssign"`
+		// AbsencePolicy tells what to do when the assignee is away on the due
+		// date: warn (default), refuse or delegate to their backup
+		AbsencePolicy string `json:"absencePolicy"`
+		// DueIn sets the due date in business days or hours, instead of
+		// DueDate
+		DueIn *calendars.Offset `json:"dueIn"`
 	}
 	if err := c.BodyParser(&taskRequest); err != nil {
 		log.Err(err).Msg("could not parse task request")
 		return fiberx.Err(c, fiber.StatusBadRequest)
 	}
 
+	if taskRequest.DueIn != nil {
+		if !taskRequest.DueDate.IsZero() {
+			log.Error().Msg("both dueDate and dueIn given")
+			return fiberx.Err(c, fiber.StatusBadRequest, "dueDate and dueIn are exclusive")
+		}
+		dueDate, ferr := dueIn(c.Context(), h.pg, *taskRequest.DueIn)
+		if ferr != nil {
+			return fiberx.Err(c, ferr.Code, ferr.Message)
+		}
+		taskRequest.DueDate = dueDate
+	}
+
+	policy, err := availability.ParsePolicy(taskRequest.AbsencePolicy)
+	if err != nil {
+		log.Err(err).Msg("could not parse absence policy")
+		return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+	}
+	if taskRequest.Priority != "" {
+		if taskRequest.Priority, err = tasks.ParsePriority(string(taskRequest.Priority)); err != nil {
+			log.Err(err).Msg("could not parse priority")
+			return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+		}
+	}
+	var strategy assignment.Strategy
+	if taskRequest.AutoAssign != "" {
+		if taskRequest.AssignedUserID > 0 {
+			log.Error().Msg("both assignedUserID and autoAssign given")
+			return fiberx.Err(c, fiber.StatusBadRequest, "assignedUserID and autoAssign are exclusive")
+		}
+		if strategy, err = assignment.ParseStrategy(taskRequest.AutoAssign); err != nil {
+			log.Err(err).Msg("could not parse strategy")
+			return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+		}
+	}
+
 	if taskRequest.AssignedUserID > 0 {
-		user, err := auth.NewDB(h.pg).FindOne(c.Context(), auth.FindOptions{
-			IDs: []int{taskRequest.AssignedUserID},
-		})
-		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				log.Error().Msg(fmt.Sprintf("user %d does not exist", taskRequest.AssignedUserID))
-				return fiberx.Err(c, fiber.StatusBadRequest)
-			}
-			log.Err(err).Msg("could not find user")
-			return fiberx.Err(c, fiber.StatusInternalServerError)
+		if ferr := h.checkAssignee(c.Context(), taskRequest.AssignedUserID); ferr != nil {
+			return fiberx.Err(c, ferr.Code, ferr.Message)
 		}
-		if user.IsEmployer() {
-			log.Error().Msg(fmt.Sprintf("user %d is not an employee", taskRequest.AssignedUserID))
-			return fiberx.Err(c, fiber.StatusBadRequest, "assignedUserID is not an employee")
+	}
+	if taskRequest.ProjectID > 0 {
+		if ferr := h.checkProjectAssignee(c.Context(), taskRequest.ProjectID, taskRequest.AssignedUserID); ferr != nil {
+			return fiberx.Err(c, ferr.Code, ferr.Message)
 		}
 	}
+	outcome, ferr := h.applyAbsencePolicy(c.Context(), h.pg, policy,
+		taskRequest.AssignedUserID, taskRequest.ProjectID, taskRequest.DueDate)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	taskRequest.AssignedUserID = outcome.UserID
+
+	customFields, ferr := h.customFieldValues(c.Context(), taskRequest.ProjectID, taskRequest.CustomFields, true)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
 
 	task := tasks.Entry{
 		Title:          taskRequest.Title,
 		Description:    taskRequest.Description,
 		AssignedUserID: taskRequest.AssignedUserID,
 		DueDate:        taskRequest.DueDate,
+		Priority:       taskRequest.Priority,
+		Labels:         taskRequest.Labels,
+		ProjectID:      taskRequest.ProjectID,
+
+		OriginalEstimate:  taskRequest.OriginalEstimate,
+		RemainingEstimate: taskRequest.RemainingEstimate,
+	}
+	for _, text := range taskRequest.Checklist {
+		task.Checklist = append(task.Checklist, tasks.ChecklistItem{Text: text})
 	}
-	id, err := tasks.NewDB(h.pg).Insert(c.Context(), task)
+	if err := task.Validate(); err != nil {
+		log.Err(err).Msg("invalid task")
+		return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	tx, err := h.pg.BeginTx(c.Context(), nil)
+	if err != nil {
+		log.Err(err).Msg("could not begin transaction")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	defer func() { _ = tx.Rollback() }()
+	var choice *assignment.Choice
+	if strategy != nil {
+		picked, _, ferr := autoAssign(c.Context(), tx, strategy, assignment.Task{
+			ProjectID: task.ProjectID,
+			Labels:    task.Labels,
+			DueDate:   task.DueDate,
+		})
+		if ferr != nil {
+			return fiberx.Err(c, ferr.Code, ferr.Message)
+		}
+		task.AssignedUserID = picked.UserID
+		choice = &picked
+	}
+	db := tasks.NewDB(tx)
+	id, err := db.Insert(c.Context(), task)
 	if err != nil {
 		log.Err(err).Msg("could not create task")
 		return fiberx.Err(c, fiber.StatusInternalServerError)
 	}
-	return c.JSON(fiber.Map{
+	if err := db.SetCustomFieldValues(c.Context(), id, customFields); err != nil {
+		log.Err(err).Msg("could not set custom field values")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	if choice != nil {
+		if err := assignment.NewDB(tx).Record(c.Context(), id, *choice); err != nil {
+			log.Err(err).Msg("could not record automatic assignment")
+			return fiberx.Err(c, fiber.StatusInternalServerError)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Err(err).Msg("could not commit task")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	response := fiber.Map{
 		"taskId": id,
+	}
+	if choice != nil {
+		response["autoAssignment"] = choice
+	}
+	if outcome.Warning != "" {
+		response["warnings"] = []string{outcome.Warning}
+	}
+	return c.JSON(response)
+}
+
+// checkAssignee makes sure that userID refers to an existing employee, and
+// returns the error to respond with otherwise.
+func (h *handlers) checkAssignee(ctx context.Context, userID int) *fiber.Error {
+	user, err := auth.NewDB(h.pg).FindOne(ctx, auth.FindOptions{
+		IDs: []int{userID},
 	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Error().Msg(fmt.Sprintf("user %d does not exist", userID))
+			return &fiber.Error{Code: fiber.StatusBadRequest}
+		}
+		log.Err(err).Msg("could not find user")
+		return &fiber.Error{Code: fiber.StatusInternalServerError}
+	}
+	if user.IsEmployer() {
+		log.Error().Msg(fmt.Sprintf("user %d is not an employee", userID))
+		return &fiber.Error{Code: fiber.StatusBadRequest, Message: "assignedUserID is not an employee"}
+	}
+	if !user.Active {
+		log.Error().Msg(fmt.Sprintf("user %d is inactive", userID))
+		return &fiber.Error{Code: fiber.StatusBadRequest, Message: "assignedUserID is inactive"}
+	}
+	return nil
 }
 
 func (h *handlers) employerGetTasks(c *fiber.Ctx) error {
-	var (
-		// search criteria
-		assignedUserID int
-		status         tasks.Status
-		sortBy         tasks.DBColumn
-		sortOrder      tasks.SortOrder
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	opts, ferr := h.employerTaskOptions(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	if opts, ferr = h.applyDefaultView(c, currentUser.ID, opts); ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
 
-		err error
+	return h.sendTaskPage(c, opts)
+}
+
+// employerTaskOptions reads the filters and sort of the employer's task
+// listing.
+func (h *handlers) employerTaskOptions(c *fiber.Ctx) (tasks.FindOptions, *fiber.Error) {
+	var (
+		opts tasks.FindOptions
+		err  error
 	)
-	if v := c.Query("assignedUserId"); v != "" {
-		assignedUserID, err = strconv.Atoi(v)
-		if err != nil {
-			log.Err(err).Msg("could not parse assignedUserID")
-			return fiberx.Err(c, fiber.StatusBadRequest)
+	if opts.AssignedUserIDs, err = queryIDs(c, "assignedUserId"); err != nil {
+		log.Err(err).Msg("could not parse assignedUserId")
+		return opts, &fiber.Error{Code: fiber.StatusBadRequest, Message: err.Error()}
+	}
+	if opts.ProjectIDs, err = queryIDs(c, "projectId"); err != nil {
+		log.Err(err).Msg("could not parse projectId")
+		return opts, &fiber.Error{Code: fiber.StatusBadRequest, Message: err.Error()}
+	}
+	if err := parseTaskFilters(c, &opts); err != nil {
+		log.Err(err).Msg("could not parse task filters")
+		return opts, &fiber.Error{Code: fiber.StatusBadRequest, Message: err.Error()}
+	}
+	if opts.Overdue {
+		var ferr *fiber.Error
+		if opts.OverdueBefore, ferr = overdueBefore(c.Context(), h.pg); ferr != nil {
+			return opts, ferr
 		}
 	}
-	if v := c.Query("status"); v != "" {
-		status, err = tasks.ParseStatus(v)
+	if v := c.Query("unassigned"); v != "" {
+		opts.Unassigned, err = strconv.ParseBool(v)
 		if err != nil {
-			log.Err(err).Msg("could not parse status")
-			return fiberx.Err(c, fiber.StatusBadRequest)
+			log.Err(err).Msg("could not parse unassigned")
+			return opts, &fiber.Error{Code: fiber.StatusBadRequest}
 		}
 	}
-	if v := c.Query("sortBy"); v != "" {
-		sortBy, err = tasks.ParseDBColumn(v)
+	if v := c.Query("sortBy"); v != "" && !strings.HasPrefix(v, tasks.CustomFieldPrefix) {
+		opts.SortBy, err = tasks.ParseDBColumn(v)
 		if err != nil {
 			log.Err(err).Msg("could not parse sortBy")
-			return fiberx.Err(c, fiber.StatusBadRequest)
+			return opts, &fiber.Error{Code: fiber.StatusBadRequest}
 		}
 	}
 	if v := c.Query("sortOrder"); v != "" {
-		sortOrder, err = tasks.ParseSortOrder(v)
+		opts.SortOrder, err = tasks.ParseSortOrder(v)
 		if err != nil {
 			log.Err(err).Msg("could not parse sortOrder")
-			return fiberx.Err(c, fiber.StatusBadRequest)
+			return opts, &fiber.Error{Code: fiber.StatusBadRequest}
 		}
 	}
 
-	opts := tasks.FindOptions{
-		SortBy:    sortBy,
-		SortOrder: sortOrder,
+	var ferr *fiber.Error
+	if opts.CustomFields, opts.SortByCustomField, ferr = h.customFieldQuery(c, opts.ProjectIDs); ferr != nil {
+		return opts, ferr
 	}
-	if assignedUserID > 0 {
-		opts.AssignedUserIDs = []int{assignedUserID}
+	return opts, nil
+}
+
+func (h *handlers) employerAddChecklistItem(c *fiber.Ctx) error {
+	taskID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse task id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
 	}
-	if status != "" {
-		opts.Statuses = []tasks.Status{status}
+	var request struct {
+		Text string `json:"text"`
 	}
-
-	entries, err := tasks.NewDB(h.pg).Find(c.Context(), opts)
-
+	if err := c.BodyParser(&request); err != nil || request.Text == "" {
+		log.Err(err).Msg("could not parse checklist item request")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	id, err := tasks.NewDB(h.pg).AddChecklistItem(c.Context(), taskID, request.Text)
 	if err != nil {
+		log.Err(err).Msg("could not add checklist item")
 		if errors.Is(err, sql.ErrNoRows) {
-			return c.JSON(fiber.Map{
-				"tasks": []tasks.Entry{},
-			})
+			return fiberx.Err(c, fiber.StatusNotFound)
 		}
-		log.Err(err).Msg("could not find tasks")
 		return fiberx.Err(c, fiber.StatusInternalServerError)
 	}
-
 	return c.JSON(fiber.Map{
-		"tasks": entries,
+		"itemId": id,
 	})
 }
 
+func (h *handlers) employerDeleteChecklistItem(c *fiber.Ctx) error {
+	taskID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse task id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	itemID, err := strconv.Atoi(c.Params("itemId"))
+	if err != nil {
+		log.Err(err).Msg("could not parse checklist item id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	if err := tasks.NewDB(h.pg).DeleteChecklistItem(c.Context(), taskID, itemID); err != nil {
+		log.Err(err).Msg("could not delete checklist item")
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
 func (h *handlers) employerGetTaskSummary(c *fiber.Ctx) error {
-	summaries, err := tasks.NewDB(h.pg).Summarize(c.Context())
+	db := tasks.NewDB(h.pg)
+	summaries, err := db.Summarize(c.Context(), tasks.SummarizeOptions{})
 	if err != nil {
 		if !errors.Is(err, sql.ErrNoRows) {
 			log.Err(err).Msg("could not summarize tasks")
 			return fiberx.Err(c, fiber.StatusInternalServerError)
 		}
 	}
+	unassigned, err := db.CountBacklog(c.Context())
+	if err != nil {
+		log.Err(err).Msg("could not count backlog")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"summaries":  summaries,
+		"unassigned": unassigned,
+	})
+}
+
+// maxBacklogPoints is the most points of backlog history at once.
+const maxBacklogPoints = 366
+
+func (h *handlers) employerGetBacklog(c *fiber.Ctx) error {
+	var (
+		to          = time.Now()
+		from        = to.AddDate(0, 0, -30)
+		granularity = tasks.GranularityDay
+
+		err error
+	)
+	if v := c.Query("from"); v != "" {
+		from, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			log.Err(err).Msg("could not parse from")
+			return fiberx.Err(c, fiber.StatusBadRequest)
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		to, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			log.Err(err).Msg("could not parse to")
+			return fiberx.Err(c, fiber.StatusBadRequest)
+		}
+	}
+	if v := c.Query("granularity"); v != "" {
+		granularity, err = tasks.ParseGranularity(v)
+		if err != nil {
+			log.Err(err).Msg("could not parse granularity")
+			return fiberx.Err(c, fiber.StatusBadRequest)
+		}
+	}
+	if !from.Before(to) {
+		return fiberx.Err(c, fiber.StatusBadRequest, "from must be before to")
+	}
+	step := 24 * time.Hour
+	if granularity == tasks.GranularityWeek {
+		step *= 7
+	}
+	if to.Sub(from) >= maxBacklogPoints*step {
+		return fiberx.Err(c, fiber.StatusBadRequest, fmt.Sprintf("at most %d %ss at once", maxBacklogPoints, granularity))
+	}
+
+	db := tasks.NewDB(h.pg)
+	size, err := db.CountBacklog(c.Context())
+	if err != nil {
+		log.Err(err).Msg("could not count backlog")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	history, err := db.BacklogHistory(c.Context(), from, to, granularity)
+	if err != nil {
+		log.Err(err).Msg("could not get backlog history")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
 	return c.JSON(fiber.Map{
-		"summaries": summaries,
+		"size":    size,
+		"history": history,
 	})
 }
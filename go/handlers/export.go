@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+
+	"siransbach/taskmanagementapi/config"
+	"siransbach/taskmanagementapi/export"
+	"siransbach/taskmanagementapi/fiberx"
+	"siransbach/taskmanagementapi/tasks"
+)
+
+// exportPageSize is the number of tasks loaded at once while exporting.
+const exportPageSize = 500
+
+// employerExportTasks streams the tasks matching the filters of the employer's
+// task listing as CSV, NDJSON, XLSX or iCalendar, a page at a time.
+func (h *handlers) employerExportTasks(c *fiber.Ctx) error {
+	format := export.FormatCSV
+	if v := c.Query("format"); v != "" {
+		var err error
+		if format, err = export.ParseFormat(v); err != nil {
+			log.Err(err).Msg("could not parse format")
+			return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+		}
+	}
+	component := export.ComponentTodo
+	if v := c.Query("component"); v != "" {
+		var err error
+		if component, err = export.ParseComponent(v); err != nil {
+			log.Err(err).Msg("could not parse component")
+			return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+		}
+	}
+	opts, ferr := h.employerTaskOptions(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	if v := c.Query("sort"); v != "" {
+		sort, err := tasks.ParseSort(v)
+		if err != nil {
+			log.Err(err).Msg("could not parse sort")
+			return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+		}
+		opts.Sort = sort
+	}
+
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="tasks-%s.%s"`,
+		time.Now().Format(time.DateOnly), format))
+	h.streamTasks(c, format, opts, func(w io.Writer) export.Writer {
+		if format == export.FormatICal {
+			return export.NewICalWriter(w, component, "Tasks")
+		}
+		return export.NewWriter(format, w)
+	})
+	return nil
+}
+
+// streamTasks responds with the tasks matching opts in the format, written by
+// the writer newWriter returns once the handler has returned.
+func (h *handlers) streamTasks(c *fiber.Ctx, format export.Format, opts tasks.FindOptions, newWriter func(w io.Writer) export.Writer) {
+	opts.Limit = exportPageSize
+	c.Set(fiber.HeaderContentType, format.ContentType())
+	// the request context is no longer usable by the time the response is
+	// written, the export gets its own deadline instead
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithTimeout(context.Background(), config.ExportTimeout())
+		defer cancel()
+		out := newWriter(w)
+		if err := h.exportTasks(ctx, out, opts); err != nil {
+			// the status has been sent already, the export ends early
+			log.Err(err).Msg("could not export tasks")
+			return
+		}
+		if err := out.Close(); err != nil {
+			log.Err(err).Msg("could not write tasks export")
+		}
+	})
+}
+
+// exportTasks writes the tasks matching opts to w, one page at a time.
+func (h *handlers) exportTasks(ctx context.Context, w export.Writer, opts tasks.FindOptions) error {
+	db := tasks.NewDB(h.pg)
+	for {
+		page, err := db.FindPage(ctx, opts, false)
+		if err != nil {
+			return err
+		}
+		for _, entry := range page.Entries {
+			if err := w.Write(entry); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		if opts.After, err = tasks.ParseCursor(page.NextCursor); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"siransbach/taskmanagementapi/tasks"
+)
+
+// parseTaskFilters reads the filters shared by the task list endpoints into
+// opts: status, due and creation date ranges, overdue, dueWithin, q and the
+// filter expression.
+func parseTaskFilters(c *fiber.Ctx, opts *tasks.FindOptions) error {
+	for _, v := range queryValues(c, "status") {
+		status, err := tasks.ParseStatus(v)
+		if err != nil {
+			return fmt.Errorf("invalid status: %s", v)
+		}
+		opts.Statuses = append(opts.Statuses, status)
+	}
+	for param, t := range map[string]*time.Time{
+		"dueFrom":     &opts.DueFrom,
+		"dueTo":       &opts.DueTo,
+		"createdFrom": &opts.CreatedFrom,
+		"createdTo":   &opts.CreatedTo,
+	} {
+		if v := c.Query(param); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %s", param, v)
+			}
+			*t = parsed
+		}
+	}
+	if v := c.Query("overdue"); v != "" {
+		overdue, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid overdue: %s", v)
+		}
+		opts.Overdue = overdue
+	}
+	if v := c.Query("dueWithin"); v != "" {
+		within, err := time.ParseDuration(v)
+		if err != nil || within <= 0 {
+			return fmt.Errorf("invalid dueWithin: %s", v)
+		}
+		opts.DueWithin = within
+	}
+	opts.Text = strings.TrimSpace(c.Query("q"))
+	if v := strings.TrimSpace(c.Query("filter")); v != "" {
+		filter, err := tasks.ParseFilter(v)
+		if err != nil {
+			return fmt.Errorf("invalid filter: %w", err)
+		}
+		opts.Filter = filter
+	}
+	return nil
+}
+
+// queryIDs parses a multi-value query parameter of IDs.
+func queryIDs(c *fiber.Ctx, key string) ([]int, error) {
+	var ids []int
+	for _, v := range queryValues(c, key) {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", key, v)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// queryValues returns the values of a query parameter given either several
+// times or as a comma-separated list, e.g. status=PENDING,IN_PROGRESS.
+func queryValues(c *fiber.Ctx, key string) []string {
+	var values []string
+	for _, v := range c.Context().QueryArgs().PeekMulti(key) {
+		for _, value := range strings.Split(string(v), ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+
+	"siransbach/taskmanagementapi/fiberx"
+	"siransbach/taskmanagementapi/imports"
+	"siransbach/taskmanagementapi/tasks"
+)
+
+// employerImportTasks creates the tasks of a CSV or JSON body, all of them or
+// none when any row is invalid. With dryRun, the rows are only checked.
+func (h *handlers) employerImportTasks(c *fiber.Ctx) error {
+	dryRun := false
+	if v := c.Query("dryRun"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			log.Err(err).Msg("could not parse dryRun")
+			return fiberx.Err(c, fiber.StatusBadRequest)
+		}
+	}
+
+	var (
+		rows []imports.Row
+		err  error
+	)
+	contentType := strings.ToLower(c.Get(fiber.HeaderContentType))
+	switch {
+	case strings.HasPrefix(contentType, "text/csv"):
+		rows, err = imports.ReadCSV(bytes.NewReader(c.Body()))
+	case strings.HasPrefix(contentType, fiber.MIMEApplicationJSON), strings.HasPrefix(contentType, "application/x-ndjson"):
+		rows, err = imports.ReadJSON(bytes.NewReader(c.Body()))
+	default:
+		log.Error().Msg("unsupported import content type: " + contentType)
+		return fiberx.Err(c, fiber.StatusBadRequest, "expected text/csv, application/json or application/x-ndjson")
+	}
+	if err != nil {
+		log.Err(err).Msg("could not read import")
+		return fiberx.Err(c, fiber.StatusBadRequest, "could not read import: "+err.Error())
+	}
+	if len(rows) == 0 {
+		log.Error().Msg("empty import")
+		return fiberx.Err(c, fiber.StatusBadRequest, "no tasks to import")
+	}
+
+	if err := imports.Check(c.Context(), h.pg, rows); err != nil {
+		log.Err(err).Msg("could not check import")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	rowErrors := imports.Errors(rows)
+	if dryRun {
+		return c.JSON(fiber.Map{
+			"rows":   len(rows),
+			"valid":  len(rowErrors) == 0,
+			"errors": rowErrors,
+		})
+	}
+	if len(rowErrors) > 0 {
+		log.Error().Msg("invalid import rows")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "invalid rows, nothing was imported",
+			"code":   fiber.StatusBadRequest,
+			"errors": rowErrors,
+		})
+	}
+
+	entries := make([]tasks.Entry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, row.Entry)
+	}
+	ids, err := tasks.Import(c.Context(), h.pg, entries)
+	if err != nil {
+		log.Err(err).Msg("could not import tasks")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"taskIds": ids,
+	})
+}
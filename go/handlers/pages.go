@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+
+	"siransbach/taskmanagementapi/fiberx"
+	"siransbach/taskmanagementapi/tasks"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// sendTaskPage responds with the page of the tasks matching opts given by the
+// limit and cursor query parameters, along with the cursor of the next page
+// and, if includeTotal is set, the number of tasks on all pages. The sort and
+// fields query parameters sort the tasks by several columns and narrow them
+// down to some fields.
+func (h *handlers) sendTaskPage(c *fiber.Ctx, opts tasks.FindOptions) error {
+	if v := c.Query("sort"); v != "" {
+		sort, err := tasks.ParseSort(v)
+		if err != nil {
+			log.Err(err).Msg("could not parse sort")
+			return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+		}
+		opts.Sort = sort
+	}
+	if v := c.Query("fields"); v != "" {
+		fields, err := tasks.ParseFields(v)
+		if err != nil {
+			log.Err(err).Msg("could not parse fields")
+			return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+		}
+		opts.Fields = fields
+	}
+	opts.Limit = defaultPageSize
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			log.Err(err).Msg("could not parse limit")
+			return fiberx.Err(c, fiber.StatusBadRequest, "invalid limit: "+v)
+		}
+		opts.Limit = min(limit, maxPageSize)
+	}
+	if v := c.Query("cursor"); v != "" {
+		cursor, err := tasks.ParseCursor(v)
+		if err != nil {
+			log.Err(err).Msg("could not parse cursor")
+			return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+		}
+		opts.After = cursor
+	}
+	withTotal := false
+	if v := c.Query("includeTotal"); v != "" {
+		var err error
+		if withTotal, err = strconv.ParseBool(v); err != nil {
+			log.Err(err).Msg("could not parse includeTotal")
+			return fiberx.Err(c, fiber.StatusBadRequest)
+		}
+	}
+
+	page, err := tasks.NewDB(h.pg).FindPage(c.Context(), opts, withTotal)
+	if err != nil {
+		log.Err(err).Msg("could not find tasks")
+		if errors.Is(err, tasks.ErrInvalidCursor) {
+			return fiberx.Err(c, fiber.StatusBadRequest, "cursor does not match the sort of the tasks")
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	response := fiber.Map{
+		"tasks":      page.Entries,
+		"nextCursor": page.NextCursor,
+	}
+	if len(opts.Fields) > 0 {
+		// the ID always comes along, to tell tasks apart
+		fields := append([]string{"id"}, opts.Fields...)
+		list := make([]map[string]json.RawMessage, 0, len(page.Entries))
+		for _, entry := range page.Entries {
+			selected, err := entry.Select(fields)
+			if err != nil {
+				log.Err(err).Msg("could not select task fields")
+				return fiberx.Err(c, fiber.StatusInternalServerError)
+			}
+			list = append(list, selected)
+		}
+		response["tasks"] = list
+	}
+	if page.Total != nil {
+		response["total"] = *page.Total
+	}
+	return c.JSON(response)
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+
+	"siransbach/taskmanagementapi/auth"
+	"siransbach/taskmanagementapi/fiberx"
+	"siransbach/taskmanagementapi/tasks"
+)
+
+func (h *handlers) employerAddAssignee(c *fiber.Ctx) error {
+	task, userID, ferr := h.findTaskAndUser(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	if ferr := h.checkAssignee(c.Context(), userID); ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	if task.ProjectID > 0 {
+		if ferr := h.checkProjectAssignee(c.Context(), task.ProjectID, userID); ferr != nil {
+			return fiberx.Err(c, ferr.Code, ferr.Message)
+		}
+	}
+	if err := tasks.NewDB(h.pg).AddAssignee(c.Context(), task.ID, userID); err != nil {
+		log.Err(err).Msg("could not add assignee")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (h *handlers) employerRemoveAssignee(c *fiber.Ctx) error {
+	task, userID, ferr := h.findTaskAndUser(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	if err := tasks.NewDB(h.pg).RemoveAssignee(c.Context(), task.ID, userID); err != nil {
+		log.Err(err).Msg("could not remove assignee")
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		if errors.Is(err, tasks.ErrPrimaryAssignee) {
+			return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (h *handlers) employerAddWatcher(c *fiber.Ctx) error {
+	task, userID, ferr := h.findTaskAndUser(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	if err := tasks.NewDB(h.pg).AddWatcher(c.Context(), task.ID, userID); err != nil {
+		log.Err(err).Msg("could not add watcher")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (h *handlers) employerRemoveWatcher(c *fiber.Ctx) error {
+	task, userID, ferr := h.findTaskAndUser(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	if err := tasks.NewDB(h.pg).RemoveWatcher(c.Context(), task.ID, userID); err != nil {
+		log.Err(err).Msg("could not remove watcher")
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// findTaskAndUser parses the :id and :userId parameters and makes sure both
+// the task and the user exist, returning the error to respond with otherwise.
+func (h *handlers) findTaskAndUser(c *fiber.Ctx) (task tasks.Entry, userID int, ferr *fiber.Error) {
+	taskID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse task id")
+		return task, 0, &fiber.Error{Code: fiber.StatusBadRequest}
+	}
+	userID, err = strconv.Atoi(c.Params("userId"))
+	if err != nil {
+		log.Err(err).Msg("could not parse user id")
+		return task, 0, &fiber.Error{Code: fiber.StatusBadRequest}
+	}
+	task, err = tasks.NewDB(h.pg).FindOne(c.Context(), tasks.FindOptions{IDs: []int{taskID}})
+	if err != nil {
+		log.Err(err).Msg("could not find task")
+		if errors.Is(err, sql.ErrNoRows) {
+			return task, 0, &fiber.Error{Code: fiber.StatusNotFound}
+		}
+		return task, 0, &fiber.Error{Code: fiber.StatusInternalServerError}
+	}
+	if _, err := auth.NewDB(h.pg).FindOne(c.Context(), auth.FindOptions{IDs: []int{userID}}); err != nil {
+		log.Err(err).Msg("could not find user")
+		if errors.Is(err, sql.ErrNoRows) {
+			return task, 0, &fiber.Error{Code: fiber.StatusNotFound}
+		}
+		return task, 0, &fiber.Error{Code: fiber.StatusInternalServerError}
+	}
+	return task, userID, nil
+}
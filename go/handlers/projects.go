@@ -0,0 +1,421 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+
+	"siransbach/taskmanagementapi/auth"
+	"siransbach/taskmanagementapi/fiberx"
+	"siransbach/taskmanagementapi/projects"
+	"siransbach/taskmanagementapi/tasks"
+)
+
+// boardColumn is a column of a Kanban board, holding the tasks of one status
+// in rank order.
+type boardColumn struct {
+	Status tasks.Status  `json:"status"`
+	Tasks  []tasks.Entry `json:"tasks"`
+}
+
+func (h *handlers) employerGetProjects(c *fiber.Ctx) error {
+	var (
+		archived bool
+		err      error
+	)
+	if v := c.Query("archived"); v != "" {
+		archived, err = strconv.ParseBool(v)
+		if err != nil {
+			log.Err(err).Msg("could not parse archived")
+			return fiberx.Err(c, fiber.StatusBadRequest)
+		}
+	}
+	list, err := projects.NewDB(h.pg).Find(c.Context(), projects.FindOptions{IncludeArchived: archived})
+	if err != nil {
+		log.Err(err).Msg("could not find projects")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	if list == nil {
+		list = []projects.Project{}
+	}
+	return c.JSON(fiber.Map{
+		"projects": list,
+	})
+}
+
+func (h *handlers) employerCreateProject(c *fiber.Ctx) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	var request struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		MemberIDs   []int  `json:"memberIds"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		log.Err(err).Msg("could not parse project request")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	for _, userID := range request.MemberIDs {
+		if ferr := h.checkAssignee(c.Context(), userID); ferr != nil {
+			return fiberx.Err(c, ferr.Code, ferr.Message)
+		}
+	}
+	p := projects.Project{
+		Name:        request.Name,
+		Description: request.Description,
+		OwnerID:     currentUser.ID,
+	}
+	if err := p.Validate(); err != nil {
+		log.Err(err).Msg("invalid project")
+		return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+	}
+	id, err := projects.NewDB(h.pg).Insert(c.Context(), p, request.MemberIDs)
+	if err != nil {
+		log.Err(err).Msg("could not create project")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"projectId": id,
+	})
+}
+
+func (h *handlers) employerGetProject(c *fiber.Ctx) error {
+	p, ferr := h.findProject(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	return c.JSON(fiber.Map{
+		"project": p,
+	})
+}
+
+func (h *handlers) employerUpdateProject(c *fiber.Ctx) error {
+	p, ferr := h.findProject(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	var request struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		OwnerID     *int    `json:"ownerId"`
+		Archived    *bool   `json:"archived"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		log.Err(err).Msg("could not parse project request")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	if request.Name != nil {
+		p.Name = *request.Name
+	}
+	if request.Description != nil {
+		p.Description = *request.Description
+	}
+	if request.OwnerID != nil {
+		if ferr := h.checkProjectOwner(c.Context(), *request.OwnerID); ferr != nil {
+			return fiberx.Err(c, ferr.Code, ferr.Message)
+		}
+		p.OwnerID = *request.OwnerID
+	}
+	if request.Archived != nil {
+		p.Archived = *request.Archived
+	}
+	if err := p.Validate(); err != nil {
+		log.Err(err).Msg("invalid project")
+		return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+	}
+	if err := projects.NewDB(h.pg).Update(c.Context(), p); err != nil {
+		log.Err(err).Msg("could not update project")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (h *handlers) employerAddProjectMember(c *fiber.Ctx) error {
+	p, ferr := h.findProject(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	userID, err := strconv.Atoi(c.Params("userId"))
+	if err != nil {
+		log.Err(err).Msg("could not parse user id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	if ferr := h.checkAssignee(c.Context(), userID); ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	if err := projects.NewDB(h.pg).AddMember(c.Context(), p.ID, userID); err != nil {
+		log.Err(err).Msg("could not add project member")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (h *handlers) employerRemoveProjectMember(c *fiber.Ctx) error {
+	p, ferr := h.findProject(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	userID, err := strconv.Atoi(c.Params("userId"))
+	if err != nil {
+		log.Err(err).Msg("could not parse user id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	backlogTaskIDs, err := projects.NewDB(h.pg).RemoveMember(c.Context(), p.ID, userID)
+	if err != nil {
+		log.Err(err).Msg("could not remove project member")
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"backlogTaskIds": backlogTaskIDs,
+	})
+}
+
+func (h *handlers) employerGetProjectTasks(c *fiber.Ctx) error {
+	p, ferr := h.findProject(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	opts := tasks.FindOptions{ProjectIDs: []int{p.ID}}
+	if opts.CustomFields, opts.SortByCustomField, ferr = h.customFieldQuery(c, opts.ProjectIDs); ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	if v := c.Query("sortBy"); v != "" && opts.SortByCustomField == nil {
+		sortBy, err := tasks.ParseDBColumn(v)
+		if err != nil {
+			log.Err(err).Msg("could not parse sortBy")
+			return fiberx.Err(c, fiber.StatusBadRequest)
+		}
+		opts.SortBy = sortBy
+	}
+	if v := c.Query("sortOrder"); v != "" {
+		sortOrder, err := tasks.ParseSortOrder(v)
+		if err != nil {
+			log.Err(err).Msg("could not parse sortOrder")
+			return fiberx.Err(c, fiber.StatusBadRequest)
+		}
+		opts.SortOrder = sortOrder
+	}
+	return h.sendTaskPage(c, opts)
+}
+
+func (h *handlers) employerGetProjectSummary(c *fiber.Ctx) error {
+	p, ferr := h.findProject(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	summaries, err := tasks.NewDB(h.pg).Summarize(c.Context(), tasks.SummarizeOptions{ProjectIDs: []int{p.ID}})
+	if err != nil {
+		log.Err(err).Msg("could not summarize tasks")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"summaries": summaries,
+	})
+}
+
+func (h *handlers) employerGetBoard(c *fiber.Ctx) error {
+	p, ferr := h.findProject(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	return h.board(c, p)
+}
+
+func (h *handlers) employerMoveTask(c *fiber.Ctx) error {
+	p, ferr := h.findProject(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	return h.moveTask(c, p, 0)
+}
+
+func (h *handlers) employeeGetProjects(c *fiber.Ctx) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	list, err := projects.NewDB(h.pg).Find(c.Context(), projects.FindOptions{MemberIDs: []int{currentUser.ID}})
+	if err != nil {
+		log.Err(err).Msg("could not find projects")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	if list == nil {
+		list = []projects.Project{}
+	}
+	return c.JSON(fiber.Map{
+		"projects": list,
+	})
+}
+
+func (h *handlers) employeeGetBoard(c *fiber.Ctx) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	p, ferr := h.findProject(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	if !p.HasMember(currentUser.ID) {
+		log.Error().Msg(fmt.Sprintf("user %d is not a member of project %d", currentUser.ID, p.ID))
+		return fiberx.Err(c, fiber.StatusNotFound)
+	}
+	return h.board(c, p)
+}
+
+// employeeMoveTask lets an employee move the tasks they are an assignee of.
+func (h *handlers) employeeMoveTask(c *fiber.Ctx) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	p, ferr := h.findProject(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	return h.moveTask(c, p, currentUser.ID)
+}
+
+func (h *handlers) board(c *fiber.Ctx, p projects.Project) error {
+	entries, err := tasks.NewDB(h.pg).Find(c.Context(), tasks.FindOptions{
+		ProjectIDs: []int{p.ID},
+		SortBy:     tasks.BoardRankCol,
+	})
+	if err != nil {
+		log.Err(err).Msg("could not find tasks")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	columns := make([]boardColumn, 0, len(tasks.Statuses))
+	for _, status := range tasks.Statuses {
+		column := boardColumn{Status: status, Tasks: []tasks.Entry{}}
+		for _, entry := range entries {
+			if entry.Status == status {
+				column.Tasks = append(column.Tasks, entry)
+			}
+		}
+		columns = append(columns, column)
+	}
+	return c.JSON(fiber.Map{
+		"project": p,
+		"columns": columns,
+	})
+}
+
+// moveTask moves a task to a position of a board column. When assigneeID is
+// set, the task must be assigned to that user.
+func (h *handlers) moveTask(c *fiber.Ctx, p projects.Project, assigneeID int) error {
+	taskID, err := strconv.Atoi(c.Params("taskId"))
+	if err != nil {
+		log.Err(err).Msg("could not parse task id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	var request struct {
+		Status   tasks.Status `json:"status"`
+		Position int          `json:"position"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		log.Err(err).Msg("could not parse move request")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	status, err := tasks.ParseStatus(string(request.Status))
+	if err != nil {
+		log.Err(err).Msg("could not parse task status")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	if assigneeID > 0 {
+		if _, err := tasks.NewDB(h.pg).FindOne(c.Context(), tasks.FindOptions{
+			IDs:            []int{taskID},
+			ProjectIDs:     []int{p.ID},
+			AnyAssigneeIDs: []int{assigneeID},
+		}); err != nil {
+			log.Err(err).Msg("could not find task")
+			if errors.Is(err, sql.ErrNoRows) {
+				return fiberx.Err(c, fiber.StatusNotFound)
+			}
+			return fiberx.Err(c, fiber.StatusInternalServerError)
+		}
+	}
+	if err := projects.NewDB(h.pg).MoveTask(c.Context(), p.ID, taskID, status, request.Position); err != nil {
+		log.Err(err).Msg("could not move task")
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		if errors.Is(err, projects.ErrArchived) {
+			return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (h *handlers) findProject(c *fiber.Ctx) (projects.Project, *fiber.Error) {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse project id")
+		return projects.Project{}, &fiber.Error{Code: fiber.StatusBadRequest}
+	}
+	p, err := projects.NewDB(h.pg).FindOne(c.Context(), projects.FindOptions{IDs: []int{id}})
+	if err != nil {
+		log.Err(err).Msg("could not find project")
+		if errors.Is(err, sql.ErrNoRows) {
+			return projects.Project{}, &fiber.Error{Code: fiber.StatusNotFound}
+		}
+		return projects.Project{}, &fiber.Error{Code: fiber.StatusInternalServerError}
+	}
+	return p, nil
+}
+
+// checkProjectAssignee makes sure that tasks can be added to the project and
+// that userID, when set, is one of its members.
+func (h *handlers) checkProjectAssignee(ctx context.Context, projectID, userID int) *fiber.Error {
+	p, err := projects.NewDB(h.pg).FindOne(ctx, projects.FindOptions{IDs: []int{projectID}})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Error().Msg(fmt.Sprintf("project %d does not exist", projectID))
+			return &fiber.Error{Code: fiber.StatusBadRequest}
+		}
+		log.Err(err).Msg("could not find project")
+		return &fiber.Error{Code: fiber.StatusInternalServerError}
+	}
+	if p.Archived {
+		log.Error().Msg(fmt.Sprintf("project %d is archived", projectID))
+		return &fiber.Error{Code: fiber.StatusBadRequest, Message: projects.ErrArchived.Error()}
+	}
+	if userID > 0 && !p.HasMember(userID) {
+		log.Error().Msg(fmt.Sprintf("user %d is not a member of project %d", userID, projectID))
+		return &fiber.Error{Code: fiber.StatusBadRequest, Message: "assignedUserID is not a member of the project"}
+	}
+	return nil
+}
+
+// checkProjectOwner makes sure that userID is an employer who can own projects.
+func (h *handlers) checkProjectOwner(ctx context.Context, userID int) *fiber.Error {
+	user, err := auth.NewDB(h.pg).FindOne(ctx, auth.FindOptions{IDs: []int{userID}})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Error().Msg(fmt.Sprintf("user %d does not exist", userID))
+			return &fiber.Error{Code: fiber.StatusBadRequest, Message: "ownerId does not exist"}
+		}
+		log.Err(err).Msg("could not find user")
+		return &fiber.Error{Code: fiber.StatusInternalServerError}
+	}
+	if !user.IsEmployer() {
+		log.Error().Msg(fmt.Sprintf("user %d is not an employer", userID))
+		return &fiber.Error{Code: fiber.StatusBadRequest, Message: "ownerId is not an employer"}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+
+	"siransbach/taskmanagementapi/config"
+	"siransbach/taskmanagementapi/fiberx"
+	"siransbach/taskmanagementapi/recurring"
+	"siransbach/taskmanagementapi/tasks"
+)
+
+func (h *handlers) employerCreateRecurringTask(c *fiber.Ctx) error {
+	var request struct {
+		Title          string    `json:"title"`
+		Description    string    `json:"description"`
+		AssignedUserID int       `json:"assignedUserID"`
+		RRule          string    `json:"rrule"`
+		DTStart        time.Time `json:"dtstart"`
+		Timezone       string    `json:"timezone"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		log.Err(err).Msg("could not parse recurring task request")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	if ferr := h.checkAssignee(c.Context(), request.AssignedUserID); ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+
+	def := recurring.Definition{
+		Title:          request.Title,
+		Description:    request.Description,
+		AssignedUserID: request.AssignedUserID,
+		RRule:          request.RRule,
+		DTStart:        request.DTStart,
+		Timezone:       request.Timezone,
+	}
+	if err := def.Validate(); err != nil {
+		log.Err(err).Msg("invalid recurring task")
+		return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+	}
+	id, err := recurring.NewDB(h.pg).Insert(c.Context(), def)
+	if err != nil {
+		log.Err(err).Msg("could not create recurring task")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	def.ID = id
+
+	// don't wait for the scheduler to pick up the new definition
+	if _, err := recurring.Materialize(c.Context(), h.pg, def, time.Now().Add(config.RecurrenceHorizon())); err != nil {
+		log.Err(err).Msg("could not materialize recurring task")
+	}
+	return c.JSON(fiber.Map{
+		"recurringTaskId": id,
+	})
+}
+
+func (h *handlers) employerGetRecurringTasks(c *fiber.Ctx) error {
+	defs, err := recurring.NewDB(h.pg).Find(c.Context(), recurring.FindOptions{})
+	if err != nil {
+		log.Err(err).Msg("could not find recurring tasks")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	if defs == nil {
+		defs = []recurring.Definition{}
+	}
+	return c.JSON(fiber.Map{
+		"recurringTasks": defs,
+	})
+}
+
+func (h *handlers) employerGetRecurringTask(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse recurring task id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	def, err := recurring.NewDB(h.pg).FindOne(c.Context(), recurring.FindOptions{IDs: []int{id}})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		log.Err(err).Msg("could not find recurring task")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	occurrences, err := tasks.NewDB(h.pg).Find(c.Context(), tasks.FindOptions{
+		RecurringTaskIDs: []int{id},
+		SortBy:           tasks.OccurrenceAtCol,
+	})
+	if err != nil {
+		log.Err(err).Msg("could not find occurrences")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	if occurrences == nil {
+		occurrences = []tasks.Entry{}
+	}
+	return c.JSON(fiber.Map{
+		"recurringTask": def,
+		"occurrences":   occurrences,
+	})
+}
+
+// employerUpdateOccurrence edits a materialized occurrence. With scope=this
+// (the default) only that task changes; with scope=future the series is split
+// so that the change also applies to every later occurrence.
+func (h *handlers) employerUpdateOccurrence(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse recurring task id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	taskID, err := strconv.Atoi(c.Params("taskId"))
+	if err != nil {
+		log.Err(err).Msg("could not parse task id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	scope := c.Query("scope", "this")
+	if scope != "this" && scope != "future" {
+		return fiberx.Err(c, fiber.StatusBadRequest, "scope must be one of this, future")
+	}
+	var request struct {
+		Title          *string    `json:"title"`
+		Description    *string    `json:"description"`
+		AssignedUserID *int       `json:"assignedUserID"`
+		DueDate        *time.Time `json:"dueDate"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		log.Err(err).Msg("could not parse occurrence request")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	if request.AssignedUserID != nil {
+		if ferr := h.checkAssignee(c.Context(), *request.AssignedUserID); ferr != nil {
+			return fiberx.Err(c, ferr.Code, ferr.Message)
+		}
+	}
+
+	def, err := recurring.NewDB(h.pg).FindOne(c.Context(), recurring.FindOptions{IDs: []int{id}})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		log.Err(err).Msg("could not find recurring task")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	task, err := tasks.NewDB(h.pg).FindOne(c.Context(), tasks.FindOptions{
+		IDs:              []int{taskID},
+		RecurringTaskIDs: []int{id},
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		log.Err(err).Msg("could not find occurrence")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+
+	if scope == "this" {
+		if request.Title != nil {
+			task.Title = *request.Title
+		}
+		if request.Description != nil {
+			task.Description = *request.Description
+		}
+		if request.AssignedUserID != nil {
+			task.AssignedUserID = *request.AssignedUserID
+			if task.ProjectID > 0 {
+				if ferr := h.checkProjectAssignee(c.Context(), task.ProjectID, task.AssignedUserID); ferr != nil {
+					return fiberx.Err(c, ferr.Code, ferr.Message)
+				}
+			}
+		}
+		if request.DueDate != nil && !request.DueDate.Equal(task.DueDate) {
+			if time.Now().After(*request.DueDate) {
+				log.Err(tasks.ErrDueDateExpired).Msg("invalid occurrence")
+				return fiberx.Err(c, fiber.StatusBadRequest, tasks.ErrDueDateExpired.Error())
+			}
+			task.DueDate = *request.DueDate
+		}
+		if err := task.ValidateFields(); err != nil {
+			log.Err(err).Msg("invalid occurrence")
+			return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+		}
+		if err := tasks.NewDB(h.pg).Update(c.Context(), task); err != nil {
+			log.Err(err).Msg("could not update occurrence")
+			if errors.Is(err, sql.ErrNoRows) {
+				return fiberx.Err(c, fiber.StatusNotFound)
+			}
+			return fiberx.Err(c, fiber.StatusInternalServerError)
+		}
+		return c.JSON(fiber.Map{
+			"taskId": task.ID,
+		})
+	}
+
+	at := task.DueDate
+	if task.OccurrenceAt != nil {
+		at = *task.OccurrenceAt
+	}
+	tail, err := def.Tail(at)
+	if err != nil {
+		log.Err(err).Msg("could not split recurring task")
+		return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+	}
+	if request.Title != nil {
+		tail.Title = *request.Title
+	}
+	if request.Description != nil {
+		tail.Description = *request.Description
+	}
+	if request.AssignedUserID != nil {
+		tail.AssignedUserID = *request.AssignedUserID
+	}
+	if request.DueDate != nil {
+		tail.DTStart = *request.DueDate
+	}
+	tailID, err := recurring.NewDB(h.pg).Split(c.Context(), def, at, tail)
+	if err != nil {
+		log.Err(err).Msg("could not split recurring task")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	tail.ID = tailID
+	if _, err := recurring.Materialize(c.Context(), h.pg, tail, time.Now().Add(config.RecurrenceHorizon())); err != nil {
+		log.Err(err).Msg("could not materialize recurring task")
+	}
+	return c.JSON(fiber.Map{
+		"recurringTaskId": tailID,
+	})
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+
+	"siransbach/taskmanagementapi/auth"
+	"siransbach/taskmanagementapi/fiberx"
+	"siransbach/taskmanagementapi/tasks"
+)
+
+// search looks for tasks by the words of their title and description.
+// Employees only find the tasks they can list otherwise.
+func (h *handlers) search(c *fiber.Ctx) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	opts := tasks.SearchOptions{
+		Query: strings.TrimSpace(c.Query("q")),
+		Limit: defaultPageSize,
+	}
+	if opts.Query == "" {
+		return fiberx.Err(c, fiber.StatusBadRequest, "q is required")
+	}
+	if !currentUser.IsEmployer() {
+		opts.VisibleTo = currentUser.ID
+	}
+	if opts.ProjectIDs, err = queryIDs(c, "projectId"); err != nil {
+		log.Err(err).Msg("could not parse projectId")
+		return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			log.Err(err).Msg("could not parse limit")
+			return fiberx.Err(c, fiber.StatusBadRequest, "invalid limit: "+v)
+		}
+		opts.Limit = min(limit, maxPageSize)
+	}
+
+	results, err := tasks.NewDB(h.pg).Search(c.Context(), opts)
+	if err != nil {
+		log.Err(err).Msg("could not search tasks")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"results": results,
+	})
+}
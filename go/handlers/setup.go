@@ -18,10 +18,53 @@ func Setup(app *fiber.App, pg *sql.DB) {
 	h := handlers{pg: pg}
 
 	app.Route("/api/"+apiVersion, func(api fiber.Router) {
+		api.Get("/search", h.search)
+		api.Get("/feeds/:token", h.getCalendarFeed)
+		api.Post("/calendar-token", h.createCalendarToken)
+		api.Delete("/calendar-token", h.deleteCalendarToken)
+		api.Route("/views", func(views fiber.Router) {
+			views.Get("/", h.getViews)
+			views.Post("/", h.createView)
+			views.Delete("/default", h.clearDefaultView)
+			views.Get("/:id", h.getView)
+			views.Put("/:id", h.updateView)
+			views.Delete("/:id", h.deleteView)
+			views.Get("/:id/tasks", h.getViewTasks)
+			views.Put("/:id/default", h.setDefaultView)
+		})
+
 		employeeRoutes := api.Group("/employee", userMustHaveRole(auth.RoleEmployee))
 		employeeRoutes.Route("/tasks", func(tasks fiber.Router) {
 			tasks.Get("/", h.employeeGetTasks)
+			tasks.Get("/watching", h.employeeGetWatchedTasks)
+			tasks.Get("/backlog", h.employeeGetBacklog)
+			tasks.Post("/batch", h.employeeBatchTasks)
+			tasks.Post("/:id/claim", h.employeeClaimTask)
 			tasks.Put("/:id/status/:status", h.employeeUpdateTaskStatus)
+			tasks.Put("/:id/checklist/:itemId", h.employeeUpdateChecklistItem)
+			tasks.Post("/:id/timer", h.employeeStartTimer)
+			tasks.Post("/:id/worklogs", h.employeeCreateWorklog)
+		})
+		employeeRoutes.Route("/absences", func(absences fiber.Router) {
+			absences.Get("/", h.employeeGetAbsences)
+			absences.Post("/", h.employeeCreateAbsence)
+			absences.Delete("/:absenceId", h.employeeDeleteAbsence)
+		})
+		employeeRoutes.Get("/timer", h.employeeGetTimer)
+		employeeRoutes.Delete("/timer", h.employeeStopTimer)
+		employeeRoutes.Route("/worklogs", func(worklogs fiber.Router) {
+			worklogs.Get("/", h.employeeGetWorklogs)
+			worklogs.Delete("/:id", h.employeeDeleteWorklog)
+		})
+		employeeRoutes.Route("/timesheets", func(timesheets fiber.Router) {
+			timesheets.Put("/entries", h.employeeSetTimesheetEntry)
+			timesheets.Get("/:day", h.employeeGetTimesheet)
+			timesheets.Post("/:day/submit", h.employeeSubmitTimesheet)
+		})
+		employeeRoutes.Route("/projects", func(projects fiber.Router) {
+			projects.Get("/", h.employeeGetProjects)
+			projects.Get("/:id/board", h.employeeGetBoard)
+			projects.Put("/:id/board/tasks/:taskId", h.employeeMoveTask)
 		})
 
 		employerRoutes := api.Group("/employer", userMustHaveRole(auth.RoleEmployer))
@@ -29,6 +72,80 @@ func Setup(app *fiber.App, pg *sql.DB) {
 			tasks.Get("/", h.employerGetTasks)
 			tasks.Post("/", h.employerCreateTask)
 			tasks.Get("/summary", h.employerGetTaskSummary)
+			tasks.Get("/export", h.employerExportTasks)
+			tasks.Post("/import", h.employerImportTasks)
+			tasks.Post("/batch", h.employerBatchTasks)
+			tasks.Get("/analytics", h.employerGetTaskAnalytics)
+			tasks.Get("/cumulative-flow", h.employerGetCumulativeFlow)
+			tasks.Get("/burndown", h.employerGetBurndown)
+			tasks.Get("/backlog", h.employerGetBacklog)
+			tasks.Post("/assignment-preview", h.employerPreviewAssignment)
+			tasks.Get("/:id/auto-assignment", h.employerGetAutoAssignment)
+			tasks.Post("/:id/checklist", h.employerAddChecklistItem)
+			tasks.Delete("/:id/checklist/:itemId", h.employerDeleteChecklistItem)
+			tasks.Post("/:id/assignees/:userId", h.employerAddAssignee)
+			tasks.Delete("/:id/assignees/:userId", h.employerRemoveAssignee)
+			tasks.Post("/:id/watchers/:userId", h.employerAddWatcher)
+			tasks.Delete("/:id/watchers/:userId", h.employerRemoveWatcher)
+			tasks.Put("/:id/custom-fields", h.employerSetCustomFieldValues)
+			tasks.Put("/:id/estimates", h.employerSetEstimates)
+			tasks.Get("/:id/worklogs", h.employerGetTaskWorklogs)
+		})
+		employerRoutes.Route("/users", func(users fiber.Router) {
+			users.Post("/:id/offboard", h.employerOffboardUser)
+			users.Post("/:id/reactivate", h.employerReactivateUser)
+			users.Get("/:id/skills", h.employerGetUserSkills)
+			users.Put("/:id/skills", h.employerSetUserSkills)
+			users.Get("/:id/absences", h.employerGetAbsences)
+			users.Post("/:id/absences", h.employerCreateAbsence)
+			users.Delete("/:id/absences/:absenceId", h.employerDeleteAbsence)
+		})
+		employerRoutes.Get("/availability", h.employerGetAvailability)
+		employerRoutes.Route("/calendars", func(calendars fiber.Router) {
+			calendars.Get("/", h.employerGetCalendars)
+			calendars.Post("/", h.employerCreateCalendar)
+			calendars.Put("/:id", h.employerUpdateCalendar)
+			calendars.Delete("/:id", h.employerDeleteCalendar)
+		})
+		employerRoutes.Route("/worklogs", func(worklogs fiber.Router) {
+			worklogs.Get("/totals", h.employerGetWorklogTotals)
+		})
+		employerRoutes.Route("/timesheets", func(timesheets fiber.Router) {
+			timesheets.Get("/", h.employerGetTimesheets)
+			timesheets.Get("/export", h.employerExportTimesheets)
+			timesheets.Get("/:id", h.employerGetTimesheet)
+			timesheets.Post("/:id/approve", h.employerApproveTimesheet)
+			timesheets.Post("/:id/reject", h.employerRejectTimesheet)
+		})
+		employerRoutes.Route("/recurring-tasks", func(recurring fiber.Router) {
+			recurring.Get("/", h.employerGetRecurringTasks)
+			recurring.Post("/", h.employerCreateRecurringTask)
+			recurring.Get("/:id", h.employerGetRecurringTask)
+			recurring.Put("/:id/occurrences/:taskId", h.employerUpdateOccurrence)
+		})
+		employerRoutes.Route("/projects", func(projects fiber.Router) {
+			projects.Get("/", h.employerGetProjects)
+			projects.Post("/", h.employerCreateProject)
+			projects.Get("/:id", h.employerGetProject)
+			projects.Put("/:id", h.employerUpdateProject)
+			projects.Post("/:id/members/:userId", h.employerAddProjectMember)
+			projects.Delete("/:id/members/:userId", h.employerRemoveProjectMember)
+			projects.Get("/:id/tasks", h.employerGetProjectTasks)
+			projects.Get("/:id/summary", h.employerGetProjectSummary)
+			projects.Get("/:id/board", h.employerGetBoard)
+			projects.Put("/:id/board/tasks/:taskId", h.employerMoveTask)
+			projects.Get("/:id/custom-fields", h.employerGetCustomFields)
+			projects.Post("/:id/custom-fields", h.employerCreateCustomField)
+			projects.Put("/:id/custom-fields/:fieldId", h.employerUpdateCustomField)
+			projects.Delete("/:id/custom-fields/:fieldId", h.employerDeleteCustomField)
+		})
+		employerRoutes.Route("/templates", func(templates fiber.Router) {
+			templates.Get("/", h.employerGetTemplates)
+			templates.Post("/", h.employerCreateTemplate)
+			templates.Get("/:id", h.employerGetTemplate)
+			templates.Put("/:id", h.employerUpdateTemplate)
+			templates.Delete("/:id", h.employerDeleteTemplate)
+			templates.Post("/:id/instantiate", h.employerInstantiateTemplate)
 		})
 	})
 }
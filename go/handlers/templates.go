@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+
+	"siransbach/taskmanagementapi/fiberx"
+	"siransbach/taskmanagementapi/tasks"
+	"siransbach/taskmanagementapi/templates"
+)
+
+func (h *handlers) employerGetTemplates(c *fiber.Ctx) error {
+	list, err := templates.NewDB(h.pg).Find(c.Context(), templates.FindOptions{})
+	if err != nil {
+		log.Err(err).Msg("could not find templates")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	if list == nil {
+		list = []templates.Template{}
+	}
+	return c.JSON(fiber.Map{
+		"templates": list,
+	})
+}
+
+func (h *handlers) employerGetTemplate(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse template id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	t, err := templates.NewDB(h.pg).FindOne(c.Context(), templates.FindOptions{IDs: []int{id}})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		log.Err(err).Msg("could not find template")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"template": t,
+	})
+}
+
+func (h *handlers) employerCreateTemplate(c *fiber.Ctx) error {
+	var t templates.Template
+	if err := c.BodyParser(&t); err != nil {
+		log.Err(err).Msg("could not parse template request")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	if t.Priority != "" {
+		priority, err := tasks.ParsePriority(string(t.Priority))
+		if err != nil {
+			log.Err(err).Msg("could not parse priority")
+			return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+		}
+		t.Priority = priority
+	}
+	if err := t.Validate(); err != nil {
+		log.Err(err).Msg("invalid template")
+		return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+	}
+	id, err := templates.NewDB(h.pg).Insert(c.Context(), t)
+	if err != nil {
+		log.Err(err).Msg("could not create template")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"templateId": id,
+	})
+}
+
+func (h *handlers) employerUpdateTemplate(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse template id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	var t templates.Template
+	if err := c.BodyParser(&t); err != nil {
+		log.Err(err).Msg("could not parse template request")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	t.ID = id
+	if t.Priority != "" {
+		priority, err := tasks.ParsePriority(string(t.Priority))
+		if err != nil {
+			log.Err(err).Msg("could not parse priority")
+			return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+		}
+		t.Priority = priority
+	}
+	if err := t.Validate(); err != nil {
+		log.Err(err).Msg("invalid template")
+		return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+	}
+	if err := templates.NewDB(h.pg).Update(c.Context(), t); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		log.Err(err).Msg("could not update template")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (h *handlers) employerDeleteTemplate(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse template id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	if err := templates.NewDB(h.pg).Delete(c.Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		log.Err(err).Msg("could not delete template")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (h *handlers) employerInstantiateTemplate(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse template id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	var request struct {
+		AssignedUserID int        `json:"assignedUserID"`
+		StartAt        *time.Time `json:"startAt"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		log.Err(err).Msg("could not parse instantiate request")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	if ferr := h.checkAssignee(c.Context(), request.AssignedUserID); ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	startAt := time.Now()
+	if request.StartAt != nil {
+		startAt = *request.StartAt
+	}
+
+	db := templates.NewDB(h.pg)
+	t, err := db.FindOne(c.Context(), templates.FindOptions{IDs: []int{id}})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		log.Err(err).Msg("could not find template")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	task, subtasks := t.Tasks(request.AssignedUserID, startAt)
+	for _, entry := range append(subtasks, task) {
+		if err := entry.Validate(); err != nil {
+			log.Err(err).Msg("invalid task from template")
+			return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+		}
+	}
+	taskID, err := db.Instantiate(c.Context(), t, request.AssignedUserID, startAt)
+	if err != nil {
+		log.Err(err).Msg("could not instantiate template")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"taskId": taskID,
+	})
+}
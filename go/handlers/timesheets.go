@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+
+	"siransbach/taskmanagementapi/auth"
+	"siransbach/taskmanagementapi/dates"
+	"siransbach/taskmanagementapi/export"
+	"siransbach/taskmanagementapi/fiberx"
+	"siransbach/taskmanagementapi/tasks"
+	"siransbach/taskmanagementapi/timesheets"
+)
+
+func (h *handlers) employeeSetTimesheetEntry(c *fiber.Ctx) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	var request struct {
+		TaskID int        `json:"taskId"`
+		Day    dates.Date `json:"day"`
+		Hours  float64    `json:"hours"`
+		Note   string     `json:"note"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		log.Err(err).Msg("could not parse timesheet entry")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	entry := timesheets.Entry{
+		UserID: currentUser.ID,
+		TaskID: request.TaskID,
+		Day:    request.Day,
+		Hours:  request.Hours,
+		Note:   request.Note,
+	}
+	if err := entry.Validate(); err != nil {
+		log.Err(err).Msg("invalid timesheet entry")
+		return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+	}
+	if _, err := tasks.NewDB(h.pg).FindOne(c.Context(), tasks.FindOptions{
+		IDs:            []int{entry.TaskID},
+		AnyAssigneeIDs: []int{currentUser.ID},
+	}); err != nil {
+		log.Err(err).Msg("could not find task")
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusBadRequest, "taskId is not one of your tasks")
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	if err := timesheets.NewDB(h.pg).SetEntry(c.Context(), entry); err != nil {
+		log.Err(err).Msg("could not set timesheet entry")
+		switch {
+		case errors.Is(err, timesheets.ErrLocked):
+			return fiberx.Err(c, fiber.StatusConflict, err.Error())
+		case errors.Is(err, timesheets.ErrDayFull):
+			return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// employeeGetTimesheet returns the timesheet of the week of the :day
+// parameter, any day of the week will do.
+func (h *handlers) employeeGetTimesheet(c *fiber.Ctx) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	day, err := dates.Parse(c.Params("day"))
+	if err != nil {
+		log.Err(err).Msg("could not parse day")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	timesheet, err := timesheets.NewDB(h.pg).Week(c.Context(), currentUser.ID, day)
+	if err != nil {
+		log.Err(err).Msg("could not get timesheet")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"timesheet": timesheet,
+	})
+}
+
+func (h *handlers) employeeSubmitTimesheet(c *fiber.Ctx) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	day, err := dates.Parse(c.Params("day"))
+	if err != nil {
+		log.Err(err).Msg("could not parse day")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	if err := timesheets.NewDB(h.pg).Submit(c.Context(), currentUser.ID, day); err != nil {
+		log.Err(err).Msg("could not submit timesheet")
+		if errors.Is(err, timesheets.ErrTransition) {
+			return fiberx.Err(c, fiber.StatusConflict, "timesheet is already submitted or approved")
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (h *handlers) employerGetTimesheets(c *fiber.Ctx) error {
+	var (
+		opts timesheets.FindOptions
+		err  error
+	)
+	if v := c.Query("status"); v != "" {
+		status, err := timesheets.ParseStatus(v)
+		if err != nil {
+			log.Err(err).Msg("could not parse status")
+			return fiberx.Err(c, fiber.StatusBadRequest)
+		}
+		opts.Statuses = []timesheets.Status{status}
+	}
+	if v := c.Query("userId"); v != "" {
+		userID, err := strconv.Atoi(v)
+		if err != nil {
+			log.Err(err).Msg("could not parse userId")
+			return fiberx.Err(c, fiber.StatusBadRequest)
+		}
+		opts.UserIDs = []int{userID}
+	}
+	if opts.From, opts.To, err = parseRange(c); err != nil {
+		log.Err(err).Msg("could not parse range")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	list, err := timesheets.NewDB(h.pg).Find(c.Context(), opts)
+	if err != nil {
+		log.Err(err).Msg("could not find timesheets")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	if list == nil {
+		list = []timesheets.Timesheet{}
+	}
+	return c.JSON(fiber.Map{
+		"timesheets": list,
+	})
+}
+
+func (h *handlers) employerGetTimesheet(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse timesheet id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	timesheet, err := timesheets.NewDB(h.pg).FindOne(c.Context(), timesheets.FindOptions{IDs: []int{id}})
+	if err != nil {
+		log.Err(err).Msg("could not find timesheet")
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"timesheet": timesheet,
+	})
+}
+
+func (h *handlers) employerApproveTimesheet(c *fiber.Ctx) error {
+	return h.reviewTimesheet(c, timesheets.StatusApproved)
+}
+
+func (h *handlers) employerRejectTimesheet(c *fiber.Ctx) error {
+	return h.reviewTimesheet(c, timesheets.StatusRejected)
+}
+
+// employerExportTimesheets exports the approved timesheets of the weeks
+// starting within the from and to range as CSV, one line per day and task.
+func (h *handlers) employerExportTimesheets(c *fiber.Ctx) error {
+	from, to, err := parseRange(c)
+	if err != nil {
+		log.Err(err).Msg("could not parse range")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	if from.IsZero() || to.IsZero() {
+		return fiberx.Err(c, fiber.StatusBadRequest, "from and to are required")
+	}
+	rows, err := timesheets.NewDB(h.pg).Export(c.Context(), from, to)
+	if err != nil {
+		log.Err(err).Msg("could not export timesheets")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+
+	c.Set(fiber.HeaderContentType, "text/csv")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="timesheets-%s-%s.csv"`,
+		from.Format(time.DateOnly), to.Format(time.DateOnly)))
+	w := csv.NewWriter(c.Response().BodyWriter())
+	_ = w.Write([]string{"user_id", "username", "week_start", "day", "task_id", "task_title", "hours"})
+	for _, r := range rows {
+		_ = w.Write([]string{
+			strconv.Itoa(r.UserID), export.EscapeFormula(r.Username), r.WeekStart.String(), r.Day.String(),
+			strconv.Itoa(r.TaskID), export.EscapeFormula(r.TaskTitle), strconv.FormatFloat(r.Hours, 'f', 2, 64),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Err(err).Msg("could not write timesheets export")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return nil
+}
+
+func (h *handlers) reviewTimesheet(c *fiber.Ctx, status timesheets.Status) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse timesheet id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	var request struct {
+		Comment string `json:"comment"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			log.Err(err).Msg("could not parse review request")
+			return fiberx.Err(c, fiber.StatusBadRequest)
+		}
+	}
+	if err := timesheets.NewDB(h.pg).Review(c.Context(), id, currentUser.ID, status, request.Comment); err != nil {
+		log.Err(err).Msg("could not review timesheet")
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		if errors.Is(err, timesheets.ErrTransition) {
+			return fiberx.Err(c, fiber.StatusConflict, "only submitted timesheets can be reviewed")
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
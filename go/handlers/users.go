@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+
+	"siransbach/taskmanagementapi/auth"
+	"siransbach/taskmanagementapi/fiberx"
+	"siransbach/taskmanagementapi/offboarding"
+)
+
+// employerOffboardUser deactivates an employee and hands their open tasks to
+// the users of reassignTo in turn, or to the backlog when there are none.
+func (h *handlers) employerOffboardUser(c *fiber.Ctx) error {
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse user id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	var request struct {
+		ReassignTo []int `json:"reassignTo"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		log.Err(err).Msg("could not parse offboarding request")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+
+	user, err := auth.NewDB(h.pg).FindOne(c.Context(), auth.FindOptions{IDs: []int{userID}})
+	if err != nil {
+		log.Err(err).Msg("could not find user")
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	if !user.IsEmployee() {
+		log.Error().Msg(fmt.Sprintf("user %d is not an employee", userID))
+		return fiberx.Err(c, fiber.StatusBadRequest, "only employees can be offboarded")
+	}
+	for _, targetID := range request.ReassignTo {
+		if targetID == userID {
+			log.Error().Msg("offboarded user among the reassignment targets")
+			return fiberx.Err(c, fiber.StatusBadRequest, "cannot reassign tasks to the offboarded user")
+		}
+		if ferr := h.checkAssignee(c.Context(), targetID); ferr != nil {
+			return fiberx.Err(c, ferr.Code, ferr.Message)
+		}
+	}
+
+	report, err := offboarding.Offboard(c.Context(), h.pg, userID, request.ReassignTo)
+	if err != nil {
+		log.Err(err).Msg("could not offboard user")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(report)
+}
+
+func (h *handlers) employerReactivateUser(c *fiber.Ctx) error {
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse user id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	if err := auth.NewDB(h.pg).Activate(c.Context(), userID); err != nil {
+		log.Err(err).Msg("could not reactivate user")
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+
+	"siransbach/taskmanagementapi/auth"
+	"siransbach/taskmanagementapi/fiberx"
+	"siransbach/taskmanagementapi/tasks"
+	"siransbach/taskmanagementapi/views"
+)
+
+func (h *handlers) getViews(c *fiber.Ctx) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	list, err := views.NewDB(h.pg).Find(c.Context(), views.FindOptions{VisibleTo: currentUser.ID})
+	if err != nil {
+		log.Err(err).Msg("could not find views")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	if list == nil {
+		list = []views.View{}
+	}
+	return c.JSON(fiber.Map{
+		"views": list,
+	})
+}
+
+func (h *handlers) getView(c *fiber.Ctx) error {
+	v, ferr := h.visibleView(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	return c.JSON(fiber.Map{
+		"view": v,
+	})
+}
+
+func (h *handlers) createView(c *fiber.Ctx) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	var v views.View
+	if err := c.BodyParser(&v); err != nil {
+		log.Err(err).Msg("could not parse view request")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	v.OwnerID = currentUser.ID
+	if err := v.Validate(); err != nil {
+		log.Err(err).Msg("invalid view")
+		return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+	}
+	id, err := views.NewDB(h.pg).Insert(c.Context(), v)
+	if err != nil {
+		log.Err(err).Msg("could not create view")
+		if errors.Is(err, views.ErrViewExists) {
+			return fiberx.Err(c, fiber.StatusConflict, err.Error())
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"viewId": id,
+	})
+}
+
+// updateView changes a view of the current user. The views shared by others
+// cannot be changed.
+func (h *handlers) updateView(c *fiber.Ctx) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse view id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	var v views.View
+	if err := c.BodyParser(&v); err != nil {
+		log.Err(err).Msg("could not parse view request")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	v.ID, v.OwnerID = id, currentUser.ID
+	if err := v.Validate(); err != nil {
+		log.Err(err).Msg("invalid view")
+		return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+	}
+	if err := views.NewDB(h.pg).Update(c.Context(), v); err != nil {
+		log.Err(err).Msg("could not update view")
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		if errors.Is(err, views.ErrViewExists) {
+			return fiberx.Err(c, fiber.StatusConflict, err.Error())
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (h *handlers) deleteView(c *fiber.Ctx) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse view id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	if err := views.NewDB(h.pg).Delete(c.Context(), id, currentUser.ID); err != nil {
+		log.Err(err).Msg("could not delete view")
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// getViewTasks lists the tasks of a view, paginated like the other listings.
+// Employees only get the tasks they can list otherwise.
+func (h *handlers) getViewTasks(c *fiber.Ctx) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	v, ferr := h.visibleView(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	var opts tasks.FindOptions
+	if !currentUser.IsEmployer() {
+		opts.AnyAssigneeIDs = []int{currentUser.ID}
+	}
+	if opts, err = v.Apply(opts); err != nil {
+		// the view was valid when it was saved
+		log.Err(err).Int("viewId", v.ID).Msg("could not apply view")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return h.sendTaskPage(c, opts)
+}
+
+func (h *handlers) setDefaultView(c *fiber.Ctx) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse view id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	if err := views.NewDB(h.pg).SetDefault(c.Context(), currentUser.ID, id); err != nil {
+		log.Err(err).Msg("could not set default view")
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (h *handlers) clearDefaultView(c *fiber.Ctx) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	if err := views.NewDB(h.pg).ClearDefault(c.Context(), currentUser.ID); err != nil {
+		log.Err(err).Msg("could not clear default view")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// visibleView finds the view of the id path parameter, provided the current
+// user owns it or it is shared.
+func (h *handlers) visibleView(c *fiber.Ctx) (views.View, *fiber.Error) {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return views.View{}, &fiber.Error{Code: fiber.StatusUnauthorized}
+	}
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse view id")
+		return views.View{}, &fiber.Error{Code: fiber.StatusBadRequest}
+	}
+	v, err := views.NewDB(h.pg).FindOne(c.Context(), views.FindOptions{IDs: []int{id}, VisibleTo: currentUser.ID})
+	if err != nil {
+		log.Err(err).Msg("could not find view")
+		if errors.Is(err, sql.ErrNoRows) {
+			return views.View{}, &fiber.Error{Code: fiber.StatusNotFound}
+		}
+		return views.View{}, &fiber.Error{Code: fiber.StatusInternalServerError}
+	}
+	return v, nil
+}
+
+// applyDefaultView applies the default view of the user to opts when a task
+// listing is called without query parameters.
+func (h *handlers) applyDefaultView(c *fiber.Ctx, userID int, opts tasks.FindOptions) (tasks.FindOptions, *fiber.Error) {
+	if len(c.Request().URI().QueryString()) > 0 {
+		return opts, nil
+	}
+	v, err := views.NewDB(h.pg).Default(c.Context(), userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return opts, nil
+		}
+		log.Err(err).Msg("could not find default view")
+		return opts, &fiber.Error{Code: fiber.StatusInternalServerError}
+	}
+	if opts, err = v.Apply(opts); err != nil {
+		log.Err(err).Int("viewId", v.ID).Msg("could not apply default view")
+		return opts, &fiber.Error{Code: fiber.StatusInternalServerError}
+	}
+	return opts, nil
+}
@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+
+	"siransbach/taskmanagementapi/auth"
+	"siransbach/taskmanagementapi/fiberx"
+	"siransbach/taskmanagementapi/tasks"
+	"siransbach/taskmanagementapi/worklogs"
+)
+
+func (h *handlers) employeeGetTimer(c *fiber.Ctx) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	timer, err := worklogs.NewDB(h.pg).FindOne(c.Context(), worklogs.FindOptions{
+		UserIDs: []int{currentUser.ID},
+		Running: true,
+	})
+	if err != nil {
+		log.Err(err).Msg("could not find timer")
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound, worklogs.ErrNoTimer.Error())
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"timer": timer,
+	})
+}
+
+func (h *handlers) employeeStartTimer(c *fiber.Ctx) error {
+	currentUser, task, ferr := h.findOwnTask(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	var request struct {
+		Description string `json:"description"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			log.Err(err).Msg("could not parse timer request")
+			return fiberx.Err(c, fiber.StatusBadRequest)
+		}
+	}
+	id, err := worklogs.NewDB(h.pg).StartTimer(c.Context(), task.ID, currentUser.ID, request.Description)
+	if err != nil {
+		log.Err(err).Msg("could not start timer")
+		if errors.Is(err, worklogs.ErrTimerRunning) {
+			return fiberx.Err(c, fiber.StatusConflict, err.Error())
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"worklogId": id,
+	})
+}
+
+func (h *handlers) employeeStopTimer(c *fiber.Ctx) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	worklog, err := worklogs.NewDB(h.pg).StopTimer(c.Context(), currentUser.ID)
+	if err != nil {
+		log.Err(err).Msg("could not stop timer")
+		if errors.Is(err, worklogs.ErrNoTimer) {
+			return fiberx.Err(c, fiber.StatusNotFound, err.Error())
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"worklog": worklog,
+	})
+}
+
+func (h *handlers) employeeCreateWorklog(c *fiber.Ctx) error {
+	currentUser, task, ferr := h.findOwnTask(c)
+	if ferr != nil {
+		return fiberx.Err(c, ferr.Code, ferr.Message)
+	}
+	var request struct {
+		StartedAt   time.Time `json:"startedAt"`
+		Minutes     int       `json:"minutes"`
+		Description string    `json:"description"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		log.Err(err).Msg("could not parse worklog request")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	endedAt := request.StartedAt.Add(time.Duration(request.Minutes) * time.Minute)
+	w := worklogs.Worklog{
+		TaskID:      task.ID,
+		UserID:      currentUser.ID,
+		StartedAt:   request.StartedAt,
+		EndedAt:     &endedAt,
+		Description: request.Description,
+	}
+	if err := w.Validate(); err != nil {
+		log.Err(err).Msg("invalid worklog")
+		return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+	}
+	id, err := worklogs.NewDB(h.pg).Insert(c.Context(), w)
+	if err != nil {
+		log.Err(err).Msg("could not create worklog")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.JSON(fiber.Map{
+		"worklogId": id,
+	})
+}
+
+func (h *handlers) employeeGetWorklogs(c *fiber.Ctx) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	opts := worklogs.FindOptions{UserIDs: []int{currentUser.ID}}
+	if opts.From, opts.To, err = parseRange(c); err != nil {
+		log.Err(err).Msg("could not parse range")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	list, err := worklogs.NewDB(h.pg).Find(c.Context(), opts)
+	if err != nil {
+		log.Err(err).Msg("could not find worklogs")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	if list == nil {
+		list = []worklogs.Worklog{}
+	}
+	return c.JSON(fiber.Map{
+		"worklogs": list,
+	})
+}
+
+func (h *handlers) employeeDeleteWorklog(c *fiber.Ctx) error {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return fiberx.Err(c, fiber.StatusUnauthorized)
+	}
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse worklog id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	if err := worklogs.NewDB(h.pg).Delete(c.Context(), id, currentUser.ID); err != nil {
+		log.Err(err).Msg("could not delete worklog")
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (h *handlers) employerSetEstimates(c *fiber.Ctx) error {
+	taskID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse task id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	var request struct {
+		OriginalEstimate  *int `json:"originalEstimate"`
+		RemainingEstimate *int `json:"remainingEstimate"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		log.Err(err).Msg("could not parse estimates request")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	if (request.OriginalEstimate != nil && *request.OriginalEstimate < 0) ||
+		(request.RemainingEstimate != nil && *request.RemainingEstimate < 0) {
+		return fiberx.Err(c, fiber.StatusBadRequest, "invalid estimate")
+	}
+	if err := tasks.NewDB(h.pg).SetEstimates(c.Context(), taskID, request.OriginalEstimate, request.RemainingEstimate); err != nil {
+		log.Err(err).Msg("could not set estimates")
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiberx.Err(c, fiber.StatusNotFound)
+		}
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (h *handlers) employerGetTaskWorklogs(c *fiber.Ctx) error {
+	taskID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse task id")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+	list, err := worklogs.NewDB(h.pg).Find(c.Context(), worklogs.FindOptions{TaskIDs: []int{taskID}})
+	if err != nil {
+		log.Err(err).Msg("could not find worklogs")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	if list == nil {
+		list = []worklogs.Worklog{}
+	}
+	return c.JSON(fiber.Map{
+		"worklogs": list,
+	})
+}
+
+// employerGetWorklogTotals sums up logged time, grouped by any of task, user
+// and period.
+func (h *handlers) employerGetWorklogTotals(c *fiber.Ctx) error {
+	opts := worklogs.TotalsOptions{Granularity: tasks.GranularityDay}
+	var err error
+	if v := c.Query("groupBy"); v != "" {
+		for _, g := range strings.Split(v, ",") {
+			groupBy, err := worklogs.ParseGroupBy(strings.TrimSpace(g))
+			if err != nil {
+				log.Err(err).Msg("could not parse groupBy")
+				return fiberx.Err(c, fiber.StatusBadRequest, err.Error())
+			}
+			opts.GroupBy = append(opts.GroupBy, groupBy)
+		}
+	}
+	if v := c.Query("granularity"); v != "" {
+		opts.Granularity, err = tasks.ParseGranularity(v)
+		if err != nil {
+			log.Err(err).Msg("could not parse granularity")
+			return fiberx.Err(c, fiber.StatusBadRequest)
+		}
+	}
+	for param, ids := range map[string]*[]int{
+		"taskId":    &opts.TaskIDs,
+		"userId":    &opts.UserIDs,
+		"projectId": &opts.ProjectIDs,
+	} {
+		if v := c.Query(param); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				log.Err(err).Msg("could not parse " + param)
+				return fiberx.Err(c, fiber.StatusBadRequest)
+			}
+			*ids = []int{id}
+		}
+	}
+	if opts.From, opts.To, err = parseRange(c); err != nil {
+		log.Err(err).Msg("could not parse range")
+		return fiberx.Err(c, fiber.StatusBadRequest)
+	}
+
+	totals, err := worklogs.NewDB(h.pg).Totals(c.Context(), opts)
+	if err != nil {
+		log.Err(err).Msg("could not sum up worklogs")
+		return fiberx.Err(c, fiber.StatusInternalServerError)
+	}
+	if totals == nil {
+		totals = []worklogs.Total{}
+	}
+	return c.JSON(fiber.Map{
+		"totals": totals,
+	})
+}
+
+// findOwnTask finds the task of the :id parameter among the tasks of the
+// current user, and returns the error to respond with otherwise.
+func (h *handlers) findOwnTask(c *fiber.Ctx) (*auth.User, tasks.Entry, *fiber.Error) {
+	currentUser, err := auth.CurrentUser(c)
+	if err != nil {
+		log.Err(err).Msg("could not get current user")
+		return nil, tasks.Entry{}, &fiber.Error{Code: fiber.StatusUnauthorized}
+	}
+	taskID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		log.Err(err).Msg("could not parse task id")
+		return currentUser, tasks.Entry{}, &fiber.Error{Code: fiber.StatusBadRequest}
+	}
+	task, err := tasks.NewDB(h.pg).FindOne(c.Context(), tasks.FindOptions{
+		IDs:            []int{taskID},
+		AnyAssigneeIDs: []int{currentUser.ID},
+	})
+	if err != nil {
+		log.Err(err).Msg("could not find task")
+		if errors.Is(err, sql.ErrNoRows) {
+			return currentUser, task, &fiber.Error{Code: fiber.StatusNotFound}
+		}
+		return currentUser, task, &fiber.Error{Code: fiber.StatusInternalServerError}
+	}
+	return currentUser, task, nil
+}
+
+// parseRange parses the optional from and to query parameters, in RFC3339.
+func parseRange(c *fiber.Ctx) (from, to time.Time, err error) {
+	if v := c.Query("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			return from, to, err
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			return from, to, err
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return from, to, errors.New("from must be before to")
+	}
+	return from, to, nil
+}
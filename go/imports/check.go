@@ -0,0 +1,150 @@
+package imports
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"siransbach/taskmanagementapi/auth"
+	"siransbach/taskmanagementapi/projects"
+)
+
+// Check validates the tasks of the rows and their assignees, the same way as
+// tasks created one by one, and adds what is wrong to the errors of the rows.
+// Assignees given by username are resolved to their ID. The users and projects
+// of all the rows are loaded at once. Imports have no custom field values, so
+// the tasks of projects with required custom fields cannot be imported.
+func Check(ctx context.Context, pg *sql.DB, rows []Row) error {
+	users, err := findUsers(ctx, pg, rows)
+	if err != nil {
+		return err
+	}
+	projectLookup, err := findProjects(ctx, pg, rows)
+	if err != nil {
+		return err
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		if row.AssignedUsername != "" {
+			user, ok := users.byUsername[row.AssignedUsername]
+			switch {
+			case !ok:
+				row.Errors = append(row.Errors, "unknown assigned_username: "+row.AssignedUsername)
+			case row.Entry.AssignedUserID > 0 && row.Entry.AssignedUserID != user.ID:
+				row.Errors = append(row.Errors, "assigned_user_id and assigned_username are different users")
+			default:
+				row.Entry.AssignedUserID = user.ID
+			}
+		}
+		if err := row.Entry.Validate(); err != nil {
+			row.Errors = append(row.Errors, err.Error())
+		}
+
+		userID := row.Entry.AssignedUserID
+		if userID > 0 {
+			user, ok := users.byID[userID]
+			switch {
+			case !ok:
+				row.Errors = append(row.Errors, fmt.Sprintf("user %d does not exist", userID))
+			case user.IsEmployer():
+				row.Errors = append(row.Errors, "assignee is not an employee")
+			case !user.Active:
+				row.Errors = append(row.Errors, "assignee is inactive")
+			}
+		}
+		if projectID := row.Entry.ProjectID; projectID > 0 {
+			p, ok := projectLookup.byID[projectID]
+			switch {
+			case !ok:
+				row.Errors = append(row.Errors, fmt.Sprintf("project %d does not exist", projectID))
+			case p.Archived:
+				row.Errors = append(row.Errors, projects.ErrArchived.Error())
+			case userID > 0 && !p.HasMember(userID):
+				row.Errors = append(row.Errors, "assignee is not a member of the project")
+			}
+			if keys := projectLookup.requiredFields[projectID]; len(keys) > 0 {
+				row.Errors = append(row.Errors, fmt.Sprintf(
+					"project %d has required custom fields, which cannot be imported: %s",
+					projectID, strings.Join(keys, ", "),
+				))
+			}
+		}
+	}
+	return nil
+}
+
+type userLookup struct {
+	byID       map[int]*auth.User
+	byUsername map[string]*auth.User
+}
+
+func findUsers(ctx context.Context, pg *sql.DB, rows []Row) (userLookup, error) {
+	lookup := userLookup{byID: make(map[int]*auth.User), byUsername: make(map[string]*auth.User)}
+	var (
+		ids       []int
+		usernames []string
+	)
+	for _, row := range rows {
+		if row.Entry.AssignedUserID > 0 {
+			ids = append(ids, row.Entry.AssignedUserID)
+		}
+		if row.AssignedUsername != "" {
+			usernames = append(usernames, row.AssignedUsername)
+		}
+	}
+	db := auth.NewDB(pg)
+	// FindOptions narrows users down by all of its fields, hence a query each
+	for _, opts := range []auth.FindOptions{{IDs: ids}, {Usernames: usernames}} {
+		if len(opts.IDs) == 0 && len(opts.Usernames) == 0 {
+			continue
+		}
+		users, err := db.Find(ctx, opts)
+		if err != nil {
+			return lookup, err
+		}
+		for _, user := range users {
+			lookup.byID[user.ID] = user
+			lookup.byUsername[user.Username] = user
+		}
+	}
+	return lookup, nil
+}
+
+type projectLookup struct {
+	byID map[int]projects.Project
+	// requiredFields are the keys of the required custom fields by project
+	requiredFields map[int][]string
+}
+
+func findProjects(ctx context.Context, pg *sql.DB, rows []Row) (projectLookup, error) {
+	lookup := projectLookup{byID: make(map[int]projects.Project), requiredFields: make(map[int][]string)}
+	var ids []int
+	for _, row := range rows {
+		if row.Entry.ProjectID > 0 {
+			ids = append(ids, row.Entry.ProjectID)
+		}
+	}
+	if len(ids) == 0 {
+		return lookup, nil
+	}
+	db := projects.NewDB(pg)
+	found, err := db.Find(ctx, projects.FindOptions{IDs: ids, IncludeArchived: true})
+	if err != nil {
+		return lookup, err
+	}
+	for _, p := range found {
+		lookup.byID[p.ID] = p
+	}
+	fields, err := db.FindCustomFields(ctx, projects.CustomFieldFindOptions{ProjectIDs: ids})
+	if err != nil {
+		return lookup, err
+	}
+	for _, f := range fields {
+		if f.Required {
+			lookup.requiredFields[f.ProjectID] = append(lookup.requiredFields[f.ProjectID], f.Key)
+		}
+	}
+	return lookup, nil
+}
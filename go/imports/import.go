@@ -0,0 +1,271 @@
+package imports
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"siransbach/taskmanagementapi/tasks"
+)
+
+type (
+	// Row is a task read from an import, along with what is wrong with it.
+	// Number is the position of the row among the tasks, from 1.
+	Row struct {
+		Number int
+		Entry  tasks.Entry
+		// AssignedUsername, when set, designates the assignee by username
+		// rather than by ID
+		AssignedUsername string
+		Errors           []string
+	}
+
+	// RowError lists what is wrong with a row of an import.
+	RowError struct {
+		Row    int      `json:"row"`
+		Errors []string `json:"errors"`
+	}
+
+	// jsonRow is a task of a JSON import, in the shape of the tasks exported as
+	// NDJSON.
+	jsonRow struct {
+		Title             string    `json:"title"`
+		Description       string    `json:"description"`
+		Status            string    `json:"status"`
+		Priority          string    `json:"priority"`
+		Labels            []string  `json:"labels"`
+		AssignedUserID    int       `json:"assignedUserId"`
+		AssignedUsername  string    `json:"assignedUsername"`
+		ProjectID         int       `json:"projectId"`
+		DueDate           time.Time `json:"dueDate"`
+		OriginalEstimate  *int      `json:"originalEstimate"`
+		RemainingEstimate *int      `json:"remainingEstimate"`
+	}
+)
+
+// MaxRows is the largest number of tasks imported at once.
+const MaxRows = 5000
+
+var ErrTooManyRows = fmt.Errorf("too many rows, at most %d can be imported at once", MaxRows)
+
+// csvColumns set the field of the row read from each column of a CSV import,
+// named after the columns of the CSV export.
+var csvColumns = map[string]func(row *Row, value string) error{
+	"title":       func(row *Row, value string) error { row.Entry.Title = value; return nil },
+	"description": func(row *Row, value string) error { row.Entry.Description = value; return nil },
+	"status": func(row *Row, value string) (err error) {
+		row.Entry.Status, err = tasks.ParseStatus(value)
+		return err
+	},
+	"priority": func(row *Row, value string) (err error) {
+		row.Entry.Priority, err = tasks.ParsePriority(value)
+		return err
+	},
+	"labels": func(row *Row, value string) error {
+		for _, label := range strings.Split(value, ",") {
+			if label = strings.TrimSpace(label); label != "" {
+				row.Entry.Labels = append(row.Entry.Labels, label)
+			}
+		}
+		return nil
+	},
+	"assigned_user_id": func(row *Row, value string) (err error) {
+		row.Entry.AssignedUserID, err = parseID(value)
+		return err
+	},
+	"assigned_username": func(row *Row, value string) error { row.AssignedUsername = value; return nil },
+	"project_id": func(row *Row, value string) (err error) {
+		row.Entry.ProjectID, err = parseID(value)
+		return err
+	},
+	"due_date": func(row *Row, value string) (err error) {
+		if row.Entry.DueDate, err = time.Parse(time.RFC3339, value); err != nil {
+			return errors.New("expected RFC3339: " + value)
+		}
+		return nil
+	},
+	"original_estimate": func(row *Row, value string) (err error) {
+		row.Entry.OriginalEstimate, err = parseMinutes(value)
+		return err
+	},
+	"remaining_estimate": func(row *Row, value string) (err error) {
+		row.Entry.RemainingEstimate, err = parseMinutes(value)
+		return err
+	},
+}
+
+// exportOnlyColumns are the columns of the CSV export that cannot be imported,
+// and are skipped so that exports can be imported back.
+var exportOnlyColumns = map[string]bool{"id": true, "created_at": true, "parent_task_id": true}
+
+// ReadCSV reads the tasks of a CSV import, whose header names the columns.
+// Values that cannot be read are reported in the errors of their row; an error
+// is only returned when the CSV itself is malformed.
+func ReadCSV(r io.Reader) ([]Row, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("missing header")
+		}
+		return nil, err
+	}
+	setters := make([]func(row *Row, value string) error, len(header))
+	names := make([]string, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if i == 0 {
+			// spreadsheets may start UTF-8 files with a byte order mark
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		set, ok := csvColumns[name]
+		if !ok && !exportOnlyColumns[name] {
+			return nil, fmt.Errorf("unknown column: %s", name)
+		}
+		setters[i], names[i] = set, name
+	}
+
+	var rows []Row
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) == MaxRows {
+			return nil, ErrTooManyRows
+		}
+		row := Row{Number: len(rows) + 1}
+		for i, value := range record {
+			if value = strings.TrimSpace(value); value == "" || setters[i] == nil {
+				continue
+			}
+			if err := setters[i](&row, value); err != nil {
+				row.Errors = append(row.Errors, fmt.Sprintf("%s: %v", names[i], err))
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+// ReadJSON reads the tasks of a JSON import, either an array of tasks or tasks
+// one after the other as exported in NDJSON. Tasks that cannot be read are
+// reported in the errors of their row.
+func ReadJSON(r io.Reader) ([]Row, error) {
+	br := bufio.NewReader(r)
+	dec := json.NewDecoder(br)
+	array, err := startsWithArray(br)
+	if err != nil {
+		return nil, err
+	}
+	if array {
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	}
+
+	var rows []Row
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		if len(rows) == MaxRows {
+			return nil, ErrTooManyRows
+		}
+		rows = append(rows, readJSONRow(len(rows)+1, raw))
+	}
+	if array {
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+func readJSONRow(number int, raw json.RawMessage) Row {
+	row := Row{Number: number}
+	var r jsonRow
+	if err := json.Unmarshal(raw, &r); err != nil {
+		row.Errors = append(row.Errors, err.Error())
+		return row
+	}
+	row.Entry = tasks.Entry{
+		Title:             r.Title,
+		Description:       r.Description,
+		Labels:            r.Labels,
+		AssignedUserID:    r.AssignedUserID,
+		ProjectID:         r.ProjectID,
+		DueDate:           r.DueDate,
+		OriginalEstimate:  r.OriginalEstimate,
+		RemainingEstimate: r.RemainingEstimate,
+	}
+	row.AssignedUsername = r.AssignedUsername
+	var err error
+	if r.Status != "" {
+		if row.Entry.Status, err = tasks.ParseStatus(r.Status); err != nil {
+			row.Errors = append(row.Errors, "status: "+err.Error())
+		}
+	}
+	if r.Priority != "" {
+		if row.Entry.Priority, err = tasks.ParsePriority(r.Priority); err != nil {
+			row.Errors = append(row.Errors, "priority: "+err.Error())
+		}
+	}
+	return row
+}
+
+// startsWithArray tells whether the next value of r is an array, leaving it
+// unread.
+func startsWithArray(r *bufio.Reader) (bool, error) {
+	for {
+		b, err := r.Peek(1)
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if !bytes.ContainsAny(b, " \t\r\n") {
+			return b[0] == '[', nil
+		}
+		_, _ = r.ReadByte()
+	}
+}
+
+// Errors returns the rows that have errors.
+func Errors(rows []Row) []RowError {
+	errs := []RowError{}
+	for _, row := range rows {
+		if len(row.Errors) > 0 {
+			errs = append(errs, RowError{Row: row.Number, Errors: row.Errors})
+		}
+	}
+	return errs
+}
+
+func parseID(value string) (int, error) {
+	id, err := strconv.Atoi(value)
+	if err != nil || id <= 0 {
+		return 0, errors.New("invalid ID: " + value)
+	}
+	return id, nil
+}
+
+func parseMinutes(value string) (*int, error) {
+	minutes, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, errors.New("expected minutes: " + value)
+	}
+	return &minutes, nil
+}
@@ -0,0 +1,110 @@
+package imports
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"siransbach/taskmanagementapi/tasks"
+)
+
+func TestReadCSV(t *testing.T) {
+	estimate := 90
+	input := "\ufeffid,Title,description,status,priority,labels,assigned_username,project_id,due_date,original_estimate\n" +
+		`7,Write report,"Quarterly, with figures",in_progress,high,"finance, q1",jdoe,3,2030-03-08T17:30:00Z,90` + "\n" +
+		"8,Broken,,DONE,,,,x,tomorrow,1h\n"
+
+	rows, err := ReadCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []Row{
+		{
+			Number: 1,
+			Entry: tasks.Entry{
+				Title:            "Write report",
+				Description:      "Quarterly, with figures",
+				Status:           tasks.StatusInProgress,
+				Priority:         tasks.PriorityHigh,
+				Labels:           []string{"finance", "q1"},
+				ProjectID:        3,
+				DueDate:          time.Date(2030, 3, 8, 17, 30, 0, 0, time.UTC),
+				OriginalEstimate: &estimate,
+			},
+			AssignedUsername: "jdoe",
+		},
+		{
+			Number: 2,
+			Entry:  tasks.Entry{Title: "Broken"},
+			Errors: []string{
+				"status: invalid status",
+				"project_id: invalid ID: x",
+				"due_date: expected RFC3339: tomorrow",
+				"original_estimate: expected minutes: 1h",
+			},
+		},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("expected %+v, got %+v", expected, rows)
+	}
+
+	if _, err := ReadCSV(strings.NewReader("title,owner\n")); err == nil || err.Error() != "unknown column: owner" {
+		t.Errorf("expected an unknown column error, got %v", err)
+	}
+	if _, err := ReadCSV(strings.NewReader("")); err == nil {
+		t.Error("expected an error without header")
+	}
+}
+
+func TestReadJSON(t *testing.T) {
+	due := time.Date(2030, 3, 8, 17, 30, 0, 0, time.UTC)
+	expected := []Row{
+		{
+			Number: 1,
+			Entry:  tasks.Entry{Title: "Write report", Priority: tasks.PriorityLow, AssignedUserID: 2, DueDate: due},
+		},
+		{
+			Number: 2,
+			Entry:  tasks.Entry{Title: "Broken"},
+			Errors: []string{"status: invalid status"},
+		},
+	}
+	inputs := map[string]string{
+		"array": ` [{"id": 5, "title": "Write report", "priority": "low", "assignedUserId": 2, "dueDate": "2030-03-08T17:30:00Z"},
+			{"title": "Broken", "status": "DONE"}]`,
+		"ndjson": `{"id": 5, "title": "Write report", "priority": "low", "assignedUserId": 2, "dueDate": "2030-03-08T17:30:00Z"}
+{"title": "Broken", "status": "DONE"}
+`,
+	}
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			rows, err := ReadJSON(strings.NewReader(input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(rows, expected) {
+				t.Errorf("expected %+v, got %+v", expected, rows)
+			}
+		})
+	}
+
+	rows, err := ReadJSON(strings.NewReader(`[{"title": 1}]`))
+	if err != nil || len(rows) != 1 || len(rows[0].Errors) != 1 {
+		t.Errorf("expected a row error, got %+v (%v)", rows, err)
+	}
+	if _, err := ReadJSON(strings.NewReader(`[{"title": "a"`)); err == nil {
+		t.Error("expected an error for malformed JSON")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	rows := []Row{{Number: 1}, {Number: 2, Errors: []string{"missing title"}}}
+	expected := []RowError{{Row: 2, Errors: []string{"missing title"}}}
+	if errs := Errors(rows); !reflect.DeepEqual(errs, expected) {
+		t.Errorf("expected %v, got %v", expected, errs)
+	}
+	if errs := Errors(rows[:1]); errs == nil || len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+}
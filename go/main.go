@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"os"
 	"os/signal"
@@ -18,6 +19,9 @@ import (
 	"siransbach/taskmanagementapi/config"
 	"siransbach/taskmanagementapi/handlers"
 	"siransbach/taskmanagementapi/postgres"
+	"siransbach/taskmanagementapi/recurring"
+	"siransbach/taskmanagementapi/snapshots"
+	"siransbach/taskmanagementapi/tasks"
 )
 
 func main() {
@@ -33,15 +37,26 @@ func main() {
 		}
 	}()
 
+	if len(os.Args) > 1 && os.Args[1] == "reconcile-summaries" {
+		reconcileSummaries(pg)
+		return
+	}
+
 	app := setupFiberApp(pg)
 
 	handlers.Setup(app, pg)
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go recurring.NewScheduler(pg, config.RecurrenceInterval(), config.RecurrenceHorizon()).Run(ctx)
+	go snapshots.NewScheduler(pg, config.SnapshotInterval()).Run(ctx)
+
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-shutdown
 		log.Info().Msg("shutting down the server...")
+		cancel()
 		// graceful shutdown
 		if err := app.Shutdown(); err != nil {
 			log.Error().Err(err).Msg("error shutting down the server")
@@ -53,6 +68,24 @@ func main() {
 	}
 }
 
+// reconcileSummaries rebuilds the task summary read model and reports the rows
+// that had drifted from the tasks.
+func reconcileSummaries(pg *sql.DB) {
+	drifts, err := tasks.ReconcileSummaries(context.Background(), pg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("error reconciling the task summaries")
+	}
+	for _, d := range drifts {
+		log.Warn().
+			Int("userId", d.UserID).
+			Int("projectId", d.ProjectID).
+			Interface("stored", d.Stored).
+			Interface("actual", d.Actual).
+			Msg("task summary drifted")
+	}
+	log.Info().Msgf("rebuilt the task summaries, %d rows had drifted", len(drifts))
+}
+
 func setupLogger() {
 	logLevel, err := zerolog.ParseLevel(config.LogLevel())
 	if err != nil {
@@ -76,7 +109,7 @@ func setupFiberApp(pg *sql.DB) *fiber.App {
 		}),
 	)
 	app.Use(recover.New(recover.Config{EnableStackTrace: true}))
-	app.Use(auth.NewMiddleware(pg))
+	app.Use(auth.NewMiddleware(pg, handlers.CalendarFeedPath))
 	app.Get("/ping", func(c *fiber.Ctx) error {
 		return c.SendString("pong")
 	})
@@ -0,0 +1,259 @@
+package offboarding
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+
+	"siransbach/taskmanagementapi/tasks"
+	"siransbach/taskmanagementapi/worklogs"
+)
+
+type (
+	// Report tells what became of the open tasks of an offboarded employee.
+	Report struct {
+		UserID int    `json:"userId"`
+		Moved  []Move `json:"moved"`
+		// UnsharedTaskIDs are the open tasks the employee was removed from as a
+		// shared assignee
+		UnsharedTaskIDs []int `json:"unsharedTaskIds"`
+		// Recurring are the recurring tasks whose future occurrences go to
+		// another employee, or to the backlog
+		Recurring []RecurringMove `json:"recurring"`
+		// StoppedWorklogID is the worklog of the timer the employee left
+		// running, if any
+		StoppedWorklogID int `json:"stoppedWorklogId,omitempty"`
+	}
+
+	// RecurringMove is a recurring task handed from the offboarded employee to
+	// another one, or to the backlog when ToUserID is 0.
+	RecurringMove struct {
+		RecurringTaskID int    `json:"recurringTaskId"`
+		Title           string `json:"title"`
+		ToUserID        int    `json:"toUserId"`
+	}
+
+	// Move is an open task handed from the offboarded employee to another one,
+	// or to the backlog when ToUserID is 0.
+	Move struct {
+		TaskID   int    `json:"taskId"`
+		Title    string `json:"title"`
+		ToUserID int    `json:"toUserId"`
+		// Reason tells why the task went to the backlog although there were
+		// users to hand it to
+		Reason string `json:"reason,omitempty"`
+	}
+
+	openTask struct {
+		id        int
+		title     string
+		projectID int
+	}
+)
+
+// Offboard deactivates the employee and hands their open tasks, earliest due
+// first, to the target users in turn, skipping those who are not members of
+// the project of a task. Without targets, or when none of them can take a
+// task, the task goes to the backlog. The recurring tasks of the employee are
+// handed to the targets in turn the same way. The employee also stops sharing
+// open tasks, their running timer is stopped and their calendar feed is
+// revoked. The targets must be active employees.
+func Offboard(ctx context.Context, pg *sql.DB, userID int, targetIDs []int) (report Report, err error) {
+	tx, err := pg.BeginTx(ctx, nil)
+	if err != nil {
+		return Report{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if err = tx.QueryRowContext(ctx,
+		"UPDATE auth.users SET active = FALSE WHERE id = $1 AND role = 'EMPLOYEE' RETURNING id", userID,
+	).Scan(&userID); err != nil {
+		return Report{}, err
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM auth.calendar_tokens WHERE user_id = $1", userID); err != nil {
+		return Report{}, err
+	}
+	stopped, err := worklogs.NewDB(tx).StopTimer(ctx, userID)
+	if errors.Is(err, worklogs.ErrNoTimer) {
+		err = nil
+	}
+	if err != nil {
+		return Report{}, err
+	}
+
+	open, err := findOpenTasks(ctx, tx, userID)
+	if err != nil {
+		return Report{}, err
+	}
+	members, err := findMembers(ctx, tx, open, targetIDs)
+	if err != nil {
+		return Report{}, err
+	}
+	recurring, err := findRecurringTasks(ctx, tx, userID)
+	if err != nil {
+		return Report{}, err
+	}
+	report = Report{
+		UserID:           userID,
+		Moved:            plan(open, targetIDs, members),
+		UnsharedTaskIDs:  []int{},
+		Recurring:        planRecurring(recurring, targetIDs),
+		StoppedWorklogID: stopped.ID,
+	}
+	db := tasks.NewDB(tx)
+	for _, m := range report.Moved {
+		if err = db.Reassign(ctx, m.TaskID, m.ToUserID); err != nil {
+			return Report{}, err
+		}
+	}
+	for _, m := range report.Recurring {
+		var assignedUserID *int
+		if m.ToUserID > 0 {
+			assignedUserID = &m.ToUserID
+		}
+		if _, err = tx.ExecContext(ctx,
+			"UPDATE api.recurring_tasks SET assigned_user_id = $1 WHERE id = $2", assignedUserID, m.RecurringTaskID,
+		); err != nil {
+			return Report{}, err
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		DELETE FROM api.task_assignees
+		WHERE user_id = $1 AND NOT is_primary
+			AND task_id IN (SELECT id FROM api.tasks WHERE status <> 'COMPLETED')
+		RETURNING task_id`,
+		userID,
+	)
+	if err != nil {
+		return Report{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			return Report{}, err
+		}
+		report.UnsharedTaskIDs = append(report.UnsharedTaskIDs, id)
+	}
+	if err = rows.Err(); err != nil {
+		return Report{}, err
+	}
+	return report, tx.Commit()
+}
+
+// plan hands the tasks to the targets in turn, each task going to the next
+// target that is a member of its project, if any. members holds the targets
+// that are members of each project.
+func plan(open []openTask, targetIDs []int, members map[int]map[int]bool) []Move {
+	moves := make([]Move, 0, len(open))
+	next := 0
+	for _, t := range open {
+		move := Move{TaskID: t.id, Title: t.title}
+		for i := range targetIDs {
+			j := (next + i) % len(targetIDs)
+			if t.projectID == 0 || members[t.projectID][targetIDs[j]] {
+				move.ToUserID = targetIDs[j]
+				next = j + 1
+				break
+			}
+		}
+		if move.ToUserID == 0 && len(targetIDs) > 0 {
+			move.Reason = "none of the users is a member of the project"
+		}
+		moves = append(moves, move)
+	}
+	return moves
+}
+
+// planRecurring hands the recurring tasks to the targets in turn. They have no
+// project, so that any target can take them.
+func planRecurring(recurring []openTask, targetIDs []int) []RecurringMove {
+	moves := make([]RecurringMove, 0, len(recurring))
+	for _, m := range plan(recurring, targetIDs, nil) {
+		moves = append(moves, RecurringMove{RecurringTaskID: m.TaskID, Title: m.Title, ToUserID: m.ToUserID})
+	}
+	return moves
+}
+
+func findOpenTasks(ctx context.Context, tx *sql.Tx, userID int) ([]openTask, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, title, COALESCE(project_id, 0) FROM api.tasks
+		WHERE assigned_user_id = $1 AND status <> 'COMPLETED'
+		ORDER BY due_date NULLS LAST, id
+		FOR UPDATE`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var open []openTask
+	for rows.Next() {
+		var t openTask
+		if err := rows.Scan(&t.id, &t.title, &t.projectID); err != nil {
+			return nil, err
+		}
+		open = append(open, t)
+	}
+	return open, rows.Err()
+}
+
+func findRecurringTasks(ctx context.Context, tx *sql.Tx, userID int) ([]openTask, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id, title FROM api.recurring_tasks WHERE assigned_user_id = $1 ORDER BY id FOR UPDATE", userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var recurring []openTask
+	for rows.Next() {
+		var t openTask
+		if err := rows.Scan(&t.id, &t.title); err != nil {
+			return nil, err
+		}
+		recurring = append(recurring, t)
+	}
+	return recurring, rows.Err()
+}
+
+// findMembers returns which of the targets are members of the projects of the
+// tasks, by project.
+func findMembers(ctx context.Context, tx *sql.Tx, open []openTask, targetIDs []int) (map[int]map[int]bool, error) {
+	members := make(map[int]map[int]bool)
+	var projectIDs []int
+	for _, t := range open {
+		if t.projectID > 0 {
+			projectIDs = append(projectIDs, t.projectID)
+		}
+	}
+	if len(projectIDs) == 0 || len(targetIDs) == 0 {
+		return members, nil
+	}
+	rows, err := tx.QueryContext(ctx,
+		"SELECT project_id, user_id FROM api.project_members WHERE project_id = ANY($1) AND user_id = ANY($2)",
+		pq.Array(projectIDs), pq.Array(targetIDs),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var projectID, userID int
+		if err := rows.Scan(&projectID, &userID); err != nil {
+			return nil, err
+		}
+		if members[projectID] == nil {
+			members[projectID] = make(map[int]bool)
+		}
+		members[projectID][userID] = true
+	}
+	return members, rows.Err()
+}
@@ -0,0 +1,82 @@
+package offboarding
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	open := []openTask{
+		{id: 1, title: "a"},
+		{id: 2, title: "b", projectID: 7},
+		{id: 3, title: "c"},
+		{id: 4, title: "d", projectID: 8},
+		{id: 5, title: "e"},
+	}
+	// only user 11 is a member of project 7, and no one of project 8
+	members := map[int]map[int]bool{7: {11: true}}
+
+	cases := []struct {
+		name      string
+		targetIDs []int
+		expected  []Move
+	}{
+		{
+			name: "backlog",
+			expected: []Move{
+				{TaskID: 1, Title: "a"}, {TaskID: 2, Title: "b"}, {TaskID: 3, Title: "c"}, {TaskID: 4, Title: "d"},
+				{TaskID: 5, Title: "e"},
+			},
+		},
+		{
+			name:      "named user",
+			targetIDs: []int{11},
+			expected: []Move{
+				{TaskID: 1, Title: "a", ToUserID: 11},
+				{TaskID: 2, Title: "b", ToUserID: 11},
+				{TaskID: 3, Title: "c", ToUserID: 11},
+				{TaskID: 4, Title: "d", Reason: "none of the users is a member of the project"},
+				{TaskID: 5, Title: "e", ToUserID: 11},
+			},
+		},
+		{
+			name:      "round-robin",
+			targetIDs: []int{10, 11, 12},
+			expected: []Move{
+				{TaskID: 1, Title: "a", ToUserID: 10},
+				// 12 would be next after 11, the only member
+				{TaskID: 2, Title: "b", ToUserID: 11},
+				{TaskID: 3, Title: "c", ToUserID: 12},
+				{TaskID: 4, Title: "d", Reason: "none of the users is a member of the project"},
+				{TaskID: 5, Title: "e", ToUserID: 10},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if moves := plan(open, c.targetIDs, members); !reflect.DeepEqual(moves, c.expected) {
+				t.Errorf("expected %+v, got %+v", c.expected, moves)
+			}
+		})
+	}
+}
+
+func TestPlanRecurring(t *testing.T) {
+	recurring := []openTask{{id: 1, title: "standup"}, {id: 2, title: "report"}, {id: 3, title: "backup"}}
+
+	expected := []RecurringMove{
+		{RecurringTaskID: 1, Title: "standup", ToUserID: 10},
+		{RecurringTaskID: 2, Title: "report", ToUserID: 11},
+		{RecurringTaskID: 3, Title: "backup", ToUserID: 10},
+	}
+	if moves := planRecurring(recurring, []int{10, 11}); !reflect.DeepEqual(moves, expected) {
+		t.Errorf("expected %+v, got %+v", expected, moves)
+	}
+
+	expected = []RecurringMove{
+		{RecurringTaskID: 1, Title: "standup"}, {RecurringTaskID: 2, Title: "report"}, {RecurringTaskID: 3, Title: "backup"},
+	}
+	if moves := planRecurring(recurring, nil); !reflect.DeepEqual(moves, expected) {
+		t.Errorf("expected %+v, got %+v", expected, moves)
+	}
+}
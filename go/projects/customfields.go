@@ -0,0 +1,248 @@
+package projects
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"siransbach/taskmanagementapi/tasks"
+)
+
+type (
+	// CustomField is a typed field defined on a project, whose values are
+	// stored against the tasks of the project.
+	CustomField struct {
+		ID        int             `json:"id"`
+		ProjectID int             `json:"projectId"`
+		Key       string          `json:"key"`
+		Name      string          `json:"name"`
+		Type      tasks.FieldType `json:"type"`
+		// Options lists the allowed values of select fields
+		Options  []string `json:"options,omitempty"`
+		Required bool     `json:"required"`
+	}
+
+	CustomFieldFindOptions struct {
+		IDs        []int
+		ProjectIDs []int
+		Keys       []string
+	}
+)
+
+// ErrCustomFieldExists is returned when a project already has a custom field
+// with the same key.
+var ErrCustomFieldExists = errors.New("custom field already exists")
+
+var customFieldKey = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+func (f CustomField) Validate() error {
+	if !customFieldKey.MatchString(f.Key) {
+		return errors.New("invalid key, expected lowercase letters, digits and underscores")
+	}
+	if f.Name == "" {
+		return errors.New("missing name")
+	}
+	if !slices.Contains(tasks.FieldTypes, f.Type) {
+		return fmt.Errorf("invalid type: %s", f.Type)
+	}
+	isSelect := f.Type == tasks.FieldTypeSingleSelect || f.Type == tasks.FieldTypeMultiSelect
+	if isSelect && len(f.Options) == 0 {
+		return errors.New("missing options")
+	}
+	if !isSelect && len(f.Options) > 0 {
+		return fmt.Errorf("options are not supported for %s fields", f.Type)
+	}
+	for i, option := range f.Options {
+		if option == "" {
+			return errors.New("empty option")
+		}
+		if slices.Contains(f.Options[:i], option) {
+			return fmt.Errorf("duplicate option: %s", option)
+		}
+	}
+	return nil
+}
+
+// NormalizeValue checks that value, as sent by a client, is valid for the
+// field and returns it in the form it is stored in. Dates are stored as
+// YYYY-MM-DD strings and users as their ID. A null value is returned as is
+// and clears the value, which required fields don't allow.
+func (f CustomField) NormalizeValue(value json.RawMessage) (json.RawMessage, error) {
+	if len(bytes.TrimSpace(value)) == 0 || string(bytes.TrimSpace(value)) == "null" {
+		if f.Required {
+			return nil, fmt.Errorf("%s: value is required", f.Key)
+		}
+		return json.RawMessage("null"), nil
+	}
+
+	var normalized interface{}
+	switch f.Type {
+	case tasks.FieldTypeText:
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			return nil, fmt.Errorf("%s: expected a string", f.Key)
+		}
+		normalized = s
+	case tasks.FieldTypeNumber:
+		var n float64
+		if err := json.Unmarshal(value, &n); err != nil {
+			return nil, fmt.Errorf("%s: expected a number", f.Key)
+		}
+		normalized = n
+	case tasks.FieldTypeDate:
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			return nil, fmt.Errorf("%s: expected a date", f.Key)
+		}
+		d, err := time.Parse(time.DateOnly, s)
+		if err != nil {
+			if d, err = time.Parse(time.RFC3339, s); err != nil {
+				return nil, fmt.Errorf("%s: expected a date", f.Key)
+			}
+		}
+		normalized = d.Format(time.DateOnly)
+	case tasks.FieldTypeSingleSelect:
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil || !slices.Contains(f.Options, s) {
+			return nil, fmt.Errorf("%s: expected one of %s", f.Key, strings.Join(f.Options, ", "))
+		}
+		normalized = s
+	case tasks.FieldTypeMultiSelect:
+		var values []string
+		if err := json.Unmarshal(value, &values); err != nil {
+			return nil, fmt.Errorf("%s: expected a list of options", f.Key)
+		}
+		selected := []string{}
+		for _, v := range values {
+			if !slices.Contains(f.Options, v) {
+				return nil, fmt.Errorf("%s: expected one of %s", f.Key, strings.Join(f.Options, ", "))
+			}
+			if !slices.Contains(selected, v) {
+				selected = append(selected, v)
+			}
+		}
+		if f.Required && len(selected) == 0 {
+			return nil, fmt.Errorf("%s: value is required", f.Key)
+		}
+		normalized = selected
+	case tasks.FieldTypeUser:
+		var id int
+		if err := json.Unmarshal(value, &id); err != nil || id <= 0 {
+			return nil, fmt.Errorf("%s: expected a user ID", f.Key)
+		}
+		normalized = id
+	default:
+		return nil, fmt.Errorf("%s: invalid type: %s", f.Key, f.Type)
+	}
+	return json.Marshal(normalized)
+}
+
+const selectCustomFieldColumns = "id,project_id,key,name,type,options,required"
+
+func (db *DB) FindCustomFields(ctx context.Context, options CustomFieldFindOptions) ([]CustomField, error) {
+	query, args := options.buildQuery()
+	rows, err := db.pg.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fields []CustomField
+	for rows.Next() {
+		var f CustomField
+		if err := rows.Scan(
+			&f.ID, &f.ProjectID, &f.Key, &f.Name, &f.Type, pq.Array(&f.Options), &f.Required,
+		); err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	return fields, rows.Err()
+}
+
+func (db *DB) FindCustomField(ctx context.Context, options CustomFieldFindOptions) (CustomField, error) {
+	fields, err := db.FindCustomFields(ctx, options)
+	if err != nil {
+		return CustomField{}, err
+	}
+	if len(fields) == 0 {
+		return CustomField{}, sql.ErrNoRows
+	}
+	return fields[0], nil
+}
+
+func (db *DB) InsertCustomField(ctx context.Context, f CustomField) (id int, err error) {
+	if err := f.Validate(); err != nil {
+		return 0, fmt.Errorf("invalid custom field: %w", err)
+	}
+	err = db.pg.QueryRowContext(ctx,
+		"INSERT INTO api.custom_fields (project_id,key,name,type,options,required) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+		f.ProjectID, f.Key, f.Name, f.Type, pq.Array(options(f)), f.Required,
+	).Scan(&id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return 0, ErrCustomFieldExists
+	}
+	return id, err
+}
+
+// UpdateCustomField renames a field or changes its options. Neither the key
+// nor the type can change once values have been stored.
+func (db *DB) UpdateCustomField(ctx context.Context, f CustomField) error {
+	if err := f.Validate(); err != nil {
+		return fmt.Errorf("invalid custom field: %w", err)
+	}
+	var updatedID int
+	return db.pg.QueryRowContext(ctx,
+		"UPDATE api.custom_fields SET name = $1, options = $2, required = $3 WHERE id = $4 AND project_id = $5 RETURNING id",
+		f.Name, pq.Array(options(f)), f.Required, f.ID, f.ProjectID,
+	).Scan(&updatedID)
+}
+
+// DeleteCustomField deletes a field along with its values.
+func (db *DB) DeleteCustomField(ctx context.Context, projectID, id int) error {
+	var deletedID int
+	return db.pg.QueryRowContext(ctx,
+		"DELETE FROM api.custom_fields WHERE id = $1 AND project_id = $2 RETURNING id", id, projectID,
+	).Scan(&deletedID)
+}
+
+func options(f CustomField) []string {
+	if f.Options == nil {
+		return []string{}
+	}
+	return f.Options
+}
+
+func (opt CustomFieldFindOptions) buildQuery() (query string, args []interface{}) {
+	var clauses []string
+	args = make([]interface{}, 0)
+
+	if len(opt.IDs) > 0 {
+		args = append(args, pq.Array(opt.IDs))
+		clauses = append(clauses, fmt.Sprintf("id = ANY($%d)", len(args)))
+	}
+	if len(opt.ProjectIDs) > 0 {
+		args = append(args, pq.Array(opt.ProjectIDs))
+		clauses = append(clauses, fmt.Sprintf("project_id = ANY($%d)", len(args)))
+	}
+	if len(opt.Keys) > 0 {
+		args = append(args, pq.Array(opt.Keys))
+		clauses = append(clauses, fmt.Sprintf("key = ANY($%d)", len(args)))
+	}
+
+	stmt := fmt.Sprintf("SELECT %s FROM api.custom_fields", selectCustomFieldColumns)
+	if len(clauses) > 0 {
+		stmt = fmt.Sprintf("%s WHERE %s", stmt, strings.Join(clauses, " AND "))
+	}
+	return stmt + " ORDER BY project_id ASC, id ASC", args
+}
@@ -0,0 +1,324 @@
+package projects
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"siransbach/taskmanagementapi/tasks"
+)
+
+type (
+	DB struct {
+		pg *sql.DB
+	}
+
+	FindOptions struct {
+		IDs             []int
+		MemberIDs       []int
+		IncludeArchived bool
+	}
+)
+
+// ErrArchived is returned when changing the tasks of an archived project.
+var ErrArchived = errors.New("project is archived")
+
+const selectColumns = "projects.id,projects.name,projects.description,projects.owner_id,projects.archived,projects.created_at"
+
+func NewDB(pg *sql.DB) *DB {
+	return &DB{pg}
+}
+
+func (db *DB) Find(ctx context.Context, options FindOptions) ([]Project, error) {
+	query, args := options.buildQuery()
+	rows, err := db.pg.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []Project
+	for rows.Next() {
+		var p Project
+		if err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.OwnerID, &p.Archived, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		projects = append(projects, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return projects, db.attachMembers(ctx, projects)
+}
+
+func (db *DB) FindOne(ctx context.Context, options FindOptions) (Project, error) {
+	options.IncludeArchived = true
+	projects, err := db.Find(ctx, options)
+	if err != nil {
+		return Project{}, err
+	}
+	if len(projects) == 0 {
+		return Project{}, sql.ErrNoRows
+	}
+	return projects[0], nil
+}
+
+// Insert creates the project with its members, all or nothing.
+func (db *DB) Insert(ctx context.Context, p Project, memberIDs []int) (id int, err error) {
+	if err := p.Validate(); err != nil {
+		return 0, fmt.Errorf("invalid project: %w", err)
+	}
+	tx, err := db.pg.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if err = tx.QueryRowContext(ctx,
+		"INSERT INTO api.projects (name,description,owner_id) VALUES ($1, $2, $3) RETURNING id",
+		p.Name, p.Description, p.OwnerID,
+	).Scan(&id); err != nil {
+		return 0, err
+	}
+	if len(memberIDs) > 0 {
+		if _, err = tx.ExecContext(ctx,
+			"INSERT INTO api.project_members (project_id,user_id) SELECT $1, unnest($2::INT[]) ON CONFLICT DO NOTHING",
+			id, pq.Array(memberIDs),
+		); err != nil {
+			return 0, err
+		}
+	}
+	return id, tx.Commit()
+}
+
+func (db *DB) Update(ctx context.Context, p Project) error {
+	if err := p.Validate(); err != nil {
+		return fmt.Errorf("invalid project: %w", err)
+	}
+	var updatedID int
+	return db.pg.QueryRowContext(ctx,
+		"UPDATE api.projects SET name = $1, description = $2, owner_id = $3, archived = $4 WHERE id = $5 RETURNING id",
+		p.Name, p.Description, p.OwnerID, p.Archived, p.ID,
+	).Scan(&updatedID)
+}
+
+func (db *DB) AddMember(ctx context.Context, projectID, userID int) error {
+	_, err := db.pg.ExecContext(ctx,
+		"INSERT INTO api.project_members (project_id,user_id) VALUES ($1, $2) ON CONFLICT (project_id, user_id) DO NOTHING",
+		projectID, userID,
+	)
+	return err
+}
+
+// RemoveMember removes the user from the project along with its open tasks:
+// those the user is the primary assignee of go to the backlog, and the user
+// stops sharing the others. It returns the IDs of the tasks moved to the
+// backlog.
+func (db *DB) RemoveMember(ctx context.Context, projectID, userID int) (backlogTaskIDs []int, err error) {
+	tx, err := db.pg.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var removedID int
+	if err = tx.QueryRowContext(ctx,
+		"DELETE FROM api.project_members WHERE project_id = $1 AND user_id = $2 RETURNING user_id",
+		projectID, userID,
+	).Scan(&removedID); err != nil {
+		return nil, err
+	}
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE api.tasks SET assigned_user_id = NULL
+		WHERE project_id = $1 AND assigned_user_id = $2 AND status <> 'COMPLETED'
+		RETURNING id`,
+		projectID, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	backlogTaskIDs = []int{}
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		backlogTaskIDs = append(backlogTaskIDs, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if _, err = tx.ExecContext(ctx, `
+		DELETE FROM api.task_assignees
+		WHERE user_id = $2 AND NOT is_primary
+			AND task_id IN (SELECT id FROM api.tasks WHERE project_id = $1 AND status <> 'COMPLETED')`,
+		projectID, userID,
+	); err != nil {
+		return nil, err
+	}
+	return backlogTaskIDs, tx.Commit()
+}
+
+// MoveTask moves a task of the project to the given position of the board
+// column of status, changing its status if needed. The column is locked while
+// the new rank is computed so that concurrent moves don't interleave.
+func (db *DB) MoveTask(ctx context.Context, projectID, taskID int, status tasks.Status, position int) (err error) {
+	tx, err := db.pg.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var archived bool
+	if err = tx.QueryRowContext(ctx,
+		"SELECT archived FROM api.projects WHERE id = $1 FOR SHARE", projectID,
+	).Scan(&archived); err != nil {
+		return err
+	}
+	if archived {
+		return ErrArchived
+	}
+	var found int
+	if err = tx.QueryRowContext(ctx,
+		"SELECT id FROM api.tasks WHERE id = $1 AND project_id = $2 FOR UPDATE", taskID, projectID,
+	).Scan(&found); err != nil {
+		return err
+	}
+
+	ids, ranks, err := lockColumn(ctx, tx, projectID, taskID, status)
+	if err != nil {
+		return err
+	}
+	rank, renumber := rankAt(ranks, position)
+	if renumber {
+		for i, id := range ids {
+			ranks[i] = float64(i+1) * rankStep
+			if _, err = tx.ExecContext(ctx,
+				"UPDATE api.tasks SET board_rank = $1 WHERE id = $2", ranks[i], id,
+			); err != nil {
+				return err
+			}
+		}
+		rank, _ = rankAt(ranks, position)
+	}
+
+	if _, err = tx.ExecContext(ctx,
+		"UPDATE api.tasks SET status = $1, board_rank = $2 WHERE id = $3", status, rank, taskID,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockColumn returns the tasks of a board column in order, leaving out the
+// task being moved.
+func lockColumn(ctx context.Context, tx *sql.Tx, projectID, taskID int, status tasks.Status) (ids []int, ranks []float64, err error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, board_rank FROM api.tasks
+		WHERE project_id = $1 AND status = $2 AND id <> $3
+		ORDER BY board_rank, id
+		FOR UPDATE`,
+		projectID, status, taskID,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			id   int
+			rank float64
+		)
+		if err := rows.Scan(&id, &rank); err != nil {
+			return nil, nil, err
+		}
+		ids = append(ids, id)
+		ranks = append(ranks, rank)
+	}
+	return ids, ranks, rows.Err()
+}
+
+func (db *DB) attachMembers(ctx context.Context, projects []Project) error {
+	if len(projects) == 0 {
+		return nil
+	}
+	ids := make([]int, 0, len(projects))
+	for _, p := range projects {
+		ids = append(ids, p.ID)
+	}
+	rows, err := db.pg.QueryContext(ctx, `
+		SELECT members.project_id, users.id, users.username
+		FROM api.project_members members
+		JOIN auth.users ON users.id = members.user_id
+		WHERE members.project_id = ANY($1)
+		ORDER BY members.project_id, users.id`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	members := make(map[int][]Member)
+	for rows.Next() {
+		var (
+			projectID int
+			m         Member
+		)
+		if err := rows.Scan(&projectID, &m.UserID, &m.Username); err != nil {
+			return err
+		}
+		members[projectID] = append(members[projectID], m)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i := range projects {
+		projects[i].Members = members[projects[i].ID]
+		if projects[i].Members == nil {
+			projects[i].Members = []Member{}
+		}
+	}
+	return nil
+}
+
+func (opt FindOptions) buildQuery() (query string, args []interface{}) {
+	var clauses []string
+	args = make([]interface{}, 0)
+
+	if len(opt.IDs) > 0 {
+		args = append(args, pq.Array(opt.IDs))
+		clauses = append(clauses, fmt.Sprintf("projects.id = ANY($%d)", len(args)))
+	}
+	if len(opt.MemberIDs) > 0 {
+		args = append(args, pq.Array(opt.MemberIDs))
+		clauses = append(clauses, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM api.project_members WHERE project_id = projects.id AND user_id = ANY($%d))", len(args)))
+	}
+	if !opt.IncludeArchived {
+		clauses = append(clauses, "NOT projects.archived")
+	}
+
+	stmt := fmt.Sprintf("SELECT %s FROM api.projects", selectColumns)
+	if len(clauses) > 0 {
+		stmt = fmt.Sprintf("%s WHERE %s", stmt, strings.Join(clauses, " AND "))
+	}
+	return stmt + " ORDER BY projects.id ASC", args
+}
@@ -0,0 +1,76 @@
+package projects
+
+import (
+	"errors"
+	"time"
+)
+
+type (
+	// Project groups tasks. Only its members can be assigned its tasks.
+	Project struct {
+		ID          int       `json:"id"`
+		Name        string    `json:"name"`
+		Description string    `json:"description"`
+		OwnerID     int       `json:"ownerId"`
+		Archived    bool      `json:"archived"`
+		CreatedAt   time.Time `json:"createdAt"`
+		Members     []Member  `json:"members"`
+	}
+
+	Member struct {
+		UserID   int    `json:"userId"`
+		Username string `json:"username"`
+	}
+)
+
+// rankStep is the gap left between two consecutive tasks of a board column
+// when the column is renumbered.
+const rankStep = 1024
+
+// minRankGap is the smallest gap between two ranks before the column has to be
+// renumbered to keep room for later moves.
+const minRankGap = 1e-6
+
+func (p Project) Validate() error {
+	if p.Name == "" {
+		return errors.New("missing name")
+	}
+	if p.OwnerID <= 0 {
+		return errors.New("invalid owner")
+	}
+	return nil
+}
+
+func (p Project) HasMember(userID int) bool {
+	for _, m := range p.Members {
+		if m.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// rankAt returns the rank of a task inserted at position in a column whose
+// other tasks have the given ranks, in order. renumber is true when there is no
+// room left between the neighbours and the column must be renumbered first.
+func rankAt(ranks []float64, position int) (rank float64, renumber bool) {
+	if position < 0 {
+		position = 0
+	}
+	if position > len(ranks) {
+		position = len(ranks)
+	}
+	switch {
+	case len(ranks) == 0:
+		return rankStep, false
+	case position == 0:
+		return ranks[0] - rankStep, false
+	case position == len(ranks):
+		return ranks[len(ranks)-1] + rankStep, false
+	}
+	before, after := ranks[position-1], ranks[position]
+	if after-before < minRankGap {
+		return 0, true
+	}
+	return before + (after-before)/2, false
+}
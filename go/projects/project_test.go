@@ -0,0 +1,106 @@
+package projects
+
+import (
+	"encoding/json"
+	"testing"
+
+	"siransbach/taskmanagementapi/tasks"
+)
+
+func TestRankAt(t *testing.T) {
+	cases := []struct {
+		name     string
+		ranks    []float64
+		position int
+		rank     float64
+		renumber bool
+	}{
+		{name: "empty column", ranks: nil, position: 0, rank: rankStep},
+		{name: "top", ranks: []float64{1024, 2048}, position: 0, rank: 0},
+		{name: "bottom", ranks: []float64{1024, 2048}, position: 2, rank: 3072},
+		{name: "past the bottom", ranks: []float64{1024, 2048}, position: 10, rank: 3072},
+		{name: "between", ranks: []float64{1024, 2048}, position: 1, rank: 1536},
+		{name: "no room left", ranks: []float64{1, 1 + 1e-9}, position: 1, renumber: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rank, renumber := rankAt(tc.ranks, tc.position)
+			if renumber != tc.renumber {
+				t.Fatalf("expected renumber %v, got %v", tc.renumber, renumber)
+			}
+			if !renumber && rank != tc.rank {
+				t.Errorf("expected rank %v, got %v", tc.rank, rank)
+			}
+		})
+	}
+}
+
+func TestProject_Validate(t *testing.T) {
+	if err := (Project{Name: "Website", OwnerID: 3}).Validate(); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if err := (Project{OwnerID: 3}).Validate(); err == nil {
+		t.Error("Expected error for missing name, got nil")
+	}
+	if err := (Project{Name: "Website"}).Validate(); err == nil {
+		t.Error("Expected error for missing owner, got nil")
+	}
+}
+
+func TestCustomField_NormalizeValue(t *testing.T) {
+	cases := []struct {
+		name     string
+		field    CustomField
+		value    string
+		expected string
+		err      bool
+	}{
+		{name: "text", field: CustomField{Type: tasks.FieldTypeText}, value: `"acme"`, expected: `"acme"`},
+		{name: "text as number", field: CustomField{Type: tasks.FieldTypeText}, value: `5`, err: true},
+		{name: "number", field: CustomField{Type: tasks.FieldTypeNumber}, value: `5.5`, expected: `5.5`},
+		{name: "date", field: CustomField{Type: tasks.FieldTypeDate}, value: `"2024-03-01"`, expected: `"2024-03-01"`},
+		{name: "timestamp as date", field: CustomField{Type: tasks.FieldTypeDate}, value: `"2024-03-01T10:00:00Z"`, expected: `"2024-03-01"`},
+		{name: "invalid date", field: CustomField{Type: tasks.FieldTypeDate}, value: `"March"`, err: true},
+		{name: "single select", field: CustomField{Type: tasks.FieldTypeSingleSelect, Options: []string{"a", "b"}}, value: `"b"`, expected: `"b"`},
+		{name: "unknown option", field: CustomField{Type: tasks.FieldTypeSingleSelect, Options: []string{"a"}}, value: `"c"`, err: true},
+		{name: "multi select", field: CustomField{Type: tasks.FieldTypeMultiSelect, Options: []string{"a", "b"}}, value: `["b","a","b"]`, expected: `["b","a"]`},
+		{name: "user", field: CustomField{Type: tasks.FieldTypeUser}, value: `3`, expected: `3`},
+		{name: "invalid user", field: CustomField{Type: tasks.FieldTypeUser}, value: `-1`, err: true},
+		{name: "clear", field: CustomField{Type: tasks.FieldTypeNumber}, value: `null`, expected: `null`},
+		{name: "clear required", field: CustomField{Type: tasks.FieldTypeNumber, Required: true}, value: `null`, err: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			value, err := tc.field.NormalizeValue(json.RawMessage(tc.value))
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected error, got %s", value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(value) != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, value)
+			}
+		})
+	}
+}
+
+func TestCustomField_Validate(t *testing.T) {
+	if err := (CustomField{Key: "story_points", Name: "Story points", Type: tasks.FieldTypeNumber}).Validate(); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if err := (CustomField{Key: "Story Points", Name: "Story points", Type: tasks.FieldTypeNumber}).Validate(); err == nil {
+		t.Error("Expected error for invalid key, got nil")
+	}
+	if err := (CustomField{Key: "size", Name: "Size", Type: tasks.FieldTypeSingleSelect}).Validate(); err == nil {
+		t.Error("Expected error for missing options, got nil")
+	}
+	if err := (CustomField{Key: "size", Name: "Size", Type: tasks.FieldTypeSingleSelect, Options: []string{"S", "S"}}).Validate(); err == nil {
+		t.Error("Expected error for duplicate options, got nil")
+	}
+}
@@ -0,0 +1,191 @@
+package recurring
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type (
+	DB struct {
+		pg *sql.DB
+	}
+
+	FindOptions struct {
+		IDs []int
+	}
+)
+
+const selectColumns = "id,title,description,assigned_user_id,rrule,dtstart,timezone,created_at,materialized_until"
+
+func NewDB(pg *sql.DB) *DB {
+	return &DB{pg}
+}
+
+func (db *DB) Find(ctx context.Context, options FindOptions) ([]Definition, error) {
+	query, args := options.buildQuery()
+	rows, err := db.pg.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var defs []Definition
+	for rows.Next() {
+		var (
+			def               Definition
+			materializedUntil sql.NullTime
+		)
+		if err := rows.Scan(
+			&def.ID, &def.Title, &def.Description, &def.AssignedUserID,
+			&def.RRule, &def.DTStart, &def.Timezone, &def.CreatedAt, &materializedUntil,
+		); err != nil {
+			return nil, err
+		}
+		if materializedUntil.Valid {
+			def.MaterializedUntil = &materializedUntil.Time
+		}
+		defs = append(defs, def)
+	}
+	return defs, rows.Err()
+}
+
+func (db *DB) FindOne(ctx context.Context, options FindOptions) (Definition, error) {
+	defs, err := db.Find(ctx, options)
+	if err != nil {
+		return Definition{}, err
+	}
+	if len(defs) == 0 {
+		return Definition{}, sql.ErrNoRows
+	}
+	return defs[0], nil
+}
+
+func (db *DB) Insert(ctx context.Context, def Definition) (id int, err error) {
+	if err := def.Validate(); err != nil {
+		return 0, fmt.Errorf("invalid definition: %w", err)
+	}
+	err = db.pg.QueryRowContext(ctx,
+		"INSERT INTO api.recurring_tasks (title,description,assigned_user_id,rrule,dtstart,timezone)"+
+			" VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+		def.Title, def.Description, def.AssignedUserID, def.RRule, def.DTStart, timezoneOrUTC(def.Timezone),
+	).Scan(&id)
+	return id, err
+}
+
+// SetMaterializedUntil records that the occurrences of the definition before
+// until were materialized. It never moves backwards.
+func (db *DB) SetMaterializedUntil(ctx context.Context, id int, until time.Time) error {
+	_, err := db.pg.ExecContext(ctx,
+		"UPDATE api.recurring_tasks SET materialized_until = GREATEST(materialized_until, $1) WHERE id = $2",
+		until, id,
+	)
+	return err
+}
+
+// Split applies tail to the occurrence at and every later occurrence of def,
+// leaving earlier occurrences untouched. Upcoming pending tasks are removed so
+// that they get re-created from tail; those already due and those already
+// started are moved to the new series and edited in place instead, as they
+// are not materialized again. Completed tasks are moved as they are. It
+// returns the ID of the definition now covering the occurrences from at.
+func (db *DB) Split(ctx context.Context, def Definition, at time.Time, tail Definition) (id int, err error) {
+	if err := tail.Validate(); err != nil {
+		return 0, fmt.Errorf("invalid definition: %w", err)
+	}
+	head, ok, err := def.Head(at)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.pg.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if ok {
+		if _, err = tx.ExecContext(ctx,
+			"UPDATE api.recurring_tasks SET rrule = $1 WHERE id = $2", head.RRule, def.ID,
+		); err != nil {
+			return 0, err
+		}
+		if err = tx.QueryRowContext(ctx,
+			"INSERT INTO api.recurring_tasks (title,description,assigned_user_id,rrule,dtstart,timezone)"+
+				" VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+			tail.Title, tail.Description, tail.AssignedUserID, tail.RRule, tail.DTStart, timezoneOrUTC(tail.Timezone),
+		).Scan(&id); err != nil {
+			return 0, err
+		}
+	} else {
+		// nothing happens before at, so the whole series is replaced and
+		// materialized again from scratch
+		id = def.ID
+		if _, err = tx.ExecContext(ctx,
+			"UPDATE api.recurring_tasks SET title = $1, description = $2, assigned_user_id = $3,"+
+				" rrule = $4, dtstart = $5, timezone = $6, materialized_until = NULL WHERE id = $7",
+			tail.Title, tail.Description, tail.AssignedUserID, tail.RRule, tail.DTStart, timezoneOrUTC(tail.Timezone), id,
+		); err != nil {
+			return 0, err
+		}
+	}
+
+	// the new series is materialized from now on, like any other
+	upcoming := time.Now()
+	if at.After(upcoming) {
+		upcoming = at
+	}
+	if _, err = tx.ExecContext(ctx,
+		"DELETE FROM api.tasks WHERE recurring_task_id = $1 AND occurrence_at >= $2 AND status = 'PENDING'",
+		def.ID, upcoming,
+	); err != nil {
+		return 0, err
+	}
+	if _, err = tx.ExecContext(ctx,
+		"UPDATE api.tasks SET recurring_task_id = $1, title = $2, description = $3, assigned_user_id = $4"+
+			" WHERE recurring_task_id = $5 AND occurrence_at >= $6 AND status <> 'COMPLETED'",
+		id, tail.Title, tail.Description, tail.AssignedUserID, def.ID, at,
+	); err != nil {
+		return 0, err
+	}
+	// completed occurrences keep what they were done as, but belong to the
+	// new series so that they are not materialized again
+	if _, err = tx.ExecContext(ctx,
+		"UPDATE api.tasks SET recurring_task_id = $1 WHERE recurring_task_id = $2 AND occurrence_at >= $3",
+		id, def.ID, at,
+	); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func (opt FindOptions) buildQuery() (query string, args []interface{}) {
+	var clauses []string
+	args = make([]interface{}, 0)
+
+	if len(opt.IDs) > 0 {
+		args = append(args, pq.Array(opt.IDs))
+		clauses = append(clauses, fmt.Sprintf("id = ANY($%d)", len(args)))
+	}
+
+	stmt := fmt.Sprintf("SELECT %s FROM api.recurring_tasks", selectColumns)
+	if len(clauses) > 0 {
+		stmt = fmt.Sprintf("%s WHERE %s", stmt, strings.Join(clauses, " AND "))
+	}
+	return stmt + " ORDER BY id ASC", args
+}
+
+func timezoneOrUTC(tz string) string {
+	if tz == "" {
+		return "UTC"
+	}
+	return tz
+}
@@ -0,0 +1,139 @@
+package recurring
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"siransbach/taskmanagementapi/tasks"
+)
+
+// Definition describes a task that repeats according to an RRULE. Individual
+// occurrences are materialized into api.tasks ahead of time by the Scheduler.
+type Definition struct {
+	ID             int       `json:"id"`
+	Title          string    `json:"title"`
+	Description    string    `json:"description"`
+	AssignedUserID int       `json:"assignedUserId"`
+	RRule          string    `json:"rrule"`
+	DTStart        time.Time `json:"dtstart"`
+	Timezone       string    `json:"timezone"`
+	CreatedAt      time.Time `json:"createdAt"`
+	// MaterializedUntil is the end of the occurrences materialized so far,
+	// nil when none were
+	MaterializedUntil *time.Time `json:"materializedUntil"`
+}
+
+func (d Definition) Location() (*time.Location, error) {
+	if d.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(d.Timezone)
+}
+
+func (d Definition) Rule() (Rule, error) {
+	loc, err := d.Location()
+	if err != nil {
+		return Rule{}, err
+	}
+	return ParseRule(d.RRule, loc)
+}
+
+func (d Definition) Validate() error {
+	if d.Title == "" {
+		return errors.New("missing title")
+	}
+	if d.AssignedUserID <= 0 {
+		return errors.New("invalid assigned user")
+	}
+	if d.DTStart.IsZero() {
+		return errors.New("missing dtstart")
+	}
+	loc, err := d.Location()
+	if err != nil {
+		return fmt.Errorf("invalid timezone: %w", err)
+	}
+	rule, err := d.Rule()
+	if err != nil {
+		return fmt.Errorf("invalid rrule: %w", err)
+	}
+	if !rule.HasOccurrence(d.DTStart.In(loc)) {
+		return errors.New("invalid rrule: no occurrence")
+	}
+	return nil
+}
+
+// Occurrences returns the occurrences of the definition within [from, to).
+func (d Definition) Occurrences(from, to time.Time) ([]time.Time, error) {
+	loc, err := d.Location()
+	if err != nil {
+		return nil, err
+	}
+	rule, err := ParseRule(d.RRule, loc)
+	if err != nil {
+		return nil, err
+	}
+	return rule.Between(d.DTStart.In(loc), from, to), nil
+}
+
+// Task returns the task materialized for the occurrence at.
+func (d Definition) Task(at time.Time) tasks.Entry {
+	return tasks.Entry{
+		Title:           d.Title,
+		Description:     d.Description,
+		AssignedUserID:  d.AssignedUserID,
+		DueDate:         at,
+		RecurringTaskID: d.ID,
+		OccurrenceAt:    &at,
+	}
+}
+
+// Head returns the definition truncated so that its last occurrence is the
+// one before at. ok is false when the definition has no occurrence before at.
+func (d Definition) Head(at time.Time) (head Definition, ok bool, err error) {
+	loc, err := d.Location()
+	if err != nil {
+		return Definition{}, false, err
+	}
+	rule, err := ParseRule(d.RRule, loc)
+	if err != nil {
+		return Definition{}, false, err
+	}
+	before := rule.CountBefore(d.DTStart.In(loc), at)
+	if before == 0 {
+		return Definition{}, false, nil
+	}
+	if rule.Count > 0 {
+		rule.Count = before
+	} else {
+		rule.Until = at.Add(-time.Second)
+	}
+	head = d
+	head.RRule = rule.String()
+	return head, true, nil
+}
+
+// Tail returns the definition continuing the series from the occurrence at,
+// keeping the remaining number of occurrences when the rule has a COUNT.
+func (d Definition) Tail(at time.Time) (Definition, error) {
+	loc, err := d.Location()
+	if err != nil {
+		return Definition{}, err
+	}
+	rule, err := ParseRule(d.RRule, loc)
+	if err != nil {
+		return Definition{}, err
+	}
+	if rule.Count > 0 {
+		rule.Count -= rule.CountBefore(d.DTStart.In(loc), at)
+		if rule.Count <= 0 {
+			return Definition{}, errors.New("no occurrence left")
+		}
+	}
+	tail := d
+	tail.ID = 0
+	tail.DTStart = at
+	tail.MaterializedUntil = nil
+	tail.RRule = rule.String()
+	return tail, nil
+}
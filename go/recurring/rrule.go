@@ -0,0 +1,445 @@
+package recurring
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// Rule is the subset of an iCalendar (RFC 5545) RRULE supported by the
+	// scheduler: FREQ, INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY and BYMONTH.
+	Rule struct {
+		Freq       Frequency
+		Interval   int
+		Count      int
+		Until      time.Time
+		ByDay      []WeekdayNum
+		ByMonthDay []int
+		ByMonth    []time.Month
+	}
+
+	Frequency string
+
+	// WeekdayNum is a BYDAY entry such as MO, 2TU or -1FR. N is zero when the
+	// entry has no ordinal.
+	WeekdayNum struct {
+		N   int
+		Day time.Weekday
+	}
+)
+
+const (
+	FreqDaily   Frequency = "DAILY"
+	FreqWeekly  Frequency = "WEEKLY"
+	FreqMonthly Frequency = "MONTHLY"
+	FreqYearly  Frequency = "YEARLY"
+)
+
+// maxPeriods bounds rule expansion so a rule that never matches (e.g.
+// BYMONTHDAY=31;BYMONTH=2) cannot loop forever.
+const maxPeriods = 100000
+
+// cyclePeriods is the number of periods of each frequency in 400 years, after
+// which the Gregorian calendar repeats, weekdays included: a rule that matches
+// nothing for that many periods in a row never matches again.
+var cyclePeriods = map[Frequency]int{
+	FreqDaily:   146097,
+	FreqWeekly:  20871,
+	FreqMonthly: 4800,
+	FreqYearly:  400,
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// ParseRule parses an RRULE value, with or without the "RRULE:" prefix. A
+// floating UNTIL (no trailing Z) is interpreted in loc.
+func ParseRule(str string, loc *time.Location) (Rule, error) {
+	str = strings.TrimPrefix(strings.TrimSpace(str), "RRULE:")
+	if str == "" {
+		return Rule{}, errors.New("empty rule")
+	}
+	rule := Rule{Interval: 1}
+	for _, part := range strings.Split(str, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return Rule{}, fmt.Errorf("invalid rule part: %s", part)
+		}
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq, err = parseFrequency(value)
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(value)
+			if err == nil && rule.Interval <= 0 {
+				err = errors.New("interval must be positive")
+			}
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(value)
+			if err == nil && rule.Count <= 0 {
+				err = errors.New("count must be positive")
+			}
+		case "UNTIL":
+			rule.Until, err = parseUntil(value, loc)
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseIntList(value, -31, 31)
+		case "BYMONTH":
+			var months []int
+			months, err = parseIntList(value, 1, 12)
+			for _, m := range months {
+				rule.ByMonth = append(rule.ByMonth, time.Month(m))
+			}
+		case "WKST":
+			if strings.ToUpper(value) != "MO" {
+				err = errors.New("only WKST=MO is supported")
+			}
+		default:
+			err = errors.New("unsupported rule part")
+		}
+		if err != nil {
+			return Rule{}, fmt.Errorf("invalid %s: %w", strings.ToUpper(key), err)
+		}
+	}
+	if rule.Freq == "" {
+		return Rule{}, errors.New("missing FREQ")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return Rule{}, errors.New("COUNT and UNTIL are mutually exclusive")
+	}
+	return rule, nil
+}
+
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByDay) > 0 {
+		var days []string
+		for _, d := range r.ByDay {
+			days = append(days, d.String())
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		var days []string
+		for _, d := range r.ByMonthDay {
+			days = append(days, strconv.Itoa(d))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonth) > 0 {
+		var months []string
+		for _, m := range r.ByMonth {
+			months = append(months, strconv.Itoa(int(m)))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+func (w WeekdayNum) String() string {
+	for k, v := range weekdays {
+		if v == w.Day {
+			if w.N != 0 {
+				return strconv.Itoa(w.N) + k
+			}
+			return k
+		}
+	}
+	return ""
+}
+
+// Between returns the occurrences of the rule starting at dtstart that fall
+// within [from, to). Occurrences are computed in dtstart's location so that
+// wall-clock times survive daylight saving transitions.
+func (r Rule) Between(dtstart, from, to time.Time) []time.Time {
+	var ret []time.Time
+	r.each(dtstart, func(t time.Time) bool {
+		if !t.Before(to) {
+			return false
+		}
+		if !t.Before(from) {
+			ret = append(ret, t)
+		}
+		return true
+	})
+	return ret
+}
+
+// CountBefore returns how many occurrences of the rule happen before t.
+func (r Rule) CountBefore(dtstart, t time.Time) int {
+	n := 0
+	r.each(dtstart, func(o time.Time) bool {
+		if !o.Before(t) {
+			return false
+		}
+		n++
+		return true
+	})
+	return n
+}
+
+// HasOccurrence reports whether the rule starting at dtstart occurs at all.
+func (r Rule) HasOccurrence(dtstart time.Time) bool {
+	found := false
+	r.each(dtstart, func(time.Time) bool {
+		found = true
+		return false
+	})
+	return found
+}
+
+func (r Rule) each(dtstart time.Time, yield func(time.Time) bool) {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = 1
+	}
+	emitted, empty := 0, 0
+	for p := 0; p < maxPeriods && empty < cyclePeriods[r.Freq]; p++ {
+		candidates := r.expand(dtstart, p*interval)
+		if len(candidates) == 0 {
+			empty++
+		} else {
+			empty = 0
+		}
+		for _, t := range candidates {
+			if t.Before(dtstart) {
+				continue
+			}
+			if !r.Until.IsZero() && t.After(r.Until) {
+				return
+			}
+			if !yield(t) {
+				return
+			}
+			emitted++
+			if r.Count > 0 && emitted >= r.Count {
+				return
+			}
+		}
+	}
+}
+
+// expand returns the sorted candidate occurrences of the period that is
+// offset periods after the one containing dtstart.
+func (r Rule) expand(dtstart time.Time, offset int) []time.Time {
+	loc := dtstart.Location()
+	y, m, d := dtstart.Date()
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, dtstart.Hour(), dtstart.Minute(), dtstart.Second(), 0, loc)
+	}
+
+	var days []time.Time
+	switch r.Freq {
+	case FreqDaily:
+		day := at(y, m, d+offset)
+		if r.matchesMonth(day) && r.matchesMonthDay(day) && r.matchesWeekday(day) {
+			days = append(days, day)
+		}
+	case FreqWeekly:
+		// weeks start on Monday (WKST=MO)
+		monday := d - (int(dtstart.Weekday())+6)%7 + 7*offset
+		for i := 0; i < 7; i++ {
+			day := at(y, m, monday+i)
+			if len(r.ByDay) == 0 && day.Weekday() != dtstart.Weekday() {
+				continue
+			}
+			if r.matchesMonth(day) && r.matchesWeekday(day) {
+				days = append(days, day)
+			}
+		}
+	case FreqMonthly:
+		first := at(y, m+time.Month(offset), 1)
+		if r.matchesMonth(first) {
+			days = r.expandMonth(first, d, at)
+		}
+	case FreqYearly:
+		year := y + offset
+		months := r.ByMonth
+		if len(months) == 0 && len(r.ByDay) > 0 && len(r.ByMonthDay) == 0 {
+			days = r.expandYearByDay(year, at)
+			break
+		}
+		if len(months) == 0 {
+			months = []time.Month{m}
+		}
+		for _, month := range months {
+			days = append(days, r.expandMonth(at(year, month, 1), d, at)...)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days
+}
+
+func (r Rule) expandMonth(first time.Time, defaultDay int, at func(int, time.Month, int) time.Time) []time.Time {
+	year, month := first.Year(), first.Month()
+	last := daysIn(year, month)
+
+	var days []time.Time
+	if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+		if defaultDay <= last {
+			days = append(days, at(year, month, defaultDay))
+		}
+		return days
+	}
+
+	for day := 1; day <= last; day++ {
+		t := at(year, month, day)
+		if len(r.ByMonthDay) > 0 && !r.matchesMonthDay(t) {
+			continue
+		}
+		if len(r.ByDay) > 0 && !matchesNthWeekday(r.ByDay, t, (day-1)/7+1, -((last-day)/7+1)) {
+			continue
+		}
+		days = append(days, t)
+	}
+	return days
+}
+
+func (r Rule) expandYearByDay(year int, at func(int, time.Month, int) time.Time) []time.Time {
+	var days []time.Time
+	total := time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+	for yday := 1; yday <= total; yday++ {
+		t := at(year, time.January, yday)
+		if matchesNthWeekday(r.ByDay, t, (yday-1)/7+1, -((total-yday)/7 + 1)) {
+			days = append(days, t)
+		}
+	}
+	return days
+}
+
+func (r Rule) matchesMonth(t time.Time) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, m := range r.ByMonth {
+		if t.Month() == m {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) matchesMonthDay(t time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	last := daysIn(t.Year(), t.Month())
+	for _, d := range r.ByMonthDay {
+		if d == t.Day() || (d < 0 && last+d+1 == t.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) matchesWeekday(t time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Day == t.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesNthWeekday reports whether t matches one of the BYDAY entries given
+// its ordinal position (nth) and reverse ordinal position (nthLast) within the
+// enclosing month or year.
+func matchesNthWeekday(byDay []WeekdayNum, t time.Time, nth, nthLast int) bool {
+	for _, wd := range byDay {
+		if wd.Day != t.Weekday() {
+			continue
+		}
+		if wd.N == 0 || wd.N == nth || wd.N == nthLast {
+			return true
+		}
+	}
+	return false
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func parseFrequency(str string) (Frequency, error) {
+	for _, f := range []Frequency{FreqDaily, FreqWeekly, FreqMonthly, FreqYearly} {
+		if strings.EqualFold(str, string(f)) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported frequency: %s", str)
+}
+
+func parseUntil(str string, loc *time.Location) (time.Time, error) {
+	if strings.HasSuffix(str, "Z") {
+		return time.Parse("20060102T150405Z", str)
+	}
+	if len(str) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", str, loc)
+		if err != nil {
+			return time.Time{}, err
+		}
+		// a date-only UNTIL includes the whole day
+		return t.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	return time.ParseInLocation("20060102T150405", str, loc)
+}
+
+func parseByDay(str string) ([]WeekdayNum, error) {
+	var ret []WeekdayNum
+	for _, v := range strings.Split(str, ",") {
+		v = strings.ToUpper(strings.TrimSpace(v))
+		if len(v) < 2 {
+			return nil, fmt.Errorf("invalid weekday: %s", v)
+		}
+		day, ok := weekdays[v[len(v)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid weekday: %s", v)
+		}
+		wd := WeekdayNum{Day: day}
+		if prefix := v[:len(v)-2]; prefix != "" {
+			n, err := strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -53 || n > 53 {
+				return nil, fmt.Errorf("invalid weekday ordinal: %s", v)
+			}
+			wd.N = n
+		}
+		ret = append(ret, wd)
+	}
+	return ret, nil
+}
+
+func parseIntList(str string, min, max int) ([]int, error) {
+	var ret []int
+	for _, v := range strings.Split(str, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n == 0 || n < min || n > max {
+			return nil, fmt.Errorf("invalid value: %s", v)
+		}
+		ret = append(ret, n)
+	}
+	return ret, nil
+}
@@ -0,0 +1,232 @@
+package recurring
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRule(t *testing.T) {
+	cases := []struct {
+		name    string
+		rule    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "weekly by day",
+			rule: "RRULE:FREQ=WEEKLY;BYDAY=MO,WE",
+			want: "FREQ=WEEKLY;BYDAY=MO,WE",
+		},
+		{
+			name: "monthly last friday with count",
+			rule: "FREQ=MONTHLY;INTERVAL=2;COUNT=5;BYDAY=-1FR",
+			want: "FREQ=MONTHLY;INTERVAL=2;COUNT=5;BYDAY=-1FR",
+		},
+		{
+			name: "until",
+			rule: "FREQ=DAILY;UNTIL=20260301T000000Z",
+			want: "FREQ=DAILY;UNTIL=20260301T000000Z",
+		},
+		{
+			name:    "missing freq",
+			rule:    "BYDAY=MO",
+			wantErr: true,
+		},
+		{
+			name:    "count and until",
+			rule:    "FREQ=DAILY;COUNT=2;UNTIL=20260301T000000Z",
+			wantErr: true,
+		},
+		{
+			name:    "invalid weekday",
+			rule:    "FREQ=WEEKLY;BYDAY=XX",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported part",
+			rule:    "FREQ=HOURLY",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := ParseRule(tc.rule, time.UTC)
+			if tc.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if rule.String() != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, rule.String())
+			}
+		})
+	}
+}
+
+func TestRule_Between(t *testing.T) {
+	saigon, err := time.LoadLocation("Asia/Ho_Chi_Minh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		rule    string
+		dtstart time.Time
+		to      time.Time
+		want    []time.Time
+	}{
+		{
+			name:    "weekly on monday and wednesday",
+			rule:    "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4",
+			dtstart: time.Date(2026, 3, 2, 9, 0, 0, 0, saigon),
+			to:      time.Date(2027, 1, 1, 0, 0, 0, 0, saigon),
+			want: []time.Time{
+				time.Date(2026, 3, 2, 9, 0, 0, 0, saigon),
+				time.Date(2026, 3, 4, 9, 0, 0, 0, saigon),
+				time.Date(2026, 3, 9, 9, 0, 0, 0, saigon),
+				time.Date(2026, 3, 11, 9, 0, 0, 0, saigon),
+			},
+		},
+		{
+			name:    "monthly on the last friday until",
+			rule:    "FREQ=MONTHLY;BYDAY=-1FR;UNTIL=20260601",
+			dtstart: time.Date(2026, 3, 1, 17, 0, 0, 0, saigon),
+			to:      time.Date(2027, 1, 1, 0, 0, 0, 0, saigon),
+			want: []time.Time{
+				time.Date(2026, 3, 27, 17, 0, 0, 0, saigon),
+				time.Date(2026, 4, 24, 17, 0, 0, 0, saigon),
+				time.Date(2026, 5, 29, 17, 0, 0, 0, saigon),
+			},
+		},
+		{
+			name:    "monthly on the 31st skips short months",
+			rule:    "FREQ=MONTHLY;COUNT=3",
+			dtstart: time.Date(2026, 1, 31, 8, 0, 0, 0, time.UTC),
+			to:      time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2026, 1, 31, 8, 0, 0, 0, time.UTC),
+				time.Date(2026, 3, 31, 8, 0, 0, 0, time.UTC),
+				time.Date(2026, 5, 31, 8, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:    "daily keeps wall clock across dst",
+			rule:    "FREQ=DAILY;COUNT=3",
+			dtstart: time.Date(2026, 3, 28, 9, 0, 0, 0, berlin),
+			to:      time.Date(2027, 1, 1, 0, 0, 0, 0, berlin),
+			want: []time.Time{
+				time.Date(2026, 3, 28, 9, 0, 0, 0, berlin),
+				time.Date(2026, 3, 29, 9, 0, 0, 0, berlin),
+				time.Date(2026, 3, 30, 9, 0, 0, 0, berlin),
+			},
+		},
+		{
+			name:    "every other week",
+			rule:    "FREQ=WEEKLY;INTERVAL=2",
+			dtstart: time.Date(2026, 3, 4, 9, 0, 0, 0, time.UTC),
+			to:      time.Date(2026, 4, 2, 0, 0, 0, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2026, 3, 4, 9, 0, 0, 0, time.UTC),
+				time.Date(2026, 3, 18, 9, 0, 0, 0, time.UTC),
+				time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:    "yearly on the second monday of march",
+			rule:    "FREQ=YEARLY;BYMONTH=3;BYDAY=2MO;COUNT=2",
+			dtstart: time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC),
+			to:      time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2026, 3, 9, 9, 0, 0, 0, time.UTC),
+				time.Date(2027, 3, 8, 9, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := ParseRule(tc.rule, tc.dtstart.Location())
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := rule.Between(tc.dtstart, tc.dtstart, tc.to)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRule_HasOccurrence(t *testing.T) {
+	dtstart := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	cases := []struct {
+		rule string
+		want bool
+	}{
+		{rule: "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", want: true},
+		{rule: "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", want: false},
+		{rule: "FREQ=MONTHLY;BYMONTH=2;BYDAY=5FR", want: true},
+		{rule: "FREQ=MONTHLY;BYMONTH=4;BYMONTHDAY=31", want: false},
+		{rule: "FREQ=WEEKLY;UNTIL=20251201T000000Z", want: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.rule, func(t *testing.T) {
+			rule, err := ParseRule(tc.rule, time.UTC)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rule.HasOccurrence(dtstart); got != tc.want {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDefinition_Split(t *testing.T) {
+	def := Definition{
+		Title:          "Weekly report",
+		AssignedUserID: 1,
+		RRule:          "FREQ=WEEKLY;COUNT=5",
+		DTStart:        time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC),
+	}
+	materializedUntil := time.Date(2026, 3, 23, 9, 0, 0, 0, time.UTC)
+	def.MaterializedUntil = &materializedUntil
+	at := time.Date(2026, 3, 16, 9, 0, 0, 0, time.UTC)
+
+	head, ok, err := def.Head(at)
+	if err != nil || !ok {
+		t.Fatalf("expected head, got %v, %v", ok, err)
+	}
+	if head.RRule != "FREQ=WEEKLY;COUNT=2" {
+		t.Errorf("expected head rule %q, got %q", "FREQ=WEEKLY;COUNT=2", head.RRule)
+	}
+
+	tail, err := def.Tail(at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tail.RRule != "FREQ=WEEKLY;COUNT=3" {
+		t.Errorf("expected tail rule %q, got %q", "FREQ=WEEKLY;COUNT=3", tail.RRule)
+	}
+	if !tail.DTStart.Equal(at) {
+		t.Errorf("expected tail to start at %v, got %v", at, tail.DTStart)
+	}
+	if tail.MaterializedUntil != nil {
+		t.Errorf("expected tail to be materialized from scratch, got %v", *tail.MaterializedUntil)
+	}
+
+	if _, ok, _ := def.Head(def.DTStart); ok {
+		t.Error("expected no head when splitting at the first occurrence")
+	}
+}
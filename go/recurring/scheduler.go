@@ -0,0 +1,86 @@
+package recurring
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"siransbach/taskmanagementapi/tasks"
+)
+
+// Scheduler periodically materializes the upcoming occurrences of every
+// recurring task definition into api.tasks.
+type Scheduler struct {
+	pg       *sql.DB
+	interval time.Duration
+	horizon  time.Duration
+}
+
+func NewScheduler(pg *sql.DB, interval, horizon time.Duration) *Scheduler {
+	return &Scheduler{pg: pg, interval: interval, horizon: horizon}
+}
+
+// Run materializes occurrences every interval until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.materializeAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) materializeAll(ctx context.Context) {
+	defs, err := NewDB(s.pg).Find(ctx, FindOptions{})
+	if err != nil {
+		log.Err(err).Msg("could not find recurring tasks")
+		return
+	}
+	until := time.Now().Add(s.horizon)
+	for _, def := range defs {
+		n, err := Materialize(ctx, s.pg, def, until)
+		if err != nil {
+			log.Err(err).Int("recurringTaskId", def.ID).Msg("could not materialize recurring task")
+			continue
+		}
+		if n > 0 {
+			log.Info().Int("recurringTaskId", def.ID).Msgf("materialized %d occurrences", n)
+		}
+	}
+}
+
+// Materialize inserts the occurrences of def due between now and until that
+// were not materialized yet, and returns how many were created. Occurrences
+// before the MaterializedUntil of def are left alone, so that those deleted
+// since are not created again.
+func Materialize(ctx context.Context, pg *sql.DB, def Definition, until time.Time) (created int, err error) {
+	from := time.Now()
+	if def.MaterializedUntil != nil && def.MaterializedUntil.After(from) {
+		from = *def.MaterializedUntil
+	}
+	if !until.After(from) {
+		return 0, nil
+	}
+	occurrences, err := def.Occurrences(from, until)
+	if err != nil {
+		return 0, err
+	}
+	db := tasks.NewDB(pg)
+	for _, at := range occurrences {
+		if _, err := db.Insert(ctx, def.Task(at)); err != nil {
+			if errors.Is(err, tasks.ErrOccurrenceExists) {
+				continue
+			}
+			return created, err
+		}
+		created++
+	}
+	return created, NewDB(pg).SetMaterializedUntil(ctx, def.ID, until)
+}
@@ -0,0 +1,93 @@
+package snapshots
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type DB struct {
+	pg *sql.DB
+}
+
+func NewDB(pg *sql.DB) *DB {
+	return &DB{pg}
+}
+
+// Take records the number of tasks of every status per primary assignee as
+// the snapshot of day, replacing the one taken earlier that day.
+func (db *DB) Take(ctx context.Context, day time.Time) (err error) {
+	tx, err := db.pg.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM api.task_status_snapshots WHERE day = $1", day.Format(time.DateOnly)); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `
+		INSERT INTO api.task_status_snapshots (day, user_id, status, count)
+		SELECT $1::date, assigned_user_id, status, COUNT(*)
+		FROM api.tasks
+		WHERE status IS NOT NULL
+		GROUP BY assigned_user_id, status`,
+		day.Format(time.DateOnly),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// History returns the number of tasks of every status at the end of every day
+// or week between From and To that was snapshotted.
+func (db *DB) History(ctx context.Context, options HistoryOptions) ([]StatusPoint, error) {
+	query, args := options.buildQuery()
+	rows, err := db.pg.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := []StatusPoint{}
+	for rows.Next() {
+		var p StatusPoint
+		if err := rows.Scan(&p.Day, &p.Pending, &p.InProgress, &p.Completed); err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// buildQuery picks the last day snapshotted of every day or week, and sums up
+// the counts of the users on that day.
+func (opt HistoryOptions) buildQuery() (query string, args []interface{}) {
+	args = []interface{}{opt.From.Format(time.DateOnly), opt.To.Format(time.DateOnly), string(opt.Granularity)}
+	users := ""
+	if len(opt.UserIDs) > 0 {
+		args = append(args, pq.Array(opt.UserIDs))
+		users = fmt.Sprintf(" AND snapshots.user_id = ANY($%d)", len(args))
+	}
+	return `
+		WITH days AS (
+			SELECT MAX(day) AS day FROM api.task_status_snapshots
+			WHERE day >= $1 AND day <= $2
+			GROUP BY date_trunc($3, day)
+		)
+		SELECT days.day,
+			COALESCE(SUM(snapshots.count) FILTER (WHERE snapshots.status = 'PENDING'), 0),
+			COALESCE(SUM(snapshots.count) FILTER (WHERE snapshots.status = 'IN_PROGRESS'), 0),
+			COALESCE(SUM(snapshots.count) FILTER (WHERE snapshots.status = 'COMPLETED'), 0)
+		FROM days
+		LEFT JOIN api.task_status_snapshots snapshots ON snapshots.day = days.day` + users + `
+		GROUP BY days.day
+		ORDER BY days.day`, args
+}
@@ -0,0 +1,37 @@
+package snapshots
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Scheduler periodically snapshots the number of tasks of every status. The
+// snapshot of a day is taken again on every run that day, so the last run of
+// the day leaves the state at its end.
+type Scheduler struct {
+	pg       *sql.DB
+	interval time.Duration
+}
+
+func NewScheduler(pg *sql.DB, interval time.Duration) *Scheduler {
+	return &Scheduler{pg: pg, interval: interval}
+}
+
+// Run takes a snapshot every interval until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		if err := NewDB(s.pg).Take(ctx, time.Now().UTC()); err != nil {
+			log.Err(err).Msg("could not take task status snapshot")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package snapshots
+
+import (
+	"time"
+
+	"siransbach/taskmanagementapi/tasks"
+)
+
+type (
+	// StatusPoint is the number of tasks of every status at the end of Day, the
+	// last day snapshotted in its day or week.
+	StatusPoint struct {
+		Day        time.Time `json:"day"`
+		Pending    int       `json:"pending"`
+		InProgress int       `json:"inProgress"`
+		Completed  int       `json:"completed"`
+	}
+
+	// BurndownPoint is the number of tasks left and completed at the end of Day.
+	// Ideal goes down linearly from the tasks left on the first day to none on
+	// the last.
+	BurndownPoint struct {
+		Day       time.Time `json:"day"`
+		Remaining int       `json:"remaining"`
+		Completed int       `json:"completed"`
+		Ideal     float64   `json:"ideal"`
+	}
+
+	HistoryOptions struct {
+		From, To    time.Time
+		Granularity tasks.Granularity
+		// UserIDs restricts the counts to the tasks the users are the primary
+		// assignee of.
+		UserIDs []int
+	}
+)
+
+// Burndown turns the status history into a burndown chart.
+func Burndown(points []StatusPoint) []BurndownPoint {
+	burndown := make([]BurndownPoint, 0, len(points))
+	if len(points) == 0 {
+		return burndown
+	}
+	var (
+		first, last = points[0].Day, points[len(points)-1].Day
+		start       = float64(points[0].Pending + points[0].InProgress)
+	)
+	for _, p := range points {
+		b := BurndownPoint{Day: p.Day, Remaining: p.Pending + p.InProgress, Completed: p.Completed, Ideal: start}
+		if last.After(first) {
+			b.Ideal = start * float64(last.Sub(p.Day)) / float64(last.Sub(first))
+		}
+		burndown = append(burndown, b)
+	}
+	return burndown
+}
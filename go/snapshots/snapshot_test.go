@@ -0,0 +1,59 @@
+package snapshots
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+
+	"siransbach/taskmanagementapi/tasks"
+)
+
+func TestBurndown(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	points := []StatusPoint{
+		{Day: day(1), Pending: 6, InProgress: 2},
+		{Day: day(3), Pending: 3, InProgress: 2, Completed: 3},
+		{Day: day(5), Pending: 1, InProgress: 1, Completed: 7},
+	}
+	expected := []BurndownPoint{
+		{Day: day(1), Remaining: 8, Ideal: 8},
+		{Day: day(3), Remaining: 5, Completed: 3, Ideal: 4},
+		{Day: day(5), Remaining: 2, Completed: 7, Ideal: 0},
+	}
+	if burndown := Burndown(points); !reflect.DeepEqual(burndown, expected) {
+		t.Errorf("expected %v, got %v", expected, burndown)
+	}
+
+	if burndown := Burndown(points[:1]); burndown[0].Ideal != 8 {
+		t.Errorf("expected the ideal of a single day to be the tasks left, got %v", burndown[0].Ideal)
+	}
+	if burndown := Burndown(nil); burndown == nil || len(burndown) != 0 {
+		t.Errorf("expected an empty burndown, got %v", burndown)
+	}
+}
+
+func TestHistoryOptions_BuildQuery(t *testing.T) {
+	opts := HistoryOptions{
+		From:        time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		To:          time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC),
+		Granularity: tasks.GranularityWeek,
+		UserIDs:     []int{2},
+	}
+	query, args := opts.buildQuery()
+
+	expectedArgs := []interface{}{"2024-03-01", "2024-03-31", "week", pq.Array([]int{2})}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("expected args %v, got %v", expectedArgs, args)
+	}
+	for _, expected := range []string{
+		"GROUP BY date_trunc($3, day)",
+		"ON snapshots.day = days.day AND snapshots.user_id = ANY($4)",
+	} {
+		if !strings.Contains(query, expected) {
+			t.Errorf("expected query to contain %q, got %q", expected, query)
+		}
+	}
+}
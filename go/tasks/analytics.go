@@ -0,0 +1,206 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type (
+	// AnalyticsOptions narrows down the tasks analyzed. From and To bound the
+	// creation of the tasks counted by status and the completion of the tasks
+	// the cycle time, on-time rate and throughput are computed from.
+	AnalyticsOptions struct {
+		From, To       time.Time
+		AnyAssigneeIDs []int
+		Statuses       []Status
+		// OverdueBefore is when tasks due before are overdue, now when zero
+		OverdueBefore time.Time
+	}
+
+	Analytics struct {
+		ByStatus map[Status]int `json:"byStatus"`
+		// Overdue counts the tasks past their due date and not completed yet.
+		Overdue   int       `json:"overdue"`
+		CycleTime CycleTime `json:"cycleTime"`
+		// OnTimeRate is the share of the completed tasks with a due date that
+		// were completed by then, nil without any.
+		OnTimeRate *float64          `json:"onTimeRate"`
+		Throughput []ThroughputPoint `json:"throughput"`
+	}
+
+	// CycleTime is the time from the creation to the completion of tasks, in
+	// hours. Without completed tasks, all but Completed are nil.
+	CycleTime struct {
+		Completed int      `json:"completed"`
+		Average   *float64 `json:"averageHours"`
+		P50       *float64 `json:"p50Hours"`
+		P90       *float64 `json:"p90Hours"`
+		P95       *float64 `json:"p95Hours"`
+	}
+
+	// ThroughputPoint is the number of tasks completed during the week starting
+	// at Week.
+	ThroughputPoint struct {
+		Week      time.Time `json:"week"`
+		Completed int       `json:"completed"`
+	}
+)
+
+// Analyze computes the analytics of the tasks matching options.
+func (db *DB) Analyze(ctx context.Context, options AnalyticsOptions) (Analytics, error) {
+	a := Analytics{ByStatus: make(map[Status]int, len(Statuses))}
+	for _, s := range Statuses {
+		a.ByStatus[s] = 0
+	}
+
+	query, args := options.countQuery()
+	rows, err := db.pg.QueryContext(ctx, query, args...)
+	if err != nil {
+		return a, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			status         Status
+			count, overdue int
+		)
+		if err := rows.Scan(&status, &count, &overdue); err != nil {
+			return a, err
+		}
+		a.ByStatus[status] = count
+		a.Overdue += overdue
+	}
+	if err := rows.Err(); err != nil {
+		return a, err
+	}
+
+	var (
+		average, p50, p90, p95 sql.NullFloat64
+		withDueDate, onTime    int
+	)
+	query, args = options.completionQuery()
+	if err := db.pg.QueryRowContext(ctx, query, args...).Scan(
+		&a.CycleTime.Completed, &average, &p50, &p90, &p95, &withDueDate, &onTime,
+	); err != nil {
+		return a, err
+	}
+	a.CycleTime.Average, a.CycleTime.P50 = nullFloat(average), nullFloat(p50)
+	a.CycleTime.P90, a.CycleTime.P95 = nullFloat(p90), nullFloat(p95)
+	if withDueDate > 0 {
+		rate := float64(onTime) / float64(withDueDate)
+		a.OnTimeRate = &rate
+	}
+
+	query, args = options.throughputQuery()
+	rows, err = db.pg.QueryContext(ctx, query, args...)
+	if err != nil {
+		return a, err
+	}
+	defer rows.Close()
+	a.Throughput = []ThroughputPoint{}
+	for rows.Next() {
+		var p ThroughputPoint
+		if err := rows.Scan(&p.Week, &p.Completed); err != nil {
+			return a, err
+		}
+		a.Throughput = append(a.Throughput, p)
+	}
+	return a, rows.Err()
+}
+
+// clauses returns the conditions on the assignees and statuses, and on the
+// column between From and To.
+func (opt AnalyticsOptions) clauses(column string, args []interface{}) ([]string, []interface{}) {
+	var clauses []string
+	if len(opt.AnyAssigneeIDs) > 0 {
+		args = append(args, pq.Array(opt.AnyAssigneeIDs))
+		clauses = append(clauses, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM api.task_assignees WHERE task_id = tasks.id AND user_id = ANY($%d))", len(args)))
+	}
+	if len(opt.Statuses) > 0 {
+		args = append(args, pq.Array(opt.Statuses))
+		clauses = append(clauses, fmt.Sprintf("tasks.status = ANY($%d)", len(args)))
+	}
+	if !opt.From.IsZero() {
+		args = append(args, opt.From)
+		clauses = append(clauses, fmt.Sprintf("%s >= $%d", column, len(args)))
+	}
+	if !opt.To.IsZero() {
+		args = append(args, opt.To)
+		clauses = append(clauses, fmt.Sprintf("%s < $%d", column, len(args)))
+	}
+	return clauses, args
+}
+
+func (opt AnalyticsOptions) countQuery() (string, []interface{}) {
+	clauses, args := opt.clauses("tasks.created_at", nil)
+	before := overdueBefore(opt.OverdueBefore, &args)
+	return "SELECT tasks.status, COUNT(*)," +
+		" COUNT(*) FILTER (WHERE tasks.status <> 'COMPLETED' AND tasks.due_date < " + before + ")" +
+		" FROM api.tasks" + where(clauses) + " GROUP BY tasks.status", args
+}
+
+func (opt AnalyticsOptions) completionQuery() (string, []interface{}) {
+	clauses, args := opt.clauses("tasks.completed_at", nil)
+	clauses = append(clauses, "tasks.completed_at IS NOT NULL")
+	const hours = "EXTRACT(EPOCH FROM tasks.completed_at - tasks.created_at) / 3600"
+	return "SELECT COUNT(*), AVG(" + hours + ")," +
+		" percentile_cont(0.5) WITHIN GROUP (ORDER BY " + hours + ")," +
+		" percentile_cont(0.9) WITHIN GROUP (ORDER BY " + hours + ")," +
+		" percentile_cont(0.95) WITHIN GROUP (ORDER BY " + hours + ")," +
+		" COUNT(*) FILTER (WHERE tasks.due_date IS NOT NULL)," +
+		" COUNT(*) FILTER (WHERE tasks.completed_at <= tasks.due_date)" +
+		" FROM api.tasks" + where(clauses), args
+}
+
+// throughputQuery counts the completed tasks of every week between From and
+// To, or between the first and last completion when unbounded, weeks without
+// any included.
+func (opt AnalyticsOptions) throughputQuery() (string, []interface{}) {
+	clauses, args := opt.clauses("tasks.completed_at", nil)
+	clauses = append(clauses, "tasks.completed_at IS NOT NULL")
+	first, last := "(SELECT MIN(completed_at) FROM completed)", "(SELECT MAX(completed_at) FROM completed)"
+	if !opt.From.IsZero() {
+		args = append(args, opt.From)
+		first = fmt.Sprintf("$%d::timestamptz", len(args))
+	}
+	if !opt.To.IsZero() {
+		// To is exclusive
+		args = append(args, opt.To)
+		last = fmt.Sprintf("$%d::timestamptz - INTERVAL '1 microsecond'", len(args))
+	}
+	return "WITH completed AS (SELECT tasks.completed_at FROM api.tasks" + where(clauses) + ")" +
+		" SELECT weeks.week, COUNT(completed.completed_at)" +
+		fmt.Sprintf(" FROM generate_series(date_trunc('week', %s), date_trunc('week', %s), INTERVAL '1 week') AS weeks(week)", first, last) +
+		" LEFT JOIN completed ON date_trunc('week', completed.completed_at) = weeks.week" +
+		" GROUP BY weeks.week ORDER BY weeks.week", args
+}
+
+func where(clauses []string) string {
+	if len(clauses) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(clauses, " AND ")
+}
+
+func nullFloat(f sql.NullFloat64) *float64 {
+	if !f.Valid {
+		return nil
+	}
+	return &f.Float64
+}
+
+// overdueBefore returns the SQL of the time tasks due before are overdue, a
+// placeholder appended to args when set and NOW() otherwise.
+func overdueBefore(before time.Time, args *[]interface{}) string {
+	if before.IsZero() {
+		return "NOW()"
+	}
+	*args = append(*args, before)
+	return fmt.Sprintf("$%d", len(*args))
+}
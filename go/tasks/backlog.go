@@ -0,0 +1,119 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type (
+	// BacklogPoint is the number of unassigned tasks at a point in time.
+	BacklogPoint struct {
+		At   time.Time `json:"at"`
+		Size int       `json:"size"`
+	}
+
+	Granularity string
+)
+
+const (
+	GranularityDay  Granularity = "day"
+	GranularityWeek Granularity = "week"
+)
+
+// ErrAlreadyClaimed is returned by Claim when the task got an assignee first.
+var ErrAlreadyClaimed = errors.New("task already claimed")
+
+func ParseGranularity(str string) (Granularity, error) {
+	for _, g := range []Granularity{GranularityDay, GranularityWeek} {
+		if str == string(g) {
+			return g, nil
+		}
+	}
+	return "", fmt.Errorf("invalid granularity: %s", str)
+}
+
+// ErrNotProjectMember is returned by Claim when the task belongs to a project
+// the user is not a member of.
+var ErrNotProjectMember = errors.New("not a member of the task's project")
+
+// Claim makes userID the primary assignee of an unassigned task that is not
+// completed yet. The check and the assignment happen in a single UPDATE, so
+// when several users race for the same task exactly one of them wins and the
+// others get ErrAlreadyClaimed.
+func (db *DB) Claim(ctx context.Context, id int, userID int) error {
+	var claimedID int
+	err := db.pg.QueryRowContext(ctx, `
+		UPDATE api.tasks SET assigned_user_id = $1
+		WHERE id = $2 AND assigned_user_id IS NULL AND status <> 'COMPLETED'
+		AND (project_id IS NULL OR EXISTS (
+			SELECT 1 FROM api.project_members WHERE project_id = tasks.project_id AND user_id = $1
+		))
+		RETURNING id`,
+		userID, id,
+	).Scan(&claimedID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	// find out why the task could not be claimed
+	var member bool
+	if err := db.pg.QueryRowContext(ctx, `
+		SELECT project_id IS NULL OR EXISTS (
+			SELECT 1 FROM api.project_members WHERE project_id = tasks.project_id AND user_id = $2
+		)
+		FROM api.tasks WHERE id = $1`,
+		id, userID,
+	).Scan(&member); err != nil {
+		return err
+	}
+	if !member {
+		return ErrNotProjectMember
+	}
+	return ErrAlreadyClaimed
+}
+
+// CountBacklog returns the number of unassigned tasks not completed yet.
+func (db *DB) CountBacklog(ctx context.Context) (n int, err error) {
+	err = db.pg.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM api.tasks WHERE assigned_user_id IS NULL AND status <> 'COMPLETED'",
+	).Scan(&n)
+	return n, err
+}
+
+// BacklogHistory returns the size of the backlog at every step between from
+// and to, replaying api.task_assignment_history. Like CountBacklog, tasks
+// completed by then are left out.
+func (db *DB) BacklogHistory(ctx context.Context, from, to time.Time, step Granularity) ([]BacklogPoint, error) {
+	rows, err := db.pg.QueryContext(ctx, `
+		SELECT points.at, COUNT(tasks.id) FILTER (WHERE NOT last_event.assigned)
+		FROM generate_series($1::timestamptz, $2::timestamptz, $3::interval) AS points(at)
+		LEFT JOIN api.tasks ON tasks.created_at <= points.at
+			AND (tasks.completed_at IS NULL OR tasks.completed_at > points.at)
+		LEFT JOIN LATERAL (
+			SELECT history.user_id IS NOT NULL AS assigned
+			FROM api.task_assignment_history history
+			WHERE history.task_id = tasks.id AND history.changed_at <= points.at
+			ORDER BY history.changed_at DESC, history.id DESC
+			LIMIT 1
+		) last_event ON TRUE
+		GROUP BY points.at
+		ORDER BY points.at`,
+		from, to, "1 "+string(step),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []BacklogPoint
+	for rows.Next() {
+		var p BacklogPoint
+		if err := rows.Scan(&p.At, &p.Size); err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
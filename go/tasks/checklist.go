@@ -0,0 +1,90 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+func (db *DB) AddChecklistItem(ctx context.Context, taskID int, text string) (id int, err error) {
+	if text == "" {
+		return 0, errors.New("missing checklist item text")
+	}
+	row := db.pg.QueryRowContext(ctx, `
+		INSERT INTO api.task_checklist_items (task_id,position,text)
+		SELECT tasks.id, COALESCE(MAX(items.position), 0) + 1, $2
+		FROM api.tasks LEFT JOIN api.task_checklist_items items ON items.task_id = tasks.id
+		WHERE tasks.id = $1 GROUP BY tasks.id
+		RETURNING id`,
+		taskID, text,
+	)
+	err = row.Scan(&id)
+	return id, err
+}
+
+// SetChecklistItemDone ticks or unticks a checklist item of a task that userID
+// is one of the assignees of.
+func (db *DB) SetChecklistItemDone(ctx context.Context, taskID, itemID, userID int, done bool) error {
+	row := db.pg.QueryRowContext(ctx, `
+		UPDATE api.task_checklist_items items
+		SET done = $1, done_at = CASE WHEN $1 THEN NOW() END
+		FROM api.task_assignees assignees
+		WHERE assignees.task_id = items.task_id AND items.id = $2 AND items.task_id = $3 AND assignees.user_id = $4
+		RETURNING items.id`,
+		done, itemID, taskID, userID,
+	)
+	var updatedID int
+	return row.Scan(&updatedID)
+}
+
+func (db *DB) DeleteChecklistItem(ctx context.Context, taskID, itemID int) error {
+	row := db.pg.QueryRowContext(ctx,
+		"DELETE FROM api.task_checklist_items WHERE id = $1 AND task_id = $2 RETURNING id",
+		itemID, taskID,
+	)
+	var deletedID int
+	return row.Scan(&deletedID)
+}
+
+func (db *DB) attachChecklists(ctx context.Context, entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	ids := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	rows, err := db.pg.QueryContext(ctx,
+		"SELECT id,task_id,position,text,done,done_at FROM api.task_checklist_items"+
+			" WHERE task_id = ANY($1) ORDER BY task_id, position",
+		pq.Array(ids),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	items := make(map[int][]ChecklistItem)
+	for rows.Next() {
+		var (
+			item   ChecklistItem
+			doneAt sql.NullTime
+		)
+		if err := rows.Scan(&item.ID, &item.TaskID, &item.Position, &item.Text, &item.Done, &doneAt); err != nil {
+			return err
+		}
+		if doneAt.Valid {
+			item.DoneAt = &doneAt.Time
+		}
+		items[item.TaskID] = append(items[item.TaskID], item)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i := range entries {
+		entries[i].Checklist = items[entries[i].ID]
+	}
+	return nil
+}
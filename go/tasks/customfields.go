@@ -0,0 +1,241 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type (
+	// FieldType is the type of a custom field defined on a project.
+	FieldType string
+
+	FilterOp string
+
+	// CustomFieldFilter matches tasks whose value for the custom field Key
+	// compares to Value with Op. Type is the type of the field, which decides
+	// how values are compared.
+	CustomFieldFilter struct {
+		Key   string
+		Type  FieldType
+		Op    FilterOp
+		Value string
+	}
+
+	// CustomFieldSort sorts tasks by their value for the custom field Key.
+	// Tasks without a value come last.
+	CustomFieldSort struct {
+		Key  string
+		Type FieldType
+	}
+)
+
+const (
+	FieldTypeText         FieldType = "TEXT"
+	FieldTypeNumber       FieldType = "NUMBER"
+	FieldTypeDate         FieldType = "DATE"
+	FieldTypeSingleSelect FieldType = "SINGLE_SELECT"
+	FieldTypeMultiSelect  FieldType = "MULTI_SELECT"
+	FieldTypeUser         FieldType = "USER"
+)
+
+var FieldTypes = []FieldType{
+	FieldTypeText, FieldTypeNumber, FieldTypeDate, FieldTypeSingleSelect, FieldTypeMultiSelect, FieldTypeUser,
+}
+
+const (
+	OpEq       FilterOp = "eq"
+	OpNe       FilterOp = "ne"
+	OpGt       FilterOp = "gt"
+	OpGte      FilterOp = "gte"
+	OpLt       FilterOp = "lt"
+	OpLte      FilterOp = "lte"
+	OpContains FilterOp = "contains"
+)
+
+var sqlOperators = map[FilterOp]string{
+	OpEq:  "=",
+	OpNe:  "<>",
+	OpGt:  ">",
+	OpGte: ">=",
+	OpLt:  "<",
+	OpLte: "<=",
+}
+
+// CustomFieldPrefix prefixes custom field keys in query parameters and sort
+// columns, e.g. cf.story_points.
+const CustomFieldPrefix = "cf."
+
+func ParseFieldType(str string) (FieldType, error) {
+	for _, t := range FieldTypes {
+		if strings.EqualFold(str, string(t)) {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("invalid field type: %s", str)
+}
+
+// ParseCustomFieldFilter parses a filter expression of the form op:value, or
+// just value for equality, for a custom field of the given type.
+func ParseCustomFieldFilter(key string, fieldType FieldType, expr string) (CustomFieldFilter, error) {
+	filter := CustomFieldFilter{Key: key, Type: fieldType, Op: OpEq, Value: expr}
+	if op, value, ok := strings.Cut(expr, ":"); ok {
+		if _, known := sqlOperators[FilterOp(op)]; known || FilterOp(op) == OpContains {
+			filter.Op, filter.Value = FilterOp(op), value
+		}
+	}
+
+	switch fieldType {
+	case FieldTypeNumber, FieldTypeUser:
+		if _, err := strconv.ParseFloat(filter.Value, 64); err != nil {
+			return CustomFieldFilter{}, fmt.Errorf("%s: invalid number: %s", key, filter.Value)
+		}
+	case FieldTypeDate:
+		if _, err := time.Parse(time.DateOnly, filter.Value); err != nil {
+			return CustomFieldFilter{}, fmt.Errorf("%s: invalid date: %s", key, filter.Value)
+		}
+	}
+	switch {
+	case fieldType == FieldTypeMultiSelect && filter.Op != OpContains:
+		return CustomFieldFilter{}, fmt.Errorf("%s: only contains is supported for multi select fields", key)
+	case filter.Op == OpContains && fieldType != FieldTypeMultiSelect && fieldType != FieldTypeText:
+		return CustomFieldFilter{}, fmt.Errorf("%s: contains is not supported for %s fields", key, fieldType)
+	}
+	return filter, nil
+}
+
+// valueExpr returns the SQL expression of a custom field value, stored as
+// JSONB in alias.value, cast according to the field type.
+func valueExpr(alias string, fieldType FieldType) string {
+	switch fieldType {
+	case FieldTypeNumber, FieldTypeUser:
+		return fmt.Sprintf("(%s.value #>> '{}')::numeric", alias)
+	case FieldTypeDate:
+		return fmt.Sprintf("(%s.value #>> '{}')::date", alias)
+	default:
+		return fmt.Sprintf("(%s.value #>> '{}')", alias)
+	}
+}
+
+// clause returns the SQL condition of the filter, appending its arguments. The
+// same key may be defined with another type in another project, so the type
+// is checked before casting the value.
+func (f CustomFieldFilter) clause(args []interface{}) (string, []interface{}) {
+	args = append(args, f.Key, f.Type)
+	keyArg, typeArg := len(args)-1, len(args)
+
+	var cmp string
+	switch {
+	case f.Op == OpContains && f.Type == FieldTypeMultiSelect:
+		args = append(args, f.Value)
+		cmp = fmt.Sprintf("cfv.value ? $%d", len(args))
+	case f.Op == OpContains:
+		args = append(args, "%"+escapeLike(f.Value)+"%")
+		cmp = fmt.Sprintf("%s ILIKE $%d", valueExpr("cfv", f.Type), len(args))
+	default:
+		args = append(args, f.Value)
+		cast := ""
+		switch f.Type {
+		case FieldTypeNumber, FieldTypeUser:
+			cast = "::numeric"
+		case FieldTypeDate:
+			cast = "::date"
+		}
+		cmp = fmt.Sprintf("%s %s $%d%s", valueExpr("cfv", f.Type), sqlOperators[f.Op], len(args), cast)
+	}
+	return fmt.Sprintf(
+		"EXISTS (SELECT 1 FROM api.task_custom_values cfv JOIN api.custom_fields cf ON cf.id = cfv.field_id"+
+			" WHERE cfv.task_id = tasks.id AND cf.key = $%d AND CASE WHEN cf.type = $%d THEN %s ELSE FALSE END)",
+		keyArg, typeArg, cmp,
+	), args
+}
+
+func (s CustomFieldSort) expr(args []interface{}) (string, []interface{}) {
+	args = append(args, s.Key, s.Type)
+	return fmt.Sprintf(
+		"(SELECT CASE WHEN cf.type = $%d THEN %s END FROM api.task_custom_values cfv"+
+			" JOIN api.custom_fields cf ON cf.id = cfv.field_id"+
+			" WHERE cfv.task_id = tasks.id AND cf.key = $%d AND cf.type = $%d LIMIT 1)",
+		len(args), valueExpr("cfv", s.Type), len(args)-1, len(args),
+	), args
+}
+
+// SetCustomFieldValues stores the given values of custom fields, keyed by
+// field ID, against a task. A null value removes the value of the field.
+func (db *DB) SetCustomFieldValues(ctx context.Context, taskID int, values map[int]json.RawMessage) error {
+	for fieldID, value := range values {
+		if value == nil || string(value) == "null" {
+			if _, err := db.pg.ExecContext(ctx,
+				"DELETE FROM api.task_custom_values WHERE task_id = $1 AND field_id = $2", taskID, fieldID,
+			); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := db.pg.ExecContext(ctx, `
+			INSERT INTO api.task_custom_values (task_id,field_id,value) VALUES ($1, $2, $3)
+			ON CONFLICT (task_id, field_id) DO UPDATE SET value = EXCLUDED.value`,
+			taskID, fieldID, []byte(value),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *DB) attachCustomFields(ctx context.Context, entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	ids := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	rows, err := db.pg.QueryContext(ctx, `
+		SELECT cfv.task_id, cf.key, cfv.value
+		FROM api.task_custom_values cfv JOIN api.custom_fields cf ON cf.id = cfv.field_id
+		WHERE cfv.task_id = ANY($1)`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	values := make(map[int]map[string]json.RawMessage)
+	for rows.Next() {
+		var (
+			taskID int
+			key    string
+			value  []byte
+		)
+		if err := rows.Scan(&taskID, &key, &value); err != nil {
+			return err
+		}
+		if values[taskID] == nil {
+			values[taskID] = make(map[string]json.RawMessage)
+		}
+		values[taskID][key] = value
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i := range entries {
+		entries[i].CustomFields = values[entries[i].ID]
+	}
+	return nil
+}
+
+// ParseCustomFieldKey strips the custom field prefix from str, e.g.
+// cf.story_points.
+func ParseCustomFieldKey(str string) (string, bool) {
+	if !strings.HasPrefix(str, CustomFieldPrefix) || len(str) == len(CustomFieldPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(str, CustomFieldPrefix), true
+}
@@ -3,22 +3,78 @@ package tasks
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/lib/pq"
 )
 
 type (
 	DB struct {
-		pg *sql.DB
+		pg Session
+	}
+
+	// Session is implemented by both *sql.DB and *sql.Tx, so that a DB can
+	// take part in a caller's transaction.
+	Session interface {
+		ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+		QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+		QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
 	}
 
 	FindOptions struct {
+		IDs             []int
 		AssignedUserIDs []int
-		Statuses        []Status
-		SortBy          DBColumn
-		SortOrder       SortOrder
+		// AnyAssigneeIDs matches tasks that any of the users is an assignee of,
+		// primary or not
+		AnyAssigneeIDs []int
+		WatcherIDs     []int
+		// Unassigned only matches tasks without a primary assignee, i.e. the
+		// backlog
+		Unassigned bool
+		// ProjectMemberID only matches tasks without a project and those of
+		// the projects the user is a member of
+		ProjectMemberID  int
+		RecurringTaskIDs []int
+		ParentTaskIDs    []int
+		ProjectIDs       []int
+		Statuses         []Status
+		// DueFrom, DueTo, CreatedFrom and CreatedTo bound the due date and the
+		// creation date, when set
+		DueFrom, DueTo         time.Time
+		CreatedFrom, CreatedTo time.Time
+		// Overdue only matches tasks past their due date and not completed yet
+		Overdue bool
+		// OverdueBefore is when tasks due before are overdue, now when zero;
+		// it is earlier outside working hours, which do not count as late
+		OverdueBefore time.Time
+		// DueWithin only matches tasks not completed yet that are due between
+		// now and now + DueWithin
+		DueWithin time.Duration
+		// Text matches tasks whose title or description contains it, ignoring
+		// case
+		Text         string
+		CustomFields []CustomFieldFilter
+		// Filter is a filter expression, see ParseFilter
+		Filter FilterExpr
+		// Sort sorts by several columns and takes precedence over SortBy,
+		// SortByCustomField and SortOrder
+		Sort   []SortKey
+		SortBy DBColumn
+		// SortByCustomField takes precedence over SortBy
+		SortByCustomField *CustomFieldSort
+		SortOrder         SortOrder
+		// Limit caps the number of tasks when positive; the tasks are then
+		// sorted by ID last, like when paging from a cursor
+		Limit int
+		// After only matches the tasks that come after the cursor
+		After *Cursor
+		// Fields restricts the fields of the tasks loaded, by their JSON name,
+		// to spare the others; the ID is always loaded. All fields are loaded
+		// when empty.
+		Fields []string
 	}
 
 	DBColumn string
@@ -27,18 +83,41 @@ type (
 )
 
 const (
-	IDCol             DBColumn = "tasks.id"
-	TitleCol          DBColumn = "tasks.title"
-	DescriptionCol    DBColumn = "tasks.description"
-	AssignedUserIDCol DBColumn = "tasks.assigned_user_id"
-	StatusCol         DBColumn = "tasks.status"
-	CreatedAtCol      DBColumn = "tasks.created_at"
-	DueDateCol        DBColumn = "tasks.due_date"
+	IDCol                DBColumn = "tasks.id"
+	TitleCol             DBColumn = "tasks.title"
+	DescriptionCol       DBColumn = "tasks.description"
+	AssignedUserIDCol    DBColumn = "tasks.assigned_user_id"
+	StatusCol            DBColumn = "tasks.status"
+	CreatedAtCol         DBColumn = "tasks.created_at"
+	DueDateCol           DBColumn = "tasks.due_date"
+	PriorityCol          DBColumn = "tasks.priority"
+	LabelsCol            DBColumn = "tasks.labels"
+	ProjectIDCol         DBColumn = "tasks.project_id"
+	BoardRankCol         DBColumn = "tasks.board_rank"
+	ParentTaskIDCol      DBColumn = "tasks.parent_task_id"
+	RecurringTaskIDCol   DBColumn = "tasks.recurring_task_id"
+	OccurrenceAtCol      DBColumn = "tasks.occurrence_at"
+	OriginalEstimateCol  DBColumn = "tasks.original_estimate"
+	RemainingEstimateCol DBColumn = "tasks.remaining_estimate"
 
 	AssignedUsernameCol DBColumn = "users.username AS assigned_username"
 )
 
-var allColumns = []DBColumn{IDCol, TitleCol, DescriptionCol, AssignedUserIDCol, StatusCol, CreatedAtCol, DueDateCol}
+var allColumns = []DBColumn{
+	IDCol, TitleCol, DescriptionCol, AssignedUserIDCol, StatusCol, CreatedAtCol, DueDateCol,
+	PriorityCol, LabelsCol, ProjectIDCol, BoardRankCol, ParentTaskIDCol, RecurringTaskIDCol, OccurrenceAtCol,
+	OriginalEstimateCol, RemainingEstimateCol,
+}
+
+// ErrOccurrenceExists is returned by Insert when the occurrence of a recurring
+// task has already been materialized.
+var ErrOccurrenceExists = errors.New("occurrence already exists")
+
+// ErrDueDateExpired is returned when a task would be due in the past.
+var ErrDueDateExpired = errors.New("due date expired")
+
+// ErrTimeLogged is returned by Delete when time was logged on the task.
+var ErrTimeLogged = errors.New("time was logged on the task")
 
 func (col DBColumn) String() string {
 	return string(col)
@@ -75,17 +154,62 @@ func ParseSortOrder(str string) (SortOrder, error) {
 	return "", fmt.Errorf("invalid sort order: %s", str)
 }
 
-func NewDB(pg *sql.DB) *DB {
+func NewDB(pg Session) *DB {
 	return &DB{pg}
 }
 
 func (db *DB) Find(ctx context.Context, options FindOptions) ([]Entry, error) {
+	entries, _, err := db.find(ctx, options)
+	return entries, err
+}
+
+// find returns the tasks matching options along with the values of their sort
+// keys when paged.
+func (db *DB) find(ctx context.Context, options FindOptions) ([]Entry, [][]sql.NullString, error) {
+	if err := options.checkCursor(); err != nil {
+		return nil, nil, err
+	}
 	query, args := options.buildQuery()
 	rows, err := db.pg.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	sortKeys := 0
+	if options.paged() {
+		keys, _ := options.sortKeys(nil)
+		sortKeys = len(keys)
+	}
+	entries, sortValues, err := db.scanRows(rows, options.columns(), sortKeys)
+	if err != nil {
+		return nil, nil, err
+	}
+	if options.loads("assignees") || options.loads("watchers") {
+		if err := db.attachParticipants(ctx, entries); err != nil {
+			return nil, nil, err
+		}
+	}
+	if options.loads("checklist") {
+		if err := db.attachChecklists(ctx, entries); err != nil {
+			return nil, nil, err
+		}
 	}
-	return db.scanRows(rows)
+	if options.loads("customFields") {
+		if err := db.attachCustomFields(ctx, entries); err != nil {
+			return nil, nil, err
+		}
+	}
+	return entries, sortValues, nil
+}
+
+func (db *DB) FindOne(ctx context.Context, options FindOptions) (Entry, error) {
+	entries, err := db.Find(ctx, options)
+	if err != nil {
+		return Entry{}, err
+	}
+	if len(entries) == 0 {
+		return Entry{}, sql.ErrNoRows
+	}
+	return entries[0], nil
 }
 
 func (db *DB) Insert(ctx context.Context, entry Entry) (id int, err error) {
@@ -96,57 +220,248 @@ func (db *DB) Insert(ctx context.Context, entry Entry) (id int, err error) {
 	if entry.AssignedUserID > 0 {
 		assignedUserID = &entry.AssignedUserID
 	}
+	var recurringTaskID *int
+	if entry.RecurringTaskID > 0 {
+		recurringTaskID = &entry.RecurringTaskID
+	}
+	var parentTaskID *int
+	if entry.ParentTaskID > 0 {
+		parentTaskID = &entry.ParentTaskID
+	}
+	var projectID *int
+	if entry.ProjectID > 0 {
+		projectID = &entry.ProjectID
+	}
 	status := StatusPending
 	if entry.Status != "" {
 		status = entry.Status
 	}
+	priority := PriorityMedium
+	if entry.Priority != "" {
+		priority = entry.Priority
+	}
+	labels := entry.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+	// the remaining estimate starts as the original one unless given
+	remainingEstimate := entry.RemainingEstimate
+	if remainingEstimate == nil {
+		remainingEstimate = entry.OriginalEstimate
+	}
+	var checklist []string
+	for _, item := range entry.Checklist {
+		checklist = append(checklist, item.Text)
+	}
 
-	row := db.pg.QueryRowContext(ctx,
-		"INSERT INTO api.tasks (title,description,assigned_user_id,status,due_date) VALUES ($1, $2, $3, $4, $5) RETURNING id",
-		entry.Title, entry.Description, assignedUserID, status, entry.DueDate,
+	// materializing the same occurrence twice is a no-op thanks to the unique
+	// index on (recurring_task_id, occurrence_at); the checklist is inserted in
+	// the same statement so that a task never exists without it
+	row := db.pg.QueryRowContext(ctx, `
+		WITH task AS (
+			INSERT INTO api.tasks (title,description,assigned_user_id,status,due_date,priority,labels,project_id,parent_task_id,recurring_task_id,occurrence_at,original_estimate,remaining_estimate)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			ON CONFLICT (recurring_task_id, occurrence_at) DO NOTHING RETURNING id
+		), checklist AS (
+			INSERT INTO api.task_checklist_items (task_id,position,text)
+			SELECT task.id, item.position, item.text FROM task, unnest($14::text[]) WITH ORDINALITY AS item(text, position)
+		)
+		SELECT id FROM task`,
+		entry.Title, entry.Description, assignedUserID, status, entry.DueDate, priority, pq.Array(labels),
+		projectID, parentTaskID, recurringTaskID, entry.OccurrenceAt, entry.OriginalEstimate, remainingEstimate,
+		pq.Array(checklist),
 	)
 	err = row.Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrOccurrenceExists
+	}
 	return id, err
 }
 
-func (db *DB) UpdateStatus(ctx context.Context, id int, assignedUserID int, status Status) error {
+// Update overwrites the editable fields of the task identified by entry.ID.
+// The due date is not checked, so that tasks already due can still be edited;
+// a new one must be in the future, as with SetDueDate.
+func (db *DB) Update(ctx context.Context, entry Entry) error {
+	if err := entry.ValidateFields(); err != nil {
+		return fmt.Errorf("invalid entry: %w", err)
+	}
+	var assignedUserID *int
+	if entry.AssignedUserID > 0 {
+		assignedUserID = &entry.AssignedUserID
+	}
+	labels := entry.Labels
+	if labels == nil {
+		labels = []string{}
+	}
 	row := db.pg.QueryRowContext(ctx,
-		"UPDATE api.tasks SET status = $1 WHERE assigned_user_id = $2 AND id = $3 RETURNING id",
-		status, id, assignedUserID,
+		"UPDATE api.tasks SET title = $1, description = $2, assigned_user_id = $3, status = $4, due_date = $5,"+
+			" priority = $6, labels = $7 WHERE id = $8 RETURNING id",
+		entry.Title, entry.Description, assignedUserID, entry.Status, entry.DueDate,
+		entry.Priority, pq.Array(labels), entry.ID,
 	)
 	var updatedID int
 	return row.Scan(&updatedID)
 }
 
+// SetEstimates changes the original and remaining estimates of a task, in
+// minutes. A nil estimate is left unchanged.
+func (db *DB) SetEstimates(ctx context.Context, id int, original, remaining *int) error {
+	if (original != nil && *original < 0) || (remaining != nil && *remaining < 0) {
+		return errors.New("invalid estimate")
+	}
+	row := db.pg.QueryRowContext(ctx, `
+		UPDATE api.tasks SET
+			original_estimate = COALESCE($1, original_estimate),
+			remaining_estimate = COALESCE($2, remaining_estimate, $1)
+		WHERE id = $3
+		RETURNING id`,
+		original, remaining, id,
+	)
+	var updatedID int
+	return row.Scan(&updatedID)
+}
+
+// UpdateStatus changes the status of a task that userID is one of the
+// assignees of.
+func (db *DB) UpdateStatus(ctx context.Context, id int, userID int, status Status) error {
+	row := db.pg.QueryRowContext(ctx, `
+		UPDATE api.tasks SET status = $1
+		WHERE id = $2 AND EXISTS (
+			SELECT 1 FROM api.task_assignees WHERE task_id = tasks.id AND user_id = $3
+		)
+		RETURNING id`,
+		status, id, userID,
+	)
+	var updatedID int
+	return row.Scan(&updatedID)
+}
+
+// SetStatus changes the status of a task, whoever its assignees are.
+func (db *DB) SetStatus(ctx context.Context, id int, status Status) error {
+	row := db.pg.QueryRowContext(ctx, "UPDATE api.tasks SET status = $1 WHERE id = $2 RETURNING id", status, id)
+	var updatedID int
+	return row.Scan(&updatedID)
+}
+
+// Reassign changes the primary assignee of a task, or moves it to the backlog
+// when userID is 0. Shared assignees are kept.
+func (db *DB) Reassign(ctx context.Context, id int, userID int) error {
+	var assignedUserID *int
+	if userID > 0 {
+		assignedUserID = &userID
+	}
+	row := db.pg.QueryRowContext(ctx,
+		"UPDATE api.tasks SET assigned_user_id = $1 WHERE id = $2 RETURNING id", assignedUserID, id,
+	)
+	var updatedID int
+	return row.Scan(&updatedID)
+}
+
+// SetDueDate changes the due date of a task, which must be in the future.
+func (db *DB) SetDueDate(ctx context.Context, id int, dueDate time.Time) error {
+	if time.Now().After(dueDate) {
+		return ErrDueDateExpired
+	}
+	row := db.pg.QueryRowContext(ctx, "UPDATE api.tasks SET due_date = $1 WHERE id = $2 RETURNING id", dueDate, id)
+	var updatedID int
+	return row.Scan(&updatedID)
+}
+
+// Delete deletes a task along with its subtasks, checklists and participants.
+// Tasks with time logged on them or their subtasks are kept, returning
+// ErrTimeLogged, as deleting them would delete the time too.
+func (db *DB) Delete(ctx context.Context, id int) error {
+	_, err := db.DeleteTree(ctx, id)
+	return err
+}
+
+// DeleteTree deletes a task like Delete and returns the IDs of the tasks it
+// deleted, the task first then its subtasks at any depth.
+func (db *DB) DeleteTree(ctx context.Context, id int) ([]int, error) {
+	row := db.pg.QueryRowContext(ctx, `
+		WITH RECURSIVE tree AS (
+			SELECT id, 0 AS depth FROM api.tasks WHERE id = $1
+			UNION ALL
+			SELECT tasks.id, tree.depth + 1 FROM api.tasks JOIN tree ON tasks.parent_task_id = tree.id
+		), task AS (
+			SELECT EXISTS (SELECT 1 FROM api.worklogs WHERE task_id IN (SELECT id FROM tree))
+				OR EXISTS (SELECT 1 FROM api.timesheet_entries WHERE task_id IN (SELECT id FROM tree)) AS logged
+			FROM api.tasks WHERE id = $1
+		), deleted AS (
+			DELETE FROM api.tasks WHERE id = $1 AND NOT (SELECT logged FROM task)
+		)
+		SELECT logged, ARRAY(SELECT id FROM tree ORDER BY depth, id) FROM task`,
+		id,
+	)
+	var (
+		logged bool
+		ids    []int64
+	)
+	if err := row.Scan(&logged, pq.Array(&ids)); err != nil {
+		return nil, err
+	}
+	if logged {
+		return nil, ErrTimeLogged
+	}
+	deleted := make([]int, 0, len(ids))
+	for _, id := range ids {
+		deleted = append(deleted, int(id))
+	}
+	return deleted, nil
+}
+
+// TaskSummary counts the tasks of a user. A task shared between several
+// assignees counts towards each of them; Primary and Shared tell how many of
+// those the user is the primary assignee of and shares with others.
 type TaskSummary struct {
 	UserID    int    `json:"userId"`
 	Username  string `json:"username"`
 	Assigned  int    `json:"assigned"`
 	Completed int    `json:"completed"`
+	Primary   int    `json:"primary"`
+	Shared    int    `json:"shared"`
+}
+
+type SummarizeOptions struct {
+	ProjectIDs []int
 }
 
-func (db *DB) Summarize(ctx context.Context) ([]TaskSummary, error) {
+// Summarize reads the counts of the tasks of every user from the
+// api.task_summaries read model.
+func (db *DB) Summarize(ctx context.Context, options SummarizeOptions) ([]TaskSummary, error) {
+	var (
+		where string
+		args  []interface{}
+	)
+	if len(options.ProjectIDs) > 0 {
+		args = append(args, pq.Array(options.ProjectIDs))
+		where = fmt.Sprintf("WHERE summaries.project_id = ANY($%d)", len(args))
+	}
 	stmt := fmt.Sprintf(`
 		SELECT 
 			users.id,
 			users.username,
-			COUNT(*) as assigned,
-			COUNT(*) FILTER (WHERE status = 'COMPLETED') as completed
-		FROM api.tasks
-		JOIN auth.users ON users.id = tasks.assigned_user_id
+			SUM(summaries.assigned),
+			SUM(summaries.completed),
+			SUM(summaries.primary_assigned),
+			SUM(summaries.shared)
+		FROM api.task_summaries summaries
+		JOIN auth.users ON users.id = summaries.user_id
+		%s
 		GROUP BY users.id ORDER BY users.id ASC
-	`)
+	`, where)
 
-	rows, err := db.pg.QueryContext(ctx, stmt)
+	rows, err := db.pg.QueryContext(ctx, stmt, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	var summaries []TaskSummary
 
 	for rows.Next() {
 		s := TaskSummary{}
-		if err = rows.Scan(&s.UserID, &s.Username, &s.Assigned, &s.Completed); err != nil {
+		if err = rows.Scan(&s.UserID, &s.Username, &s.Assigned, &s.Completed, &s.Primary, &s.Shared); err != nil {
 			return nil, err
 		}
 		summaries = append(summaries, s)
@@ -155,57 +470,198 @@ func (db *DB) Summarize(ctx context.Context) ([]TaskSummary, error) {
 	return summaries, nil
 }
 
-func (db *DB) scanRows(rows *sql.Rows) ([]Entry, error) {
-	var entries []Entry
+// scanRows scans rows of the given columns, followed by the values of sortKeys
+// sort keys.
+func (db *DB) scanRows(rows *sql.Rows, cols []DBColumn, sortKeys int) ([]Entry, [][]sql.NullString, error) {
+	defer rows.Close()
+	var (
+		entries    []Entry
+		sortValues [][]sql.NullString
+	)
 	for rows.Next() {
-		var entry Entry
-
-		err := rows.Scan(
-			&entry.ID, &entry.Title, &entry.Description, &entry.AssignedUserID,
-			&entry.Status, &entry.CreatedAt, &entry.DueDate, &entry.AssignedUsername,
+		var (
+			entry     Entry
+			nullable  nullableColumns
+			sortValue = make([]sql.NullString, sortKeys)
+			dest      = make([]interface{}, 0, len(cols)+sortKeys)
 		)
-		if err != nil {
-			return nil, err
+		for _, col := range cols {
+			dest = append(dest, entry.dest(col, &nullable))
 		}
+		for i := range sortValue {
+			dest = append(dest, &sortValue[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, nil, err
+		}
+		nullable.apply(&entry)
 		entries = append(entries, entry)
+		if sortKeys > 0 {
+			sortValues = append(sortValues, sortValue)
+		}
 	}
-	return entries, nil
+	return entries, sortValues, rows.Err()
 }
 
 func (opt FindOptions) buildQuery() (query string, args []interface{}) {
-	var clauses []string
+	clauses, args := opt.filterClauses()
+
+	selectCols := toColumnStrings(opt.columns())
+	if !opt.paged() {
+		stmt := fmt.Sprintf(
+			"SELECT %s FROM api.tasks LEFT JOIN auth.users ON users.id = tasks.assigned_user_id",
+			strings.Join(selectCols, ","),
+		)
+		if len(clauses) > 0 {
+			stmt = fmt.Sprintf("%s WHERE %s", stmt, strings.Join(clauses, " AND "))
+		}
+		sort, args := opt.sortClause(args)
+		return stmt + sort, args
+	}
+
+	// the sort values are selected as text to build the cursor of the next page
+	keys, args := opt.sortKeys(args)
+	for i, key := range keys {
+		selectCols = append(selectCols, fmt.Sprintf("(%s)::text AS sort_key_%d", key.expr, i+1))
+	}
+	if opt.After != nil {
+		var clause string
+		clause, args = opt.After.afterClause(keys, opt.idOrder(), args)
+		clauses = append(clauses, clause)
+	}
+	stmt := fmt.Sprintf(
+		"SELECT %s FROM api.tasks LEFT JOIN auth.users ON users.id = tasks.assigned_user_id",
+		strings.Join(selectCols, ","),
+	)
+	if len(clauses) > 0 {
+		stmt = fmt.Sprintf("%s WHERE %s", stmt, strings.Join(clauses, " AND "))
+	}
+	stmt += orderBy(keys, opt.idOrder())
+	if opt.Limit > 0 {
+		args = append(args, opt.Limit)
+		stmt = fmt.Sprintf("%s LIMIT $%d", stmt, len(args))
+	}
+	return stmt, args
+}
+
+// buildCountQuery counts the tasks matching the filters of opt, on all pages.
+func (opt FindOptions) buildCountQuery() (query string, args []interface{}) {
+	clauses, args := opt.filterClauses()
+	stmt := "SELECT COUNT(*) FROM api.tasks"
+	if len(clauses) > 0 {
+		stmt = fmt.Sprintf("%s WHERE %s", stmt, strings.Join(clauses, " AND "))
+	}
+	return stmt, args
+}
+
+func (opt FindOptions) filterClauses() (clauses []string, args []interface{}) {
 	args = make([]interface{}, 0)
 
+	if len(opt.IDs) > 0 {
+		args = append(args, pq.Array(opt.IDs))
+		clauses = append(clauses, fmt.Sprintf("tasks.id = ANY($%d)", len(args)))
+	}
 	if len(opt.AssignedUserIDs) > 0 {
 		args = append(args, pq.Array(opt.AssignedUserIDs))
 		clauses = append(clauses, fmt.Sprintf("assigned_user_id = ANY($%d)", len(args)))
 	}
+	if opt.Unassigned {
+		clauses = append(clauses, "tasks.assigned_user_id IS NULL")
+	}
+	if opt.ProjectMemberID > 0 {
+		args = append(args, opt.ProjectMemberID)
+		clauses = append(clauses, fmt.Sprintf("(tasks.project_id IS NULL OR EXISTS ("+
+			"SELECT 1 FROM api.project_members WHERE project_id = tasks.project_id AND user_id = $%d))", len(args)))
+	}
+	if len(opt.AnyAssigneeIDs) > 0 {
+		args = append(args, pq.Array(opt.AnyAssigneeIDs))
+		clauses = append(clauses, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM api.task_assignees WHERE task_id = tasks.id AND user_id = ANY($%d))", len(args)))
+	}
+	if len(opt.WatcherIDs) > 0 {
+		args = append(args, pq.Array(opt.WatcherIDs))
+		clauses = append(clauses, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM api.task_watchers WHERE task_id = tasks.id AND user_id = ANY($%d))", len(args)))
+	}
 	if len(opt.Statuses) > 0 {
 		args = append(args, pq.Array(opt.Statuses))
 		clauses = append(clauses, fmt.Sprintf("status = ANY($%d)", len(args)))
 	}
-
-	selectCols := append(allColumns, AssignedUsernameCol)
-	stmt := fmt.Sprintf(
-		"SELECT %s FROM api.tasks JOIN auth.users ON users.id = tasks.assigned_user_id",
-		strings.Join(toColumnStrings(selectCols), ","),
-	)
-
-	if len(clauses) == 0 {
-		return fmt.Sprintf("%s%s", stmt, opt.sortClause()), args
+	if len(opt.RecurringTaskIDs) > 0 {
+		args = append(args, pq.Array(opt.RecurringTaskIDs))
+		clauses = append(clauses, fmt.Sprintf("recurring_task_id = ANY($%d)", len(args)))
 	}
-	return fmt.Sprintf("%s WHERE %s%s", stmt, strings.Join(clauses, " AND "), opt.sortClause()), args
+	if len(opt.ProjectIDs) > 0 {
+		args = append(args, pq.Array(opt.ProjectIDs))
+		clauses = append(clauses, fmt.Sprintf("project_id = ANY($%d)", len(args)))
+	}
+	if len(opt.ParentTaskIDs) > 0 {
+		args = append(args, pq.Array(opt.ParentTaskIDs))
+		clauses = append(clauses, fmt.Sprintf("parent_task_id = ANY($%d)", len(args)))
+	}
+	if !opt.DueFrom.IsZero() {
+		args = append(args, opt.DueFrom)
+		clauses = append(clauses, fmt.Sprintf("tasks.due_date >= $%d", len(args)))
+	}
+	if !opt.DueTo.IsZero() {
+		args = append(args, opt.DueTo)
+		clauses = append(clauses, fmt.Sprintf("tasks.due_date < $%d", len(args)))
+	}
+	if !opt.CreatedFrom.IsZero() {
+		args = append(args, opt.CreatedFrom)
+		clauses = append(clauses, fmt.Sprintf("tasks.created_at >= $%d", len(args)))
+	}
+	if !opt.CreatedTo.IsZero() {
+		args = append(args, opt.CreatedTo)
+		clauses = append(clauses, fmt.Sprintf("tasks.created_at < $%d", len(args)))
+	}
+	if opt.Overdue {
+		clauses = append(clauses, "tasks.due_date < "+overdueBefore(opt.OverdueBefore, &args)+" AND tasks.status <> 'COMPLETED'")
+	}
+	if opt.DueWithin > 0 {
+		args = append(args, int64(opt.DueWithin.Seconds()))
+		clauses = append(clauses, fmt.Sprintf(
+			"tasks.due_date >= NOW() AND tasks.due_date < NOW() + $%d * INTERVAL '1 second' AND tasks.status <> 'COMPLETED'",
+			len(args)))
+	}
+	if opt.Text != "" {
+		args = append(args, "%"+escapeLike(opt.Text)+"%")
+		clauses = append(clauses, fmt.Sprintf("(tasks.title ILIKE $%d OR tasks.description ILIKE $%d)", len(args), len(args)))
+	}
+	for _, filter := range opt.CustomFields {
+		var clause string
+		clause, args = filter.clause(args)
+		clauses = append(clauses, clause)
+	}
+	if opt.Filter != nil {
+		var clause string
+		clause, args = opt.Filter.clause(args)
+		clauses = append(clauses, clause)
+	}
+	return clauses, args
 }
 
-func (opt FindOptions) sortClause() string {
-	if opt.SortBy == "" {
-		return ""
+func (opt FindOptions) sortClause(args []interface{}) (string, []interface{}) {
+	if len(opt.Sort) > 0 {
+		keys, args := opt.sortKeys(args)
+		return orderBy(keys, opt.idOrder()), args
 	}
-	sortOrder := SortOrderAscending
-	if opt.SortOrder == SortOrderDescending {
-		sortOrder = SortOrderDescending
+	sortOrder := opt.sortOrder()
+	if opt.SortByCustomField != nil {
+		var expr string
+		expr, args = opt.SortByCustomField.expr(args)
+		return fmt.Sprintf(" ORDER BY %s %s NULLS LAST, tasks.id", expr, sortOrder), args
 	}
-	return fmt.Sprintf(" ORDER BY %s %s", opt.SortBy, sortOrder)
+	if opt.SortBy == "" {
+		return "", args
+	}
+	return fmt.Sprintf(" ORDER BY %s %s", opt.SortBy, sortOrder), args
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern, so that str is matched
+// literally.
+func escapeLike(str string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(str)
 }
 
 func toColumnStrings(cols []DBColumn) []string {
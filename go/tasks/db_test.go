@@ -3,14 +3,23 @@ package tasks
 import (
 	"context"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/lib/pq"
 
 	"siransbach/taskmanagementapi/postgres"
 )
 
+const selectAll = "SELECT tasks.id,tasks.title,tasks.description,tasks.assigned_user_id,tasks.status" +
+	",tasks.created_at,tasks.due_date,tasks.priority,tasks.labels,tasks.project_id,tasks.board_rank,tasks.parent_task_id" +
+	",tasks.recurring_task_id,tasks.occurrence_at,tasks.original_estimate,tasks.remaining_estimate" +
+	",users.username AS assigned_username" +
+	" FROM api.tasks LEFT JOIN auth.users ON users.id = tasks.assigned_user_id"
+
 func TestFindOptions_BuildQuery(t *testing.T) {
+	due, high := "2024-03-01 09:00:00+00", "HIGH"
 	cases := []struct {
 		name  string
 		opts  FindOptions
@@ -18,21 +27,17 @@ func TestFindOptions_BuildQuery(t *testing.T) {
 		args  []interface{}
 	}{
 		{
-			name: "no options",
-			opts: FindOptions{},
-			query: "SELECT tasks.id,tasks.title,tasks.description,tasks.assigned_user_id,tasks.status" +
-				",tasks.created_at,tasks.due_date,users.username AS assigned_username" +
-				" FROM api.tasks JOIN auth.users ON users.id = tasks.assigned_user_id",
-			args: []interface{}{},
+			name:  "no options",
+			opts:  FindOptions{},
+			query: selectAll,
+			args:  []interface{}{},
 		},
 		{
 			name: "with assigned user IDs",
 			opts: FindOptions{
 				AssignedUserIDs: []int{1, 2},
 			},
-			query: "SELECT tasks.id,tasks.title,tasks.description,tasks.assigned_user_id,tasks.status" +
-				",tasks.created_at,tasks.due_date,users.username AS assigned_username" +
-				" FROM api.tasks JOIN auth.users ON users.id = tasks.assigned_user_id WHERE assigned_user_id = ANY($1)",
+			query: selectAll + " WHERE assigned_user_id = ANY($1)",
 			args: []interface{}{
 				pq.Array([]int{1, 2}),
 			},
@@ -41,37 +46,204 @@ func TestFindOptions_BuildQuery(t *testing.T) {
 			opts: FindOptions{
 				Statuses: []Status{StatusCompleted, StatusInProgress},
 			},
-			query: "SELECT tasks.id,tasks.title,tasks.description,tasks.assigned_user_id,tasks.status" +
-				",tasks.created_at,tasks.due_date,users.username AS assigned_username" +
-				" FROM api.tasks JOIN auth.users ON users.id = tasks.assigned_user_id WHERE status = ANY($1)",
+			query: selectAll + " WHERE status = ANY($1)",
 			args: []interface{}{
 				pq.Array([]Status{StatusCompleted, StatusInProgress}),
 			},
 		},
+		{
+			name: "with backlog of project member",
+			opts: FindOptions{
+				Unassigned:      true,
+				ProjectMemberID: 3,
+			},
+			query: selectAll + " WHERE tasks.assigned_user_id IS NULL AND (tasks.project_id IS NULL OR EXISTS (" +
+				"SELECT 1 FROM api.project_members WHERE project_id = tasks.project_id AND user_id = $1))",
+			args: []interface{}{
+				3,
+			},
+		},
 		{
 			name: "with assigned user IDs, statuses",
 			opts: FindOptions{
 				AssignedUserIDs: []int{1, 2},
 				Statuses:        []Status{StatusCompleted, StatusInProgress},
 			},
-			query: "SELECT tasks.id,tasks.title,tasks.description,tasks.assigned_user_id,tasks.status" +
-				",tasks.created_at,tasks.due_date,users.username AS assigned_username" +
-				" FROM api.tasks JOIN auth.users ON users.id = tasks.assigned_user_id WHERE assigned_user_id = ANY($1) AND status = ANY($2)",
+			query: selectAll + " WHERE assigned_user_id = ANY($1) AND status = ANY($2)",
 			args: []interface{}{
 				pq.Array([]int{1, 2}),
 				pq.Array([]Status{StatusCompleted, StatusInProgress}),
 			},
 		},
+		{
+			name: "with IDs, recurring task IDs, project IDs",
+			opts: FindOptions{
+				IDs:              []int{3},
+				RecurringTaskIDs: []int{7},
+				ProjectIDs:       []int{2},
+			},
+			query: selectAll + " WHERE tasks.id = ANY($1) AND recurring_task_id = ANY($2) AND project_id = ANY($3)",
+			args: []interface{}{
+				pq.Array([]int{3}),
+				pq.Array([]int{7}),
+				pq.Array([]int{2}),
+			},
+		},
+		{
+			name: "with any assignee IDs, watcher IDs",
+			opts: FindOptions{
+				AnyAssigneeIDs: []int{1},
+				WatcherIDs:     []int{2},
+			},
+			query: selectAll +
+				" WHERE EXISTS (SELECT 1 FROM api.task_assignees WHERE task_id = tasks.id AND user_id = ANY($1))" +
+				" AND EXISTS (SELECT 1 FROM api.task_watchers WHERE task_id = tasks.id AND user_id = ANY($2))",
+			args: []interface{}{
+				pq.Array([]int{1}),
+				pq.Array([]int{2}),
+			},
+		},
+		{
+			name: "unassigned",
+			opts: FindOptions{
+				Unassigned: true,
+				Statuses:   []Status{StatusPending},
+			},
+			query: selectAll + " WHERE tasks.assigned_user_id IS NULL AND status = ANY($1)",
+			args: []interface{}{
+				pq.Array([]Status{StatusPending}),
+			},
+		},
 		{
 			name: "with sort",
 			opts: FindOptions{
 				SortBy:    CreatedAtCol,
 				SortOrder: SortOrderAscending,
 			},
-			query: "SELECT tasks.id,tasks.title,tasks.description,tasks.assigned_user_id,tasks.status" +
-				",tasks.created_at,tasks.due_date,users.username AS assigned_username" +
-				" FROM api.tasks JOIN auth.users ON users.id = tasks.assigned_user_id ORDER BY tasks.created_at ASC",
-			args: []interface{}{},
+			query: selectAll + " ORDER BY tasks.created_at ASC",
+			args:  []interface{}{},
+		},
+		{
+			name: "with due and created ranges, overdue, due within, text",
+			opts: FindOptions{
+				DueFrom:     time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+				DueTo:       time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+				CreatedFrom: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				CreatedTo:   time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+				Overdue:     true,
+				DueWithin:   72 * time.Hour,
+				Text:        "50%_off",
+			},
+			query: selectAll + " WHERE tasks.due_date >= $1 AND tasks.due_date < $2" +
+				" AND tasks.created_at >= $3 AND tasks.created_at < $4" +
+				" AND tasks.due_date < NOW() AND tasks.status <> 'COMPLETED'" +
+				" AND tasks.due_date >= NOW() AND tasks.due_date < NOW() + $5 * INTERVAL '1 second' AND tasks.status <> 'COMPLETED'" +
+				" AND (tasks.title ILIKE $6 OR tasks.description ILIKE $6)",
+			args: []interface{}{
+				time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+				int64(259200),
+				`%50\%\_off%`,
+			},
+		},
+		{
+			name: "with custom field filter, custom field sort",
+			opts: FindOptions{
+				ProjectIDs:        []int{2},
+				CustomFields:      []CustomFieldFilter{{Key: "points", Type: FieldTypeNumber, Op: OpGte, Value: "3"}},
+				SortByCustomField: &CustomFieldSort{Key: "due", Type: FieldTypeDate},
+				SortOrder:         SortOrderDescending,
+			},
+			query: selectAll + " WHERE project_id = ANY($1)" +
+				" AND EXISTS (SELECT 1 FROM api.task_custom_values cfv JOIN api.custom_fields cf ON cf.id = cfv.field_id" +
+				" WHERE cfv.task_id = tasks.id AND cf.key = $2 AND CASE WHEN cf.type = $3" +
+				" THEN (cfv.value #>> '{}')::numeric >= $4::numeric ELSE FALSE END)" +
+				" ORDER BY (SELECT CASE WHEN cf.type = $6 THEN (cfv.value #>> '{}')::date END FROM api.task_custom_values cfv" +
+				" JOIN api.custom_fields cf ON cf.id = cfv.field_id" +
+				" WHERE cfv.task_id = tasks.id AND cf.key = $5 AND cf.type = $6 LIMIT 1) DESC NULLS LAST, tasks.id",
+			args: []interface{}{
+				pq.Array([]int{2}), "points", FieldTypeNumber, "3", "due", FieldTypeDate,
+			},
+		},
+		{
+			name: "with statuses, filter expression",
+			opts: FindOptions{
+				Statuses: []Status{StatusPending},
+				Filter: FilterOr{
+					Left:  FilterCondition{Field: "id", Op: OpEq, Values: []FilterValue{{Value: 3}}},
+					Right: FilterCondition{Field: "due_date", Op: OpIsNull},
+				},
+			},
+			query: selectAll + " WHERE status = ANY($1) AND (tasks.id = $2 OR tasks.due_date IS NULL)",
+			args: []interface{}{
+				pq.Array([]Status{StatusPending}), 3,
+			},
+		},
+		{
+			name: "with limit",
+			opts: FindOptions{
+				Statuses: []Status{StatusPending},
+				Limit:    51,
+			},
+			query: selectAll + " WHERE status = ANY($1) ORDER BY tasks.id ASC LIMIT $2",
+			args: []interface{}{
+				pq.Array([]Status{StatusPending}), 51,
+			},
+		},
+		{
+			name: "with sort, cursor, limit",
+			opts: FindOptions{
+				SortBy:    DueDateCol,
+				SortOrder: SortOrderDescending,
+				After:     &Cursor{Sort: "tasks.due_date DESC,tasks.id DESC", Values: []*string{&due}, ID: 12},
+				Limit:     21,
+			},
+			query: strings.Replace(selectAll, " FROM", ",(tasks.due_date)::text AS sort_key_1 FROM", 1) +
+				" WHERE ((tasks.due_date < $1 OR tasks.due_date IS NULL) OR (tasks.due_date = $1 AND tasks.id < $2))" +
+				" ORDER BY tasks.due_date DESC NULLS LAST, tasks.id DESC LIMIT $3",
+			args: []interface{}{
+				due, 12, 21,
+			},
+		},
+		{
+			name: "with sort by assigned username, cursor without value",
+			opts: FindOptions{
+				SortBy: "assigned_username",
+				After:  &Cursor{Sort: "assigned_username ASC,tasks.id ASC", Values: []*string{nil}, ID: 4},
+			},
+			query: strings.Replace(selectAll, " FROM", ",(users.username)::text AS sort_key_1 FROM", 1) +
+				" WHERE ((users.username IS NULL AND tasks.id > $1))" +
+				" ORDER BY users.username ASC NULLS LAST, tasks.id ASC",
+			args: []interface{}{
+				4,
+			},
+		},
+		{
+			name: "with multi-column sort",
+			opts: FindOptions{
+				Sort: []SortKey{{Column: DueDateCol, Order: SortOrderDescending}, {Column: TitleCol}},
+			},
+			query: selectAll + " ORDER BY tasks.due_date DESC NULLS LAST, tasks.title ASC NULLS LAST, tasks.id DESC",
+			args:  []interface{}{},
+		},
+		{
+			name: "with multi-column sort, cursor, fields",
+			opts: FindOptions{
+				Sort:   []SortKey{{Column: PriorityCol, Order: SortOrderDescending}, {Column: DueDateCol}},
+				After:  &Cursor{Sort: "tasks.priority DESC,tasks.due_date ASC,tasks.id DESC", Values: []*string{&high, nil}, ID: 7},
+				Fields: []string{"title", "dueDate", "checklist"},
+				Limit:  11,
+			},
+			query: "SELECT tasks.id,tasks.title,tasks.due_date,(tasks.priority)::text AS sort_key_1,(tasks.due_date)::text AS sort_key_2" +
+				" FROM api.tasks LEFT JOIN auth.users ON users.id = tasks.assigned_user_id" +
+				" WHERE ((tasks.priority < $1 OR tasks.priority IS NULL)" +
+				" OR (tasks.priority = $1 AND tasks.due_date IS NULL AND tasks.id < $2))" +
+				" ORDER BY tasks.priority DESC NULLS LAST, tasks.due_date ASC NULLS LAST, tasks.id DESC LIMIT $3",
+			args: []interface{}{
+				high, 7, 11,
+			},
 		},
 	}
 
@@ -88,6 +260,75 @@ func TestFindOptions_BuildQuery(t *testing.T) {
 	}
 }
 
+func TestSearchOptions_BuildQuery(t *testing.T) {
+	opts := SearchOptions{Query: `"weekly report" -draft`, VisibleTo: 3, ProjectIDs: []int{2}, Limit: 20}
+	query, args := opts.buildQuery()
+
+	expectedArgs := []interface{}{`"weekly report" -draft`, 3, pq.Array([]int{2}), 20}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("expected args %v, got %v", expectedArgs, args)
+	}
+	for _, expected := range []string{
+		"websearch_to_tsquery(api.search_language(), $1) search_query",
+		"WHERE tasks.search_vector @@ search_query AND (EXISTS (SELECT 1 FROM api.task_assignees" +
+			" WHERE task_id = tasks.id AND user_id = $2)",
+		"OR (tasks.assigned_user_id IS NULL AND tasks.status <> 'COMPLETED' AND (tasks.project_id IS NULL" +
+			" OR EXISTS (SELECT 1 FROM api.project_members WHERE project_id = tasks.project_id AND user_id = $2))))" +
+			" AND tasks.project_id = ANY($3)",
+		"LIMIT $4",
+		"StartSel=\uE000, StopSel=\uE001",
+	} {
+		if !strings.Contains(query, expected) {
+			t.Errorf("expected query to contain %q, got %q", expected, query)
+		}
+	}
+}
+
+func TestAnalyticsOptions_Queries(t *testing.T) {
+	from := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	opts := AnalyticsOptions{From: from, AnyAssigneeIDs: []int{3}, Statuses: []Status{StatusCompleted}}
+
+	query, args := opts.countQuery()
+	expected := "SELECT tasks.status, COUNT(*), COUNT(*) FILTER (WHERE tasks.status <> 'COMPLETED' AND tasks.due_date < NOW())" +
+		" FROM api.tasks WHERE EXISTS (SELECT 1 FROM api.task_assignees WHERE task_id = tasks.id AND user_id = ANY($1))" +
+		" AND tasks.status = ANY($2) AND tasks.created_at >= $3 GROUP BY tasks.status"
+	if query != expected {
+		t.Errorf("expected query %q, got %q", expected, query)
+	}
+	expectedArgs := []interface{}{pq.Array([]int{3}), pq.Array([]Status{StatusCompleted}), from}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("expected args %v, got %v", expectedArgs, args)
+	}
+
+	query, _ = opts.completionQuery()
+	if !strings.Contains(query, "AND tasks.completed_at >= $3 AND tasks.completed_at IS NOT NULL") {
+		t.Errorf("expected the completion of tasks to be bounded, got %q", query)
+	}
+
+	query, args = opts.throughputQuery()
+	for _, expected := range []string{
+		"generate_series(date_trunc('week', $4::timestamptz), date_trunc('week', (SELECT MAX(completed_at) FROM completed))",
+		"LEFT JOIN completed ON date_trunc('week', completed.completed_at) = weeks.week",
+	} {
+		if !strings.Contains(query, expected) {
+			t.Errorf("expected query to contain %q, got %q", expected, query)
+		}
+	}
+	if len(args) != 4 {
+		t.Errorf("expected 4 args, got %v", args)
+	}
+
+	cutoff := time.Date(2024, 3, 8, 17, 0, 0, 0, time.UTC)
+	opts.OverdueBefore = cutoff
+	query, args = opts.countQuery()
+	if !strings.Contains(query, "tasks.due_date < $4)") {
+		t.Errorf("expected the overdue cutoff to be a placeholder, got %q", query)
+	}
+	if len(args) != 4 || args[3] != cutoff {
+		t.Errorf("expected the overdue cutoff last, got %v", args)
+	}
+}
+
 func TestDB_Find(t *testing.T) {
 	pg, err := postgres.Connect(postgres.ConnectionString())
 	if err != nil {
@@ -114,3 +355,27 @@ func TestDB_UpdateStatus(t *testing.T) {
 		panic(err)
 	}
 }
+
+func TestImportValues(t *testing.T) {
+	due := time.Date(2030, 3, 8, 17, 30, 0, 0, time.UTC)
+	estimate := 90
+	values := importValues(7, Entry{Title: "Write report", DueDate: due, OriginalEstimate: &estimate})
+	expected := []interface{}{
+		7, "Write report", "", (*int)(nil), "PENDING", due, "MEDIUM",
+		pq.Array([]string{}), (*int)(nil), &estimate, &estimate,
+	}
+	if len(values) != len(importColumns) {
+		t.Fatalf("expected a value per column, got %d values for %d columns", len(values), len(importColumns))
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+}
+
+func TestEscapeHighlight(t *testing.T) {
+	got := escapeHighlight(`<img src=x onerror="alert(1)"> ` + highlightStart + "report" + highlightStop + " & co")
+	expected := `&lt;img src=x onerror=&#34;alert(1)&#34;&gt; <mark>report</mark> &amp; co`
+	if got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
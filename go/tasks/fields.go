@@ -0,0 +1,178 @@
+package tasks
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// nullableColumns receives the columns that may be NULL while scanning a task.
+type nullableColumns struct {
+	assignedUserID    sql.NullInt64
+	assignedUsername  sql.NullString
+	projectID         sql.NullInt64
+	parentTaskID      sql.NullInt64
+	recurringTaskID   sql.NullInt64
+	occurrenceAt      sql.NullTime
+	originalEstimate  sql.NullInt64
+	remainingEstimate sql.NullInt64
+}
+
+// fieldColumns maps the JSON fields of tasks to the columns they are loaded
+// from. The other fields are loaded by separate queries.
+var fieldColumns = map[string]DBColumn{
+	"id":                IDCol,
+	"title":             TitleCol,
+	"description":       DescriptionCol,
+	"assignedUserId":    AssignedUserIDCol,
+	"assignedUsername":  AssignedUsernameCol,
+	"status":            StatusCol,
+	"createdAt":         CreatedAtCol,
+	"dueDate":           DueDateCol,
+	"priority":          PriorityCol,
+	"labels":            LabelsCol,
+	"projectId":         ProjectIDCol,
+	"boardRank":         BoardRankCol,
+	"parentTaskId":      ParentTaskIDCol,
+	"recurringTaskId":   RecurringTaskIDCol,
+	"occurrenceAt":      OccurrenceAtCol,
+	"originalEstimate":  OriginalEstimateCol,
+	"remainingEstimate": RemainingEstimateCol,
+}
+
+var attachedFields = []string{"assignees", "watchers", "checklist", "customFields"}
+
+// ParseFields parses a comma-separated list of the JSON fields of tasks, e.g.
+// id,title,status.
+func ParseFields(str string) ([]string, error) {
+	var fields []string
+	for _, field := range strings.Split(str, ",") {
+		field = strings.TrimSpace(field)
+		if _, ok := fieldColumns[field]; !ok && !contains(attachedFields, field) {
+			return nil, fmt.Errorf("invalid field: %s", field)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// Select returns the given JSON fields of the entry only, all of them when
+// fields is empty.
+func (e Entry) Select(fields []string) (map[string]json.RawMessage, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return all, nil
+	}
+	selected := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if value, ok := all[field]; ok {
+			selected[field] = value
+		}
+	}
+	return selected, nil
+}
+
+// columns returns the columns to select, in the order of allColumns.
+func (opt FindOptions) columns() []DBColumn {
+	if len(opt.Fields) == 0 {
+		return append(allColumns, AssignedUsernameCol)
+	}
+	cols := []DBColumn{IDCol}
+	for _, col := range append(allColumns, AssignedUsernameCol) {
+		if col == IDCol {
+			continue
+		}
+		for _, field := range opt.Fields {
+			if fieldColumns[field] == col {
+				cols = append(cols, col)
+				break
+			}
+		}
+	}
+	return cols
+}
+
+// loads tells whether the JSON field of tasks is loaded.
+func (opt FindOptions) loads(field string) bool {
+	return len(opt.Fields) == 0 || contains(opt.Fields, field)
+}
+
+// dest returns where to scan col into.
+func (e *Entry) dest(col DBColumn, nullable *nullableColumns) interface{} {
+	switch col {
+	case IDCol:
+		return &e.ID
+	case TitleCol:
+		return &e.Title
+	case DescriptionCol:
+		return &e.Description
+	case AssignedUserIDCol:
+		return &nullable.assignedUserID
+	case AssignedUsernameCol:
+		return &nullable.assignedUsername
+	case StatusCol:
+		return &e.Status
+	case CreatedAtCol:
+		return &e.CreatedAt
+	case DueDateCol:
+		return &e.DueDate
+	case PriorityCol:
+		return &e.Priority
+	case LabelsCol:
+		return pq.Array(&e.Labels)
+	case ProjectIDCol:
+		return &nullable.projectID
+	case BoardRankCol:
+		return &e.BoardRank
+	case ParentTaskIDCol:
+		return &nullable.parentTaskID
+	case RecurringTaskIDCol:
+		return &nullable.recurringTaskID
+	case OccurrenceAtCol:
+		return &nullable.occurrenceAt
+	case OriginalEstimateCol:
+		return &nullable.originalEstimate
+	case RemainingEstimateCol:
+		return &nullable.remainingEstimate
+	}
+	panic(fmt.Sprintf("unknown column %s", col))
+}
+
+func (n nullableColumns) apply(e *Entry) {
+	// unassigned tasks have neither
+	e.AssignedUserID = int(n.assignedUserID.Int64)
+	e.AssignedUsername = n.assignedUsername.String
+	e.ProjectID = int(n.projectID.Int64)
+	e.ParentTaskID = int(n.parentTaskID.Int64)
+	e.RecurringTaskID = int(n.recurringTaskID.Int64)
+	if n.occurrenceAt.Valid {
+		e.OccurrenceAt = &n.occurrenceAt.Time
+	}
+	if n.originalEstimate.Valid {
+		minutes := int(n.originalEstimate.Int64)
+		e.OriginalEstimate = &minutes
+	}
+	if n.remainingEstimate.Valid {
+		minutes := int(n.remainingEstimate.Int64)
+		e.RemainingEstimate = &minutes
+	}
+}
+
+func contains(list []string, str string) bool {
+	for _, s := range list {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
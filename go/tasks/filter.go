@@ -0,0 +1,578 @@
+package tasks
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// A filter expression combines conditions on the fields of tasks with and, or,
+// not and parentheses, e.g.
+//
+//	status in (PENDING, IN_PROGRESS) and (due_date < now() + 3d or title ~ "deploy")
+//
+// Conditions compare a field to values with =, !=, <, <=, >, >=, ~ (contains,
+// ignoring case), !~, in (...), not in (...), is null and is not null. Values
+// are "strings" or 'strings', integers, words such as PENDING, and now()
+// optionally plus or minus a duration in s, m, h, d or w.
+
+type (
+	// FilterExpr is a parsed filter expression, see ParseFilter.
+	FilterExpr interface {
+		clause(args []interface{}) (string, []interface{})
+	}
+
+	FilterAnd struct {
+		Left, Right FilterExpr
+	}
+
+	FilterOr struct {
+		Left, Right FilterExpr
+	}
+
+	FilterNot struct {
+		Expr FilterExpr
+	}
+
+	// FilterCondition compares the field of tasks named Field with Values.
+	FilterCondition struct {
+		Field  string
+		Op     FilterOp
+		Values []FilterValue
+	}
+
+	// FilterValue is either a literal Value, a string, an int, a Status or a
+	// Priority, or the time of the query plus Offset when Now is set.
+	FilterValue struct {
+		Value  interface{}
+		Now    bool
+		Offset time.Duration
+	}
+
+	// FilterError tells what is wrong with a filter expression and where, Pos
+	// being the 1-based position of the offending character.
+	FilterError struct {
+		Pos int
+		Msg string
+	}
+
+	fieldKind int
+
+	filterField struct {
+		expr     string
+		kind     fieldKind
+		nullable bool
+	}
+)
+
+const (
+	OpNotContains FilterOp = "not contains"
+	OpIn          FilterOp = "in"
+	OpNotIn       FilterOp = "not in"
+	OpIsNull      FilterOp = "is null"
+	OpIsNotNull   FilterOp = "is not null"
+)
+
+const (
+	kindText fieldKind = iota
+	kindInt
+	kindTime
+	kindStatus
+	kindPriority
+	kindLabels
+)
+
+// maxFilterDepth bounds the nesting of filter expressions.
+const maxFilterDepth = 32
+
+var filterFields = map[string]filterField{
+	"id":                 {"tasks.id", kindInt, false},
+	"title":              {"tasks.title", kindText, false},
+	"description":        {"tasks.description", kindText, true},
+	"status":             {"tasks.status", kindStatus, false},
+	"priority":           {"tasks.priority", kindPriority, false},
+	"labels":             {"tasks.labels", kindLabels, false},
+	"due_date":           {"tasks.due_date", kindTime, true},
+	"created_at":         {"tasks.created_at", kindTime, false},
+	"assigned_user_id":   {"tasks.assigned_user_id", kindInt, true},
+	"project_id":         {"tasks.project_id", kindInt, true},
+	"parent_task_id":     {"tasks.parent_task_id", kindInt, true},
+	"recurring_task_id":  {"tasks.recurring_task_id", kindInt, true},
+	"original_estimate":  {"tasks.original_estimate", kindInt, true},
+	"remaining_estimate": {"tasks.remaining_estimate", kindInt, true},
+}
+
+// comparisonOps maps the comparison operators of the language to FilterOps.
+var comparisonOps = map[string]FilterOp{
+	"=":  OpEq,
+	"!=": OpNe,
+	"<>": OpNe,
+	"<":  OpLt,
+	"<=": OpLte,
+	">":  OpGt,
+	">=": OpGte,
+	"~":  OpContains,
+	"!~": OpNotContains,
+}
+
+// kindOps lists the operators each kind of field supports, besides is null.
+var kindOps = map[fieldKind][]FilterOp{
+	kindText:     {OpEq, OpNe, OpContains, OpNotContains, OpIn, OpNotIn},
+	kindInt:      {OpEq, OpNe, OpLt, OpLte, OpGt, OpGte, OpIn, OpNotIn},
+	kindTime:     {OpEq, OpNe, OpLt, OpLte, OpGt, OpGte},
+	kindStatus:   {OpEq, OpNe, OpLt, OpLte, OpGt, OpGte, OpIn, OpNotIn},
+	kindPriority: {OpEq, OpNe, OpLt, OpLte, OpGt, OpGte, OpIn, OpNotIn},
+	kindLabels:   {OpEq, OpNe, OpIn, OpNotIn},
+}
+
+var durationUnits = map[byte]time.Duration{
+	's': time.Second,
+	'm': time.Minute,
+	'h': time.Hour,
+	'd': 24 * time.Hour,
+	'w': 7 * 24 * time.Hour,
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
+
+// ParseFilter parses a filter expression. Errors are *FilterError.
+func ParseFilter(str string) (FilterExpr, error) {
+	tokens, err := lexFilter(str)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	expr, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorf(tok, "unexpected %s", tok)
+	}
+	return expr, nil
+}
+
+type (
+	tokenKind int
+
+	filterToken struct {
+		kind tokenKind
+		text string
+		pos  int
+	}
+
+	filterParser struct {
+		tokens []filterToken
+		next   int
+	}
+)
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenDuration
+	tokenString
+	tokenOperator
+	tokenPunct
+)
+
+func (t filterToken) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of filter"
+	case tokenString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// is tells whether t is the punctuation, operator or keyword text, keywords
+// being case-insensitive.
+func (t filterToken) is(text string) bool {
+	if t.kind == tokenIdent {
+		return strings.EqualFold(t.text, text)
+	}
+	return (t.kind == tokenPunct || t.kind == tokenOperator) && t.text == text
+}
+
+func lexFilter(str string) ([]filterToken, error) {
+	var tokens []filterToken
+	for i := 0; i < len(str); {
+		c := str[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c == '(' || c == ')' || c == ',' || c == '+' || c == '-':
+			i++
+			tokens = append(tokens, filterToken{tokenPunct, string(c), start + 1})
+		case strings.ContainsRune("=!<>~", rune(c)):
+			for i < len(str) && strings.ContainsRune("=!<>~", rune(str[i])) {
+				i++
+			}
+			op := str[start:i]
+			if _, ok := comparisonOps[op]; !ok {
+				return nil, &FilterError{Pos: start + 1, Msg: fmt.Sprintf("unknown operator %q", op)}
+			}
+			tokens = append(tokens, filterToken{tokenOperator, op, start + 1})
+		case c == '"' || c == '\'':
+			var sb strings.Builder
+			i++
+			for ; i < len(str) && str[i] != c; i++ {
+				if str[i] == '\\' && i+1 < len(str) {
+					i++
+				}
+				sb.WriteByte(str[i])
+			}
+			if i == len(str) {
+				return nil, &FilterError{Pos: start + 1, Msg: "unterminated string"}
+			}
+			i++
+			tokens = append(tokens, filterToken{tokenString, sb.String(), start + 1})
+		case c >= '0' && c <= '9':
+			for i < len(str) && str[i] >= '0' && str[i] <= '9' {
+				i++
+			}
+			kind := tokenNumber
+			if i < len(str) && isIdentByte(str[i]) {
+				if _, ok := durationUnits[str[i]]; !ok || (i+1 < len(str) && isIdentByte(str[i+1])) {
+					return nil, &FilterError{Pos: start + 1, Msg: "invalid number or duration"}
+				}
+				i++
+				kind = tokenDuration
+			}
+			tokens = append(tokens, filterToken{kind, str[start:i], start + 1})
+		case isIdentByte(c):
+			for i < len(str) && (isIdentByte(str[i]) || str[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, filterToken{tokenIdent, str[start:i], start + 1})
+		default:
+			return nil, &FilterError{Pos: start + 1, Msg: fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+	return append(tokens, filterToken{kind: tokenEOF, pos: len(str) + 1}), nil
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.next]
+}
+
+func (p *filterParser) advance() filterToken {
+	tok := p.tokens[p.next]
+	if tok.kind != tokenEOF {
+		p.next++
+	}
+	return tok
+}
+
+func (p *filterParser) expect(text string) error {
+	if tok := p.advance(); !tok.is(text) {
+		return p.errorf(tok, "expected %q, got %s", text, tok)
+	}
+	return nil
+}
+
+func (p *filterParser) errorf(tok filterToken, format string, args ...interface{}) *FilterError {
+	return &FilterError{Pos: tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *filterParser) parseOr(depth int) (FilterExpr, error) {
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("or") {
+		p.advance()
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = FilterOr{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd(depth int) (FilterExpr, error) {
+	left, err := p.parseUnary(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("and") {
+		p.advance()
+		right, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = FilterAnd{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary(depth int) (FilterExpr, error) {
+	tok := p.peek()
+	if depth > maxFilterDepth {
+		return nil, p.errorf(tok, "filter is nested too deeply")
+	}
+	switch {
+	case tok.is("not"):
+		p.advance()
+		expr, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return FilterNot{expr}, nil
+	case tok.is("("):
+		p.advance()
+		expr, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+	return p.parseCondition()
+}
+
+func (p *filterParser) parseCondition() (FilterExpr, error) {
+	fieldTok := p.advance()
+	if fieldTok.kind != tokenIdent {
+		return nil, p.errorf(fieldTok, "expected a field, got %s", fieldTok)
+	}
+	name := strings.ToLower(fieldTok.text)
+	field, ok := filterFields[name]
+	if !ok {
+		return nil, p.errorf(fieldTok, "unknown field %q", fieldTok.text)
+	}
+
+	opTok := p.advance()
+	cond := FilterCondition{Field: name}
+	switch {
+	case opTok.kind == tokenOperator:
+		cond.Op = comparisonOps[opTok.text]
+	case opTok.is("in"):
+		cond.Op = OpIn
+	case opTok.is("not"):
+		if err := p.expect("in"); err != nil {
+			return nil, err
+		}
+		cond.Op = OpNotIn
+	case opTok.is("is"):
+		cond.Op = OpIsNull
+		if p.peek().is("not") {
+			p.advance()
+			cond.Op = OpIsNotNull
+		}
+		if err := p.expect("null"); err != nil {
+			return nil, err
+		}
+		if !field.nullable {
+			return nil, p.errorf(opTok, "%s is never null", name)
+		}
+		return cond, nil
+	default:
+		return nil, p.errorf(opTok, "expected an operator, got %s", opTok)
+	}
+	if !supports(field.kind, cond.Op) {
+		return nil, p.errorf(opTok, "operator %q is not supported for %s", opTok.text, name)
+	}
+
+	if cond.Op != OpIn && cond.Op != OpNotIn {
+		value, err := p.parseValue(field.kind)
+		if err != nil {
+			return nil, err
+		}
+		cond.Values = []FilterValue{value}
+		return cond, nil
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	for {
+		value, err := p.parseValue(field.kind)
+		if err != nil {
+			return nil, err
+		}
+		cond.Values = append(cond.Values, value)
+		if !p.peek().is(",") {
+			break
+		}
+		p.advance()
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return cond, nil
+}
+
+func supports(kind fieldKind, op FilterOp) bool {
+	for _, supported := range kindOps[kind] {
+		if op == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// parseValue parses a value and checks that it suits the kind of field.
+func (p *filterParser) parseValue(kind fieldKind) (FilterValue, error) {
+	tok := p.advance()
+	switch kind {
+	case kindText, kindLabels:
+		if tok.kind == tokenString || (kind == kindLabels && tok.kind == tokenIdent) {
+			return FilterValue{Value: tok.text}, nil
+		}
+		return FilterValue{}, p.errorf(tok, "expected a string, got %s", tok)
+	case kindInt:
+		if tok.kind == tokenNumber {
+			n, err := strconv.Atoi(tok.text)
+			if err != nil {
+				return FilterValue{}, p.errorf(tok, "invalid number %s", tok)
+			}
+			return FilterValue{Value: n}, nil
+		}
+		return FilterValue{}, p.errorf(tok, "expected a number, got %s", tok)
+	case kindStatus, kindPriority:
+		if tok.kind != tokenIdent && tok.kind != tokenString {
+			return FilterValue{}, p.errorf(tok, "expected a word, got %s", tok)
+		}
+		if kind == kindStatus {
+			status, err := ParseStatus(tok.text)
+			if err != nil {
+				return FilterValue{}, p.errorf(tok, "invalid status %s", tok)
+			}
+			return FilterValue{Value: status}, nil
+		}
+		priority, err := ParsePriority(tok.text)
+		if err != nil {
+			return FilterValue{}, p.errorf(tok, "invalid priority %s", tok)
+		}
+		return FilterValue{Value: priority}, nil
+	}
+
+	// times are either strings or relative to now
+	if tok.kind == tokenString {
+		for _, layout := range []string{time.RFC3339, time.DateOnly} {
+			if t, err := time.Parse(layout, tok.text); err == nil {
+				return FilterValue{Value: t}, nil
+			}
+		}
+		return FilterValue{}, p.errorf(tok, "invalid time %s, expected RFC3339 or YYYY-MM-DD", tok)
+	}
+	if !tok.is("now") {
+		return FilterValue{}, p.errorf(tok, "expected a time or now(), got %s", tok)
+	}
+	if err := p.expect("("); err != nil {
+		return FilterValue{}, err
+	}
+	if err := p.expect(")"); err != nil {
+		return FilterValue{}, err
+	}
+	value := FilterValue{Now: true}
+	if sign := p.peek(); sign.is("+") || sign.is("-") {
+		p.advance()
+		durationTok := p.advance()
+		if durationTok.kind != tokenDuration {
+			return FilterValue{}, p.errorf(durationTok, "expected a duration such as 3d, got %s", durationTok)
+		}
+		n, err := strconv.Atoi(durationTok.text[:len(durationTok.text)-1])
+		if err != nil {
+			return FilterValue{}, p.errorf(durationTok, "invalid duration %s", durationTok)
+		}
+		value.Offset = time.Duration(n) * durationUnits[durationTok.text[len(durationTok.text)-1]]
+		if sign.is("-") {
+			value.Offset = -value.Offset
+		}
+	}
+	return value, nil
+}
+
+func (f FilterAnd) clause(args []interface{}) (string, []interface{}) {
+	left, args := f.Left.clause(args)
+	right, args := f.Right.clause(args)
+	return fmt.Sprintf("(%s AND %s)", left, right), args
+}
+
+func (f FilterOr) clause(args []interface{}) (string, []interface{}) {
+	left, args := f.Left.clause(args)
+	right, args := f.Right.clause(args)
+	return fmt.Sprintf("(%s OR %s)", left, right), args
+}
+
+func (f FilterNot) clause(args []interface{}) (string, []interface{}) {
+	expr, args := f.Expr.clause(args)
+	return fmt.Sprintf("NOT (%s)", expr), args
+}
+
+// clause compiles the condition, its values all being passed as arguments.
+func (f FilterCondition) clause(args []interface{}) (string, []interface{}) {
+	field := filterFields[f.Field]
+	switch f.Op {
+	case OpIsNull:
+		return fmt.Sprintf("%s IS NULL", field.expr), args
+	case OpIsNotNull:
+		return fmt.Sprintf("%s IS NOT NULL", field.expr), args
+	case OpIn, OpNotIn:
+		args = append(args, f.array())
+		switch {
+		case field.kind == kindLabels && f.Op == OpIn:
+			return fmt.Sprintf("%s && $%d::text[]", field.expr, len(args)), args
+		case field.kind == kindLabels:
+			return fmt.Sprintf("NOT (%s && $%d::text[])", field.expr, len(args)), args
+		case f.Op == OpIn:
+			return fmt.Sprintf("%s = ANY($%d)", field.expr, len(args)), args
+		}
+		return fmt.Sprintf("%s <> ALL($%d)", field.expr, len(args)), args
+	}
+
+	value := f.Values[0]
+	if value.Now {
+		args = append(args, int64(value.Offset.Seconds()))
+		return fmt.Sprintf("%s %s NOW() + $%d * INTERVAL '1 second'", field.expr, sqlOperators[f.Op], len(args)), args
+	}
+	switch {
+	case f.Op == OpContains:
+		args = append(args, "%"+escapeLike(value.Value.(string))+"%")
+		return fmt.Sprintf("%s ILIKE $%d", field.expr, len(args)), args
+	case f.Op == OpNotContains:
+		args = append(args, "%"+escapeLike(value.Value.(string))+"%")
+		return fmt.Sprintf("%s NOT ILIKE $%d", field.expr, len(args)), args
+	case field.kind == kindLabels && f.Op == OpEq:
+		args = append(args, value.Value)
+		return fmt.Sprintf("$%d = ANY(%s)", len(args), field.expr), args
+	case field.kind == kindLabels:
+		args = append(args, value.Value)
+		return fmt.Sprintf("NOT ($%d = ANY(%s))", len(args), field.expr), args
+	}
+	args = append(args, value.Value)
+	return fmt.Sprintf("%s %s $%d", field.expr, sqlOperators[f.Op], len(args)), args
+}
+
+// array returns the values of an in condition as an array argument.
+func (f FilterCondition) array() interface{} {
+	if filterFields[f.Field].kind == kindInt {
+		ints := make([]int, 0, len(f.Values))
+		for _, v := range f.Values {
+			ints = append(ints, v.Value.(int))
+		}
+		return pq.Array(ints)
+	}
+	strs := make([]string, 0, len(f.Values))
+	for _, v := range f.Values {
+		strs = append(strs, fmt.Sprint(v.Value))
+	}
+	return pq.Array(strs)
+}
@@ -0,0 +1,91 @@
+package tasks
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestParseFilter(t *testing.T) {
+	cases := []struct {
+		name   string
+		filter string
+		clause string
+		args   []interface{}
+	}{
+		{
+			name:   "in, or, nested",
+			filter: `status in (PENDING, in_progress) and (due_date < now() + 3d or title ~ "deploy")`,
+			clause: "(tasks.status = ANY($1) AND (tasks.due_date < NOW() + $2 * INTERVAL '1 second' OR tasks.title ILIKE $3))",
+			args:   []interface{}{pq.Array([]string{"PENDING", "IN_PROGRESS"}), int64(259200), "%deploy%"},
+		},
+		{
+			name:   "precedence",
+			filter: "priority >= HIGH or assigned_user_id = 4 and not project_id in (1, 2)",
+			clause: "(tasks.priority >= $1 OR (tasks.assigned_user_id = $2 AND NOT (tasks.project_id = ANY($3))))",
+			args:   []interface{}{PriorityHigh, 4, pq.Array([]int{1, 2})},
+		},
+		{
+			name:   "null, labels, dates",
+			filter: `DUE_DATE is not null and labels = 'ops' and labels not in (a, b) and created_at >= "2024-01-01"`,
+			clause: "(((tasks.due_date IS NOT NULL AND $1 = ANY(tasks.labels)) AND NOT (tasks.labels && $2::text[]))" +
+				" AND tasks.created_at >= $3)",
+			args: []interface{}{"ops", pq.Array([]string{"a", "b"}), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:   "escaped string, not contains, past",
+			filter: `description !~ "50%\"" and due_date > now() - 1w`,
+			clause: "(tasks.description NOT ILIKE $1 AND tasks.due_date > NOW() + $2 * INTERVAL '1 second')",
+			args:   []interface{}{`%50\%"%`, int64(-604800)},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, err := ParseFilter(tc.filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			clause, args := expr.clause(nil)
+			if clause != tc.clause {
+				t.Errorf("expected clause %q, got %q", tc.clause, clause)
+			}
+			if !reflect.DeepEqual(args, tc.args) {
+				t.Errorf("expected args %v, got %v", tc.args, args)
+			}
+		})
+	}
+}
+
+func TestParseFilter_Errors(t *testing.T) {
+	cases := []struct {
+		filter string
+		err    string
+	}{
+		{"", "expected a field, got end of filter at position 1"},
+		{"owner = 3", `unknown field "owner" at position 1`},
+		{"status = DONE", `invalid status "DONE" at position 10`},
+		{"status ~ PENDING", `operator "~" is not supported for status at position 8`},
+		{"title = 3", `expected a string, got "3" at position 9`},
+		{`title = "deploy`, "unterminated string at position 9"},
+		{"(id = 1 or id = 2", "expected \")\", got end of filter at position 18"},
+		{"id = 1 id = 2", `unexpected "id" at position 8`},
+		{"title is null", "title is never null at position 7"},
+		{"due_date < now() + 3y", "invalid number or duration at position 20"},
+		{"id == 1", `unknown operator "==" at position 4`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.filter, func(t *testing.T) {
+			_, err := ParseFilter(tc.filter)
+			var filterErr *FilterError
+			if !errors.As(err, &filterErr) {
+				t.Fatalf("expected a FilterError, got %v", err)
+			}
+			if err.Error() != tc.err {
+				t.Errorf("expected %q, got %q", tc.err, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// importColumns are the columns of api.tasks copied on import.
+var importColumns = []string{
+	"id", "title", "description", "assigned_user_id", "status", "due_date", "priority", "labels", "project_id",
+	"original_estimate", "remaining_estimate",
+}
+
+// Import inserts the entries at once with COPY, in a single transaction: either
+// all of them are inserted or none. The IDs are reserved up front so that they
+// can be returned in the order of the entries, which COPY cannot do.
+func Import(ctx context.Context, pg *sql.DB, entries []Entry) (ids []int, err error) {
+	for i, entry := range entries {
+		if err := entry.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid entry %d: %w", i+1, err)
+		}
+	}
+	if len(entries) == 0 {
+		return []int{}, nil
+	}
+
+	tx, err := pg.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	rows, err := tx.QueryContext(ctx,
+		"SELECT nextval(pg_get_serial_sequence('api.tasks', 'id')) FROM generate_series(1, $1)", len(entries),
+	)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyInSchema("api", "tasks", importColumns...))
+	if err != nil {
+		return nil, err
+	}
+	for i, entry := range entries {
+		if _, err = stmt.ExecContext(ctx, importValues(ids[i], entry)...); err != nil {
+			_ = stmt.Close()
+			return nil, err
+		}
+	}
+	// the rows are only sent to the server once the copy is flushed
+	if _, err = stmt.ExecContext(ctx); err != nil {
+		_ = stmt.Close()
+		return nil, err
+	}
+	if err = stmt.Close(); err != nil {
+		return nil, err
+	}
+	return ids, tx.Commit()
+}
+
+// importValues returns the values of importColumns for the entry, defaulted
+// the same way as Insert does.
+func importValues(id int, entry Entry) []interface{} {
+	var assignedUserID, projectID *int
+	if entry.AssignedUserID > 0 {
+		assignedUserID = &entry.AssignedUserID
+	}
+	if entry.ProjectID > 0 {
+		projectID = &entry.ProjectID
+	}
+	status := StatusPending
+	if entry.Status != "" {
+		status = entry.Status
+	}
+	priority := PriorityMedium
+	if entry.Priority != "" {
+		priority = entry.Priority
+	}
+	labels := entry.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+	remainingEstimate := entry.RemainingEstimate
+	if remainingEstimate == nil {
+		remainingEstimate = entry.OriginalEstimate
+	}
+	return []interface{}{
+		id, entry.Title, entry.Description, assignedUserID, string(status), entry.DueDate, string(priority),
+		pq.Array(labels), projectID, entry.OriginalEstimate, remainingEstimate,
+	}
+}
@@ -0,0 +1,155 @@
+package tasks
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type (
+	// Cursor points right after a task in a listing sorted a given way, so that
+	// the next page starts from there however the tasks change in between. It
+	// travels to clients as an opaque string.
+	Cursor struct {
+		// Sort identifies the keys the listing is sorted by and their order
+		Sort string `json:"s"`
+		// Values are the values of the sort keys for the task, as text, nil
+		// when it has none
+		Values []*string `json:"v,omitempty"`
+		ID     int       `json:"id"`
+	}
+
+	Page struct {
+		Entries []Entry
+		// NextCursor is empty on the last page
+		NextCursor string
+		// Total counts the tasks on all pages, when requested
+		Total *int
+	}
+)
+
+// ErrInvalidCursor is returned when a cursor is malformed or was handed out for
+// a listing sorted another way.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+func ParseCursor(str string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(str)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(b, &cursor); err != nil || cursor.ID <= 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+func (c Cursor) String() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// FindPage returns at most options.Limit tasks, starting after options.After,
+// along with the cursor of the next page. withTotal also counts the tasks
+// matching options across all pages.
+func (db *DB) FindPage(ctx context.Context, options FindOptions, withTotal bool) (Page, error) {
+	if options.Limit <= 0 {
+		return Page{}, errors.New("invalid limit")
+	}
+	limit := options.Limit
+	// fetching one more task tells whether there is a next page
+	options.Limit++
+	entries, sortValues, err := db.find(ctx, options)
+	if err != nil {
+		return Page{}, err
+	}
+
+	page := Page{Entries: entries}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		keys, _ := options.sortKeys(nil)
+		cursor := Cursor{Sort: sortName(keys, options.idOrder()), ID: entries[limit-1].ID}
+		for _, value := range sortValues[limit-1] {
+			if value.Valid {
+				cursor.Values = append(cursor.Values, &value.String)
+			} else {
+				cursor.Values = append(cursor.Values, nil)
+			}
+		}
+		page.NextCursor = cursor.String()
+	}
+	if page.Entries == nil {
+		page.Entries = []Entry{}
+	}
+
+	if withTotal {
+		query, args := options.buildCountQuery()
+		var total int
+		if err := db.pg.QueryRowContext(ctx, query, args...).Scan(&total); err != nil {
+			return Page{}, err
+		}
+		page.Total = &total
+	}
+	return page, nil
+}
+
+// paged tells whether the tasks are fetched a page at a time, in which case
+// they are always sorted by ID last so that the order is stable.
+func (opt FindOptions) paged() bool {
+	return opt.Limit > 0 || opt.After != nil
+}
+
+// afterClause returns the SQL condition matching the tasks that come after the
+// cursor in a listing sorted by keys, appending its arguments: the tasks with
+// the same values for the first keys and a value coming after for the next
+// one, or the same values for all keys and an ID coming after. Tasks without
+// a value come last whatever the order.
+func (c Cursor) afterClause(keys []sortKey, idOrder SortOrder, args []interface{}) (string, []interface{}) {
+	var (
+		after []string
+		equal []string
+	)
+	for i, key := range keys {
+		value := c.Values[i]
+		if value == nil {
+			// nothing comes after a missing value but tasks missing it too
+			equal = append(equal, fmt.Sprintf("%s IS NULL", key.expr))
+			continue
+		}
+		args = append(args, *value)
+		later := fmt.Sprintf("(%s %s $%d OR %s IS NULL)", key.expr, comparator(key.order), len(args), key.expr)
+		after = append(after, conjunction(append(equal, later)))
+		equal = append(equal, fmt.Sprintf("%s = $%d", key.expr, len(args)))
+	}
+	args = append(args, c.ID)
+	after = append(after, conjunction(append(equal, fmt.Sprintf("tasks.id %s $%d", comparator(idOrder), len(args)))))
+	return "(" + strings.Join(after, " OR ") + ")", args
+}
+
+func comparator(order SortOrder) string {
+	if order == SortOrderDescending {
+		return "<"
+	}
+	return ">"
+}
+
+func conjunction(conditions []string) string {
+	if len(conditions) == 1 {
+		return conditions[0]
+	}
+	return "(" + strings.Join(conditions, " AND ") + ")"
+}
+
+func (opt FindOptions) checkCursor() error {
+	if opt.After == nil {
+		return nil
+	}
+	keys, _ := opt.sortKeys(nil)
+	if opt.After.Sort != sortName(keys, opt.idOrder()) || len(opt.After.Values) != len(keys) {
+		return ErrInvalidCursor
+	}
+	return nil
+}
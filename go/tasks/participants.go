@@ -0,0 +1,107 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// ErrPrimaryAssignee is returned when trying to remove the primary assignee of
+// a task, which can only be replaced by reassigning the task.
+var ErrPrimaryAssignee = errors.New("cannot remove the primary assignee")
+
+// AddAssignee shares the task with userID, next to its primary assignee.
+func (db *DB) AddAssignee(ctx context.Context, taskID, userID int) error {
+	_, err := db.pg.ExecContext(ctx,
+		"INSERT INTO api.task_assignees (task_id,user_id,is_primary) VALUES ($1, $2, FALSE)"+
+			" ON CONFLICT (task_id, user_id) DO NOTHING",
+		taskID, userID,
+	)
+	return err
+}
+
+func (db *DB) RemoveAssignee(ctx context.Context, taskID, userID int) error {
+	var isPrimary bool
+	if err := db.pg.QueryRowContext(ctx,
+		"SELECT is_primary FROM api.task_assignees WHERE task_id = $1 AND user_id = $2",
+		taskID, userID,
+	).Scan(&isPrimary); err != nil {
+		return err
+	}
+	if isPrimary {
+		return ErrPrimaryAssignee
+	}
+	_, err := db.pg.ExecContext(ctx,
+		"DELETE FROM api.task_assignees WHERE task_id = $1 AND user_id = $2 AND NOT is_primary",
+		taskID, userID,
+	)
+	return err
+}
+
+func (db *DB) AddWatcher(ctx context.Context, taskID, userID int) error {
+	_, err := db.pg.ExecContext(ctx,
+		"INSERT INTO api.task_watchers (task_id,user_id) VALUES ($1, $2) ON CONFLICT (task_id, user_id) DO NOTHING",
+		taskID, userID,
+	)
+	return err
+}
+
+func (db *DB) RemoveWatcher(ctx context.Context, taskID, userID int) error {
+	var deletedID int
+	return db.pg.QueryRowContext(ctx,
+		"DELETE FROM api.task_watchers WHERE task_id = $1 AND user_id = $2 RETURNING user_id",
+		taskID, userID,
+	).Scan(&deletedID)
+}
+
+func (db *DB) attachParticipants(ctx context.Context, entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	ids := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	rows, err := db.pg.QueryContext(ctx, `
+		SELECT participants.task_id, participants.watcher, users.id, users.username, participants.is_primary
+		FROM (
+			SELECT task_id, user_id, is_primary, FALSE AS watcher FROM api.task_assignees WHERE task_id = ANY($1)
+			UNION ALL
+			SELECT task_id, user_id, FALSE, TRUE FROM api.task_watchers WHERE task_id = ANY($1)
+		) participants
+		JOIN auth.users ON users.id = participants.user_id
+		ORDER BY participants.task_id, participants.is_primary DESC, users.id`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	assignees := make(map[int][]Participant)
+	watchers := make(map[int][]Participant)
+	for rows.Next() {
+		var (
+			taskID  int
+			watcher bool
+			p       Participant
+		)
+		if err := rows.Scan(&taskID, &watcher, &p.UserID, &p.Username, &p.Primary); err != nil {
+			return err
+		}
+		if watcher {
+			watchers[taskID] = append(watchers[taskID], p)
+		} else {
+			assignees[taskID] = append(assignees[taskID], p)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i := range entries {
+		entries[i].Assignees = assignees[entries[i].ID]
+		entries[i].Watchers = watchers[entries[i].ID]
+	}
+	return nil
+}
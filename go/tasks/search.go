@@ -0,0 +1,146 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+type (
+	SearchOptions struct {
+		// Query is a web search style query: words, "quoted phrases", or and
+		// -excluded words
+		Query string
+		// VisibleTo restricts the results to the tasks the employee can list:
+		// the ones they are an assignee or a watcher of, and the backlog they
+		// can claim
+		VisibleTo  int
+		ProjectIDs []int
+		Limit      int
+	}
+
+	// SearchResult is a task matching a search along with how well it matches
+	// and its title and an excerpt of its description, matches highlighted.
+	SearchResult struct {
+		Entry
+		Rank           float64 `json:"rank"`
+		TitleHighlight string  `json:"titleHighlight"`
+		Snippet        string  `json:"snippet"`
+	}
+)
+
+// Matches are highlighted between HighlightStart and HighlightStop in search
+// results, the text around them being HTML-escaped so that highlights are safe
+// to render as HTML.
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+// highlightStart and highlightStop delimit matches in the text highlighted by
+// the database, until it is escaped. They are private use characters, which
+// task content has no business containing.
+const (
+	highlightStart = "\uE000"
+	highlightStop  = "\uE001"
+)
+
+var highlighter = strings.NewReplacer(highlightStart, HighlightStart, highlightStop, HighlightStop)
+
+// Search returns the tasks matching options.Query, best matches first.
+func (db *DB) Search(ctx context.Context, options SearchOptions) ([]SearchResult, error) {
+	query, args := options.buildQuery()
+	rows, err := db.pg.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		results []SearchResult
+		ids     []int
+	)
+	for rows.Next() {
+		var r SearchResult
+		if err := rows.Scan(&r.ID, &r.Rank, &r.TitleHighlight, &r.Snippet); err != nil {
+			return nil, err
+		}
+		r.TitleHighlight = escapeHighlight(r.TitleHighlight)
+		r.Snippet = escapeHighlight(r.Snippet)
+		results = append(results, r)
+		ids = append(ids, r.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return []SearchResult{}, nil
+	}
+
+	entries, err := db.Find(ctx, FindOptions{IDs: ids})
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]Entry, len(entries))
+	for _, entry := range entries {
+		byID[entry.ID] = entry
+	}
+	// tasks deleted in between are left out
+	found := results[:0]
+	for _, r := range results {
+		if entry, ok := byID[r.ID]; ok {
+			r.Entry = entry
+			found = append(found, r)
+		}
+	}
+	return found, nil
+}
+
+// buildQuery ranks the matching tasks first and only highlights the ones
+// returned, highlighting being the expensive part.
+func (opt SearchOptions) buildQuery() (query string, args []interface{}) {
+	args = []interface{}{opt.Query}
+	clauses := []string{"tasks.search_vector @@ search_query"}
+
+	if opt.VisibleTo > 0 {
+		args = append(args, opt.VisibleTo)
+		clauses = append(clauses, fmt.Sprintf(
+			"(EXISTS (SELECT 1 FROM api.task_assignees WHERE task_id = tasks.id AND user_id = $%[1]d)"+
+				" OR EXISTS (SELECT 1 FROM api.task_watchers WHERE task_id = tasks.id AND user_id = $%[1]d)"+
+				" OR (tasks.assigned_user_id IS NULL AND tasks.status <> 'COMPLETED' AND (tasks.project_id IS NULL"+
+				" OR EXISTS (SELECT 1 FROM api.project_members WHERE project_id = tasks.project_id AND user_id = $%[1]d))))",
+			len(args)))
+	}
+	if len(opt.ProjectIDs) > 0 {
+		args = append(args, pq.Array(opt.ProjectIDs))
+		clauses = append(clauses, fmt.Sprintf("tasks.project_id = ANY($%d)", len(args)))
+	}
+	args = append(args, opt.Limit)
+
+	options := fmt.Sprintf("StartSel=%s, StopSel=%s", highlightStart, highlightStop)
+	return fmt.Sprintf(`
+		SELECT matches.id, matches.rank,
+			ts_headline(api.search_language(), tasks.title, matches.search_query, '%[1]s, HighlightAll=true'),
+			ts_headline(api.search_language(), COALESCE(tasks.description, ''), matches.search_query,
+				'%[1]s, MaxFragments=2, MaxWords=20, MinWords=5')
+		FROM (
+			SELECT tasks.id, ts_rank_cd(tasks.search_vector, search_query) AS rank, search_query
+			FROM api.tasks, websearch_to_tsquery(api.search_language(), $1) search_query
+			WHERE %[2]s
+			ORDER BY rank DESC, tasks.id
+			LIMIT $%[3]d
+		) matches
+		JOIN api.tasks ON tasks.id = matches.id
+		ORDER BY matches.rank DESC, matches.id`,
+		options, strings.Join(clauses, " AND "), len(args),
+	), args
+}
+
+// escapeHighlight HTML-escapes text highlighted by the database, then turns
+// its highlight delimiters into HighlightStart and HighlightStop.
+func escapeHighlight(text string) string {
+	return highlighter.Replace(html.EscapeString(text))
+}
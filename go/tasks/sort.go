@@ -0,0 +1,120 @@
+package tasks
+
+import (
+	"fmt"
+	"strings"
+)
+
+type (
+	// SortKey sorts tasks by Column in Order. Tasks without a value for the
+	// column come last.
+	SortKey struct {
+		Column DBColumn
+		Order  SortOrder
+	}
+
+	// sortKey is a key tasks are sorted by once compiled: Sort keys, the custom
+	// field of SortByCustomField, or SortBy.
+	sortKey struct {
+		// name identifies the key in cursors
+		name  string
+		expr  string
+		order SortOrder
+	}
+)
+
+// ParseSort parses a comma-separated list of columns to sort by, in order,
+// each prefixed with - to sort in descending order, e.g. -due_date,title.
+func ParseSort(str string) ([]SortKey, error) {
+	var keys []SortKey
+	for _, part := range strings.Split(str, ",") {
+		part = strings.TrimSpace(part)
+		key := SortKey{Order: SortOrderAscending}
+		if strings.HasPrefix(part, "-") {
+			key.Order, part = SortOrderDescending, part[1:]
+		}
+		col, err := ParseDBColumn(part)
+		if err != nil {
+			return nil, err
+		}
+		key.Column = col
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// expr returns the SQL expression of the column, without its alias.
+func (col DBColumn) expr() string {
+	if col == "assigned_username" || col == AssignedUsernameCol {
+		// the alias of the column is only known to ORDER BY
+		return "users.username"
+	}
+	return col.String()
+}
+
+func (opt FindOptions) sorted() bool {
+	return len(opt.Sort) > 0 || opt.SortByCustomField != nil || opt.SortBy != ""
+}
+
+func (opt FindOptions) sortOrder() SortOrder {
+	if opt.SortOrder == SortOrderDescending {
+		return SortOrderDescending
+	}
+	return SortOrderAscending
+}
+
+// sortKeys returns the keys tasks are sorted by, appending their arguments.
+// Sort takes precedence over SortByCustomField, which takes precedence over
+// SortBy.
+func (opt FindOptions) sortKeys(args []interface{}) ([]sortKey, []interface{}) {
+	switch {
+	case len(opt.Sort) > 0:
+		keys := make([]sortKey, 0, len(opt.Sort))
+		for _, key := range opt.Sort {
+			order := SortOrderAscending
+			if key.Order == SortOrderDescending {
+				order = SortOrderDescending
+			}
+			keys = append(keys, sortKey{name: key.Column.String(), expr: key.Column.expr(), order: order})
+		}
+		return keys, args
+	case opt.SortByCustomField != nil:
+		expr, args := opt.SortByCustomField.expr(args)
+		return []sortKey{{name: CustomFieldPrefix + opt.SortByCustomField.Key, expr: expr, order: opt.sortOrder()}}, args
+	case opt.SortBy != "":
+		return []sortKey{{name: opt.SortBy.String(), expr: opt.SortBy.expr(), order: opt.sortOrder()}}, args
+	}
+	return nil, args
+}
+
+// idOrder is the order of the ID tiebreaker, the one of the first key.
+func (opt FindOptions) idOrder() SortOrder {
+	if len(opt.Sort) > 0 && opt.Sort[0].Order == SortOrderDescending {
+		return SortOrderDescending
+	}
+	if len(opt.Sort) > 0 {
+		return SortOrderAscending
+	}
+	return opt.sortOrder()
+}
+
+// orderBy returns the ORDER BY clause of keys, tasks without a value last and
+// the ID as a tiebreaker.
+func orderBy(keys []sortKey, idOrder SortOrder) string {
+	terms := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		terms = append(terms, fmt.Sprintf("%s %s NULLS LAST", key.expr, key.order))
+	}
+	terms = append(terms, fmt.Sprintf("tasks.id %s", idOrder))
+	return " ORDER BY " + strings.Join(terms, ", ")
+}
+
+// sortName identifies the sort of a listing in cursors.
+func sortName(keys []sortKey, idOrder SortOrder) string {
+	names := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		names = append(names, fmt.Sprintf("%s %s", key.name, key.order))
+	}
+	names = append(names, fmt.Sprintf("tasks.id %s", idOrder))
+	return strings.Join(names, ",")
+}
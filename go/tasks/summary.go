@@ -0,0 +1,90 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+)
+
+type (
+	// SummaryDrift is a row of the api.task_summaries read model that differed
+	// from the tasks it counts. ProjectID is 0 for the tasks without a project.
+	SummaryDrift struct {
+		UserID    int
+		ProjectID int
+		Stored    SummaryCounts
+		Actual    SummaryCounts
+	}
+
+	SummaryCounts struct {
+		Assigned  int
+		Completed int
+		Primary   int
+		Shared    int
+	}
+)
+
+// driftQuery compares the api.task_summaries read model with the tasks it
+// counts, returning the rows that differ.
+const driftQuery = `
+	SELECT
+		COALESCE(stored.user_id, actual.user_id),
+		COALESCE(stored.project_id, actual.project_id, 0),
+		COALESCE(stored.assigned, 0), COALESCE(stored.completed, 0),
+		COALESCE(stored.primary_assigned, 0), COALESCE(stored.shared, 0),
+		COALESCE(actual.assigned, 0), COALESCE(actual.completed, 0),
+		COALESCE(actual.primary_assigned, 0), COALESCE(actual.shared, 0)
+	FROM api.task_summaries stored
+	FULL JOIN api.compute_task_summaries(NULL) actual
+		ON actual.user_id = stored.user_id AND actual.project_id IS NOT DISTINCT FROM stored.project_id
+	WHERE (stored.assigned, stored.completed, stored.primary_assigned, stored.shared)
+		IS DISTINCT FROM (actual.assigned, actual.completed, actual.primary_assigned, actual.shared)
+	ORDER BY 1, 2`
+
+// ReconcileSummaries rebuilds the api.task_summaries read model from scratch
+// and returns the rows that had drifted from the tasks. Writes to the read
+// model wait for the rebuild to finish.
+func ReconcileSummaries(ctx context.Context, pg *sql.DB) (drifts []SummaryDrift, err error) {
+	tx, err := pg.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, "LOCK TABLE api.task_summaries IN EXCLUSIVE MODE"); err != nil {
+		return nil, err
+	}
+	rows, err := tx.QueryContext(ctx, driftQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d SummaryDrift
+		if err = rows.Scan(
+			&d.UserID, &d.ProjectID,
+			&d.Stored.Assigned, &d.Stored.Completed, &d.Stored.Primary, &d.Stored.Shared,
+			&d.Actual.Assigned, &d.Actual.Completed, &d.Actual.Primary, &d.Actual.Shared,
+		); err != nil {
+			return nil, err
+		}
+		drifts = append(drifts, d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM api.task_summaries"); err != nil {
+		return nil, err
+	}
+	if _, err = tx.ExecContext(ctx,
+		"INSERT INTO api.task_summaries (user_id, project_id, assigned, completed, primary_assigned, shared)"+
+			" SELECT * FROM api.compute_task_summaries(NULL)",
+	); err != nil {
+		return nil, err
+	}
+	return drifts, tx.Commit()
+}
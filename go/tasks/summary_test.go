@@ -0,0 +1,122 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"siransbach/taskmanagementapi/postgres"
+)
+
+// expectNoDrift fails when the api.task_summaries read model differs from
+// compute_task_summaries(NULL) after step.
+func expectNoDrift(t *testing.T, pg *sql.DB, step string) {
+	t.Helper()
+	rows, err := pg.QueryContext(context.Background(), driftQuery)
+	if err != nil {
+		panic(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID, projectID int
+		var stored, actual SummaryCounts
+		if err := rows.Scan(
+			&userID, &projectID,
+			&stored.Assigned, &stored.Completed, &stored.Primary, &stored.Shared,
+			&actual.Assigned, &actual.Completed, &actual.Primary, &actual.Shared,
+		); err != nil {
+			panic(err)
+		}
+		t.Errorf("after %s: user %d project %d stored %+v, expected %+v", step, userID, projectID, stored, actual)
+	}
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+}
+
+func TestDB_Summaries(t *testing.T) {
+	pg, err := postgres.Connect(postgres.ConnectionString())
+	if err != nil {
+		panic(err)
+	}
+	ctx, db := context.Background(), NewDB(pg)
+
+	var projectID int
+	if err := pg.QueryRowContext(ctx,
+		"INSERT INTO api.projects (name, owner_id) VALUES ('Summaries', 3) RETURNING id",
+	).Scan(&projectID); err != nil {
+		panic(err)
+	}
+	defer pg.ExecContext(ctx, "DELETE FROM api.projects WHERE id = $1", projectID)
+
+	id, err := db.Insert(ctx, Entry{Title: "Count me", AssignedUserID: 1, DueDate: time.Now().Add(24 * time.Hour)})
+	if err != nil {
+		panic(err)
+	}
+	defer db.Delete(ctx, id)
+	expectNoDrift(t, pg, "insert")
+
+	if err := db.AddAssignee(ctx, id, 2); err != nil {
+		panic(err)
+	}
+	expectNoDrift(t, pg, "share")
+
+	if err := db.Reassign(ctx, id, 2); err != nil {
+		panic(err)
+	}
+	expectNoDrift(t, pg, "reassign")
+
+	if err := db.SetStatus(ctx, id, StatusCompleted); err != nil {
+		panic(err)
+	}
+	expectNoDrift(t, pg, "status change")
+
+	if _, err := pg.ExecContext(ctx, "UPDATE api.tasks SET project_id = $1 WHERE id = $2", projectID, id); err != nil {
+		panic(err)
+	}
+	expectNoDrift(t, pg, "project move")
+
+	if err := db.Delete(ctx, id); err != nil {
+		panic(err)
+	}
+	expectNoDrift(t, pg, "delete")
+}
+
+func TestDB_ReconcileSummaries(t *testing.T) {
+	pg, err := postgres.Connect(postgres.ConnectionString())
+	if err != nil {
+		panic(err)
+	}
+	ctx, db := context.Background(), NewDB(pg)
+
+	id, err := db.Insert(ctx, Entry{Title: "Drift", AssignedUserID: 1, DueDate: time.Now().Add(24 * time.Hour)})
+	if err != nil {
+		panic(err)
+	}
+	defer db.Delete(ctx, id)
+	if _, err := ReconcileSummaries(ctx, pg); err != nil {
+		panic(err)
+	}
+
+	if _, err := pg.ExecContext(ctx,
+		"UPDATE api.task_summaries SET assigned = assigned + 1 WHERE user_id = 1 AND project_id IS NULL",
+	); err != nil {
+		panic(err)
+	}
+	drifts, err := ReconcileSummaries(ctx, pg)
+	if err != nil {
+		panic(err)
+	}
+	if len(drifts) != 1 || drifts[0].UserID != 1 || drifts[0].ProjectID != 0 ||
+		drifts[0].Stored.Assigned != drifts[0].Actual.Assigned+1 {
+		t.Errorf("expected the drift of user 1 without a project, got %+v", drifts)
+	}
+
+	if drifts, err = ReconcileSummaries(ctx, pg); err != nil {
+		panic(err)
+	}
+	if len(drifts) != 0 {
+		t.Errorf("expected no drift after reconciling, got %+v", drifts)
+	}
+}
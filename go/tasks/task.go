@@ -1,6 +1,7 @@
 package tasks
 
 import (
+	"encoding/json"
 	"errors"
 	"strings"
 	"time"
@@ -14,11 +15,52 @@ type (
 		Title            string    `json:"title"`
 		Description      string    `json:"description"`
 		Status           Status    `json:"status"`
+		Priority         Priority  `json:"priority"`
+		Labels           []string  `json:"labels"`
 		CreatedAt        time.Time `json:"createdAt"`
 		DueDate          time.Time `json:"dueDate"`
+
+		ProjectID    int             `json:"projectId,omitempty"`
+		BoardRank    float64         `json:"boardRank"`
+		ParentTaskID int             `json:"parentTaskId,omitempty"`
+		Checklist    []ChecklistItem `json:"checklist,omitempty"`
+
+		// Assignees includes the primary assignee (AssignedUserID) and everyone
+		// sharing the task; Watchers can see the task but don't own it.
+		Assignees []Participant `json:"assignees,omitempty"`
+		Watchers  []Participant `json:"watchers,omitempty"`
+
+		// CustomFields holds the values of the custom fields of the task's
+		// project, keyed by field key
+		CustomFields map[string]json.RawMessage `json:"customFields,omitempty"`
+
+		// in minutes, the remaining estimate going down as time is logged
+		OriginalEstimate  *int `json:"originalEstimate,omitempty"`
+		RemainingEstimate *int `json:"remainingEstimate,omitempty"`
+
+		// set on tasks materialized from a recurring task definition
+		RecurringTaskID int        `json:"recurringTaskId,omitempty"`
+		OccurrenceAt    *time.Time `json:"occurrenceAt,omitempty"`
 	}
 
 	Status string
+
+	Priority string
+
+	Participant struct {
+		UserID   int    `json:"userId"`
+		Username string `json:"username"`
+		Primary  bool   `json:"primary,omitempty"`
+	}
+
+	ChecklistItem struct {
+		ID       int        `json:"id"`
+		TaskID   int        `json:"taskId"`
+		Position int        `json:"position"`
+		Text     string     `json:"text"`
+		Done     bool       `json:"done"`
+		DoneAt   *time.Time `json:"doneAt,omitempty"`
+	}
 )
 
 const (
@@ -29,6 +71,15 @@ const (
 
 var Statuses = []Status{StatusPending, StatusInProgress, StatusCompleted}
 
+const (
+	PriorityLow    Priority = "LOW"
+	PriorityMedium Priority = "MEDIUM"
+	PriorityHigh   Priority = "HIGH"
+	PriorityUrgent Priority = "URGENT"
+)
+
+var Priorities = []Priority{PriorityLow, PriorityMedium, PriorityHigh, PriorityUrgent}
+
 func (e Entry) Pending() bool {
 	return e.Status == StatusPending
 }
@@ -41,15 +92,34 @@ func (e Entry) Completed() bool {
 	return e.Status == StatusCompleted
 }
 
+// Validate checks a new task, which must be due in the future.
 func (e Entry) Validate() error {
+	if err := e.ValidateFields(); err != nil {
+		return err
+	}
+	if time.Now().After(e.DueDate) {
+		return ErrDueDateExpired
+	}
+	return nil
+}
+
+// ValidateFields checks the fields of a task but its due date, which tasks
+// already due keep when edited.
+func (e Entry) ValidateFields() error {
 	if e.Title == "" {
 		return errors.New("missing title")
 	}
-	if e.AssignedUserID <= 0 {
+	// zero means unassigned, the task then waits in the backlog
+	if e.AssignedUserID < 0 {
 		return errors.New("invalid assigned user")
 	}
-	if time.Now().After(e.DueDate) {
-		return errors.New("due date expired")
+	if e.Priority != "" {
+		if _, err := ParsePriority(string(e.Priority)); err != nil {
+			return err
+		}
+	}
+	if (e.OriginalEstimate != nil && *e.OriginalEstimate < 0) || (e.RemainingEstimate != nil && *e.RemainingEstimate < 0) {
+		return errors.New("invalid estimate")
 	}
 	return nil
 }
@@ -62,3 +132,12 @@ func ParseStatus(str string) (Status, error) {
 	}
 	return "", errors.New("invalid status")
 }
+
+func ParsePriority(str string) (Priority, error) {
+	for _, p := range Priorities {
+		if strings.EqualFold(str, string(p)) {
+			return p, nil
+		}
+	}
+	return "", errors.New("invalid priority")
+}
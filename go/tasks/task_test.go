@@ -1,6 +1,8 @@
 package tasks
 
 import (
+	"errors"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -78,6 +80,11 @@ func TestEntry_Validate(t *testing.T) {
 			entry:   Entry{Title: "Valid Title", AssignedUserID: -1, DueDate: time.Now().Add(-time.Hour)},
 			wantErr: true,
 		},
+		{
+			name:    "Unassigned",
+			entry:   Entry{Title: "Valid Title", DueDate: time.Now().Add(time.Hour)},
+			wantErr: false,
+		},
 		{
 			name:    "Zero Assigned User ID",
 			entry:   Entry{Title: "Valid Title", AssignedUserID: 0, DueDate: time.Now().Add(-time.Hour)},
@@ -88,6 +95,11 @@ func TestEntry_Validate(t *testing.T) {
 			entry:   Entry{Title: "Valid Title", AssignedUserID: 1, DueDate: time.Now().Add(time.Hour)},
 			wantErr: false,
 		},
+		{
+			name:    "Negative Estimate",
+			entry:   Entry{Title: "Valid Title", AssignedUserID: 1, DueDate: time.Now().Add(time.Hour), OriginalEstimate: &[]int{-30}[0]},
+			wantErr: true,
+		},
 	}
 
 	for _, tc := range cases {
@@ -102,3 +114,151 @@ func TestEntry_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePriority(t *testing.T) {
+	cases := []struct {
+		str     string
+		want    Priority
+		wantErr bool
+	}{
+		{str: "low", want: PriorityLow},
+		{str: "URGENT", want: PriorityUrgent},
+		{str: "critical", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.str, func(t *testing.T) {
+			got, err := ParsePriority(tc.str)
+			if tc.wantErr && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tc.wantErr && got != tc.want {
+				t.Errorf("Expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParseCustomFieldFilter(t *testing.T) {
+	cases := []struct {
+		name      string
+		fieldType FieldType
+		expr      string
+		expected  CustomFieldFilter
+		err       bool
+	}{
+		{name: "equality by default", fieldType: FieldTypeText, expr: "acme",
+			expected: CustomFieldFilter{Key: "k", Type: FieldTypeText, Op: OpEq, Value: "acme"}},
+		{name: "operator", fieldType: FieldTypeNumber, expr: "lt:5",
+			expected: CustomFieldFilter{Key: "k", Type: FieldTypeNumber, Op: OpLt, Value: "5"}},
+		{name: "colon in value", fieldType: FieldTypeText, expr: "a:b",
+			expected: CustomFieldFilter{Key: "k", Type: FieldTypeText, Op: OpEq, Value: "a:b"}},
+		{name: "multi select contains", fieldType: FieldTypeMultiSelect, expr: "contains:red",
+			expected: CustomFieldFilter{Key: "k", Type: FieldTypeMultiSelect, Op: OpContains, Value: "red"}},
+		{name: "invalid number", fieldType: FieldTypeNumber, expr: "gt:many", err: true},
+		{name: "invalid date", fieldType: FieldTypeDate, expr: "2024-13-01", err: true},
+		{name: "multi select equality", fieldType: FieldTypeMultiSelect, expr: "red", err: true},
+		{name: "number contains", fieldType: FieldTypeNumber, expr: "contains:1", err: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			filter, err := ParseCustomFieldFilter("k", tc.fieldType, tc.expr)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected error, got %v", filter)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if filter != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, filter)
+			}
+		})
+	}
+}
+
+func TestCursor(t *testing.T) {
+	value := "2024-03-01 09:00:00+00"
+	cursor := Cursor{Sort: "tasks.due_date DESC,tasks.id DESC", Values: []*string{&value}, ID: 12}
+	parsed, err := ParseCursor(cursor.String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(*parsed, cursor) {
+		t.Errorf("expected %+v, got %+v", cursor, *parsed)
+	}
+
+	for _, str := range []string{"", "not a cursor", Cursor{Sort: "tasks.title"}.String()} {
+		if _, err := ParseCursor(str); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%q: expected ErrInvalidCursor, got %v", str, err)
+		}
+	}
+
+	opts := FindOptions{SortBy: TitleCol, After: &cursor}
+	if err := opts.checkCursor(); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor for another sort, got %v", err)
+	}
+	opts = FindOptions{SortBy: DueDateCol, SortOrder: SortOrderDescending, After: &cursor}
+	if err := opts.checkCursor(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	opts = FindOptions{Sort: []SortKey{{Column: DueDateCol, Order: SortOrderDescending}}, After: &cursor}
+	if err := opts.checkCursor(); err != nil {
+		t.Errorf("unexpected error for the same sort as keys: %v", err)
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	keys, err := ParseSort("-due_date, title,assigned_username")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []SortKey{
+		{Column: DueDateCol, Order: SortOrderDescending},
+		{Column: TitleCol, Order: SortOrderAscending},
+		{Column: "assigned_username", Order: SortOrderAscending},
+	}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected %v, got %v", expected, keys)
+	}
+	for _, str := range []string{"", "-", "due_date,,title", "owner"} {
+		if _, err := ParseSort(str); err == nil {
+			t.Errorf("%q: expected an error", str)
+		}
+	}
+}
+
+func TestParseFields_Select(t *testing.T) {
+	fields, err := ParseFields("id, title,checklist")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := ParseFields("id,secret"); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+
+	selected, err := Entry{ID: 3, Title: "Deploy", Description: "a long description"}.Select(fields)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the checklist is empty, hence omitted
+	if len(selected) != 2 || string(selected["id"]) != "3" || string(selected["title"]) != `"Deploy"` {
+		t.Errorf("unexpected selection %v", selected)
+	}
+}
+
+func TestEntry_ValidateFields(t *testing.T) {
+	due := Entry{Title: "Valid Title", AssignedUserID: 1, DueDate: time.Now().Add(-time.Hour)}
+	if err := due.ValidateFields(); err != nil {
+		t.Errorf("Expected no error for a task already due, got %v", err)
+	}
+	if err := due.Validate(); !errors.Is(err, ErrDueDateExpired) {
+		t.Errorf("Expected %v, got %v", ErrDueDateExpired, err)
+	}
+	due.Title = ""
+	if err := due.ValidateFields(); err == nil {
+		t.Error("Expected error, got nil")
+	}
+}
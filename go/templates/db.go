@@ -0,0 +1,174 @@
+package templates
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"siransbach/taskmanagementapi/tasks"
+)
+
+type (
+	DB struct {
+		pg *sql.DB
+	}
+
+	FindOptions struct {
+		IDs []int
+	}
+)
+
+const selectColumns = "id,name,title,description,due_offset_hours,priority,labels,checklist,subtasks,created_at"
+
+func NewDB(pg *sql.DB) *DB {
+	return &DB{pg}
+}
+
+func (db *DB) Find(ctx context.Context, options FindOptions) ([]Template, error) {
+	query, args := options.buildQuery()
+	rows, err := db.pg.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []Template
+	for rows.Next() {
+		var (
+			t        Template
+			subtasks []byte
+		)
+		if err := rows.Scan(
+			&t.ID, &t.Name, &t.Title, &t.Description, &t.DueOffsetHours, &t.Priority,
+			pq.Array(&t.Labels), pq.Array(&t.Checklist), &subtasks, &t.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(subtasks, &t.Subtasks); err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+func (db *DB) FindOne(ctx context.Context, options FindOptions) (Template, error) {
+	templates, err := db.Find(ctx, options)
+	if err != nil {
+		return Template{}, err
+	}
+	if len(templates) == 0 {
+		return Template{}, sql.ErrNoRows
+	}
+	return templates[0], nil
+}
+
+func (db *DB) Insert(ctx context.Context, t Template) (id int, err error) {
+	args, err := t.args()
+	if err != nil {
+		return 0, err
+	}
+	err = db.pg.QueryRowContext(ctx,
+		"INSERT INTO api.task_templates (name,title,description,due_offset_hours,priority,labels,checklist,subtasks)"+
+			" VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id",
+		args...,
+	).Scan(&id)
+	return id, err
+}
+
+func (db *DB) Update(ctx context.Context, t Template) error {
+	args, err := t.args()
+	if err != nil {
+		return err
+	}
+	var updatedID int
+	return db.pg.QueryRowContext(ctx,
+		"UPDATE api.task_templates SET name = $1, title = $2, description = $3, due_offset_hours = $4,"+
+			" priority = $5, labels = $6, checklist = $7, subtasks = $8 WHERE id = $9 RETURNING id",
+		append(args, t.ID)...,
+	).Scan(&updatedID)
+}
+
+func (db *DB) Delete(ctx context.Context, id int) error {
+	var deletedID int
+	return db.pg.QueryRowContext(ctx,
+		"DELETE FROM api.task_templates WHERE id = $1 RETURNING id", id,
+	).Scan(&deletedID)
+}
+
+// Instantiate creates the task and subtasks described by t for
+// assignedUserID in a single transaction, and returns the ID of the task.
+func (db *DB) Instantiate(ctx context.Context, t Template, assignedUserID int, startAt time.Time) (id int, err error) {
+	task, subtasks := t.Tasks(assignedUserID, startAt)
+
+	tx, err := db.pg.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	taskDB := tasks.NewDB(tx)
+	if id, err = taskDB.Insert(ctx, task); err != nil {
+		return 0, err
+	}
+	for _, sub := range subtasks {
+		sub.ParentTaskID = id
+		if _, err = taskDB.Insert(ctx, sub); err != nil {
+			return 0, fmt.Errorf("subtask %q: %w", sub.Title, err)
+		}
+	}
+	return id, tx.Commit()
+}
+
+func (t Template) args() ([]interface{}, error) {
+	if err := t.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	priority := tasks.PriorityMedium
+	if t.Priority != "" {
+		priority = t.Priority
+	}
+	labels, checklist, subtasks := t.Labels, t.Checklist, t.Subtasks
+	if labels == nil {
+		labels = []string{}
+	}
+	if checklist == nil {
+		checklist = []string{}
+	}
+	if subtasks == nil {
+		subtasks = []Subtask{}
+	}
+	rawSubtasks, err := json.Marshal(subtasks)
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{
+		t.Name, t.Title, t.Description, t.DueOffsetHours, priority,
+		pq.Array(labels), pq.Array(checklist), rawSubtasks,
+	}, nil
+}
+
+func (opt FindOptions) buildQuery() (query string, args []interface{}) {
+	var clauses []string
+	args = make([]interface{}, 0)
+
+	if len(opt.IDs) > 0 {
+		args = append(args, pq.Array(opt.IDs))
+		clauses = append(clauses, fmt.Sprintf("id = ANY($%d)", len(args)))
+	}
+
+	stmt := fmt.Sprintf("SELECT %s FROM api.task_templates", selectColumns)
+	if len(clauses) > 0 {
+		stmt = fmt.Sprintf("%s WHERE %s", stmt, strings.Join(clauses, " AND "))
+	}
+	return stmt + " ORDER BY id ASC", args
+}
@@ -0,0 +1,104 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"siransbach/taskmanagementapi/tasks"
+)
+
+type (
+	// Template is a reusable blueprint for tasks that are created the same way
+	// every time, such as onboarding or release tasks.
+	Template struct {
+		ID             int            `json:"id"`
+		Name           string         `json:"name"`
+		Title          string         `json:"title"`
+		Description    string         `json:"description"`
+		DueOffsetHours int            `json:"dueOffsetHours"`
+		Priority       tasks.Priority `json:"priority"`
+		Labels         []string       `json:"labels"`
+		Checklist      []string       `json:"checklist"`
+		Subtasks       []Subtask      `json:"subtasks"`
+		CreatedAt      time.Time      `json:"createdAt"`
+	}
+
+	Subtask struct {
+		Title          string   `json:"title"`
+		Description    string   `json:"description"`
+		DueOffsetHours int      `json:"dueOffsetHours"`
+		Checklist      []string `json:"checklist"`
+	}
+)
+
+func (t Template) Validate() error {
+	if t.Name == "" {
+		return errors.New("missing name")
+	}
+	if t.Title == "" {
+		return errors.New("missing title")
+	}
+	if t.DueOffsetHours <= 0 {
+		return errors.New("due offset must be positive")
+	}
+	if t.Priority != "" {
+		if _, err := tasks.ParsePriority(string(t.Priority)); err != nil {
+			return err
+		}
+	}
+	for i, item := range t.Checklist {
+		if item == "" {
+			return fmt.Errorf("empty checklist item %d", i+1)
+		}
+	}
+	for i, sub := range t.Subtasks {
+		if sub.Title == "" {
+			return fmt.Errorf("subtask %d: missing title", i+1)
+		}
+		if sub.DueOffsetHours < 0 {
+			return fmt.Errorf("subtask %d: due offset must not be negative", i+1)
+		}
+	}
+	return nil
+}
+
+// Tasks returns the task and subtasks created when instantiating the template
+// for assignedUserID at startAt. Subtasks without their own due offset share
+// the due date of the task.
+func (t Template) Tasks(assignedUserID int, startAt time.Time) (tasks.Entry, []tasks.Entry) {
+	task := tasks.Entry{
+		Title:          t.Title,
+		Description:    t.Description,
+		AssignedUserID: assignedUserID,
+		Priority:       t.Priority,
+		Labels:         t.Labels,
+		Checklist:      checklist(t.Checklist),
+		DueDate:        startAt.Add(time.Duration(t.DueOffsetHours) * time.Hour),
+	}
+	var subtasks []tasks.Entry
+	for _, sub := range t.Subtasks {
+		due := task.DueDate
+		if sub.DueOffsetHours > 0 {
+			due = startAt.Add(time.Duration(sub.DueOffsetHours) * time.Hour)
+		}
+		subtasks = append(subtasks, tasks.Entry{
+			Title:          sub.Title,
+			Description:    sub.Description,
+			AssignedUserID: assignedUserID,
+			Priority:       t.Priority,
+			Labels:         t.Labels,
+			Checklist:      checklist(sub.Checklist),
+			DueDate:        due,
+		})
+	}
+	return task, subtasks
+}
+
+func checklist(items []string) []tasks.ChecklistItem {
+	var ret []tasks.ChecklistItem
+	for _, text := range items {
+		ret = append(ret, tasks.ChecklistItem{Text: text})
+	}
+	return ret
+}
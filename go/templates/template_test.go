@@ -0,0 +1,93 @@
+package templates
+
+import (
+	"testing"
+	"time"
+
+	"siransbach/taskmanagementapi/tasks"
+)
+
+func TestTemplate_Validate(t *testing.T) {
+	cases := []struct {
+		name     string
+		template Template
+		wantErr  bool
+	}{
+		{
+			name:     "Valid",
+			template: Template{Name: "onboarding", Title: "Onboard", DueOffsetHours: 24},
+			wantErr:  false,
+		},
+		{
+			name:     "Missing Name",
+			template: Template{Title: "Onboard", DueOffsetHours: 24},
+			wantErr:  true,
+		},
+		{
+			name:     "Missing Due Offset",
+			template: Template{Name: "onboarding", Title: "Onboard"},
+			wantErr:  true,
+		},
+		{
+			name:     "Invalid Priority",
+			template: Template{Name: "onboarding", Title: "Onboard", DueOffsetHours: 24, Priority: "SOON"},
+			wantErr:  true,
+		},
+		{
+			name: "Subtask Without Title",
+			template: Template{
+				Name: "onboarding", Title: "Onboard", DueOffsetHours: 24,
+				Subtasks: []Subtask{{DueOffsetHours: 2}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.template.Validate()
+			if tc.wantErr && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tc.wantErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestTemplate_Tasks(t *testing.T) {
+	template := Template{
+		Name:           "release",
+		Title:          "Release",
+		DueOffsetHours: 48,
+		Priority:       tasks.PriorityHigh,
+		Labels:         []string{"release"},
+		Checklist:      []string{"tag", "deploy"},
+		Subtasks: []Subtask{
+			{Title: "Changelog", DueOffsetHours: 24},
+			{Title: "Announce"},
+		},
+	}
+	startAt := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	task, subtasks := template.Tasks(1, startAt)
+	if !task.DueDate.Equal(startAt.Add(48 * time.Hour)) {
+		t.Errorf("expected task due at %v, got %v", startAt.Add(48*time.Hour), task.DueDate)
+	}
+	if len(task.Checklist) != 2 || task.Checklist[1].Text != "deploy" {
+		t.Errorf("expected checklist to be copied, got %v", task.Checklist)
+	}
+	if len(subtasks) != 2 {
+		t.Fatalf("expected 2 subtasks, got %d", len(subtasks))
+	}
+	if !subtasks[0].DueDate.Equal(startAt.Add(24 * time.Hour)) {
+		t.Errorf("expected subtask due at %v, got %v", startAt.Add(24*time.Hour), subtasks[0].DueDate)
+	}
+	if !subtasks[1].DueDate.Equal(task.DueDate) {
+		t.Errorf("expected subtask without offset to share the task due date, got %v", subtasks[1].DueDate)
+	}
+	if subtasks[0].AssignedUserID != 1 || subtasks[0].Priority != tasks.PriorityHigh {
+		t.Errorf("expected subtask to inherit assignee and priority, got %+v", subtasks[0])
+	}
+}
@@ -0,0 +1,331 @@
+package timesheets
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"siransbach/taskmanagementapi/dates"
+)
+
+type (
+	DB struct {
+		pg *sql.DB
+	}
+
+	FindOptions struct {
+		IDs      []int
+		UserIDs  []int
+		Statuses []Status
+		// From and To bound the start of the weeks, when set
+		From, To time.Time
+	}
+
+	// ExportRow is a line of the payroll export: the hours a user spent on a
+	// task on a day of an approved timesheet.
+	ExportRow struct {
+		UserID    int
+		Username  string
+		WeekStart dates.Date
+		Day       dates.Date
+		TaskID    int
+		TaskTitle string
+		Hours     float64
+	}
+)
+
+const selectColumns = "timesheets.id,timesheets.user_id,users.username,timesheets.week_start,timesheets.status," +
+	"timesheets.submitted_at,timesheets.reviewer_id,timesheets.reviewed_at,timesheets.comment"
+
+func NewDB(pg *sql.DB) *DB {
+	return &DB{pg}
+}
+
+// Find returns timesheets without their entries.
+func (db *DB) Find(ctx context.Context, options FindOptions) ([]Timesheet, error) {
+	query, args := options.buildQuery()
+	rows, err := db.pg.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var timesheets []Timesheet
+	for rows.Next() {
+		var (
+			t           Timesheet
+			submittedAt sql.NullTime
+			reviewerID  sql.NullInt64
+			reviewedAt  sql.NullTime
+		)
+		if err := rows.Scan(
+			&t.ID, &t.UserID, &t.Username, &t.WeekStart.Time, &t.Status,
+			&submittedAt, &reviewerID, &reviewedAt, &t.Comment,
+		); err != nil {
+			return nil, err
+		}
+		if submittedAt.Valid {
+			t.SubmittedAt = &submittedAt.Time
+		}
+		t.ReviewerID = int(reviewerID.Int64)
+		if reviewedAt.Valid {
+			t.ReviewedAt = &reviewedAt.Time
+		}
+		timesheets = append(timesheets, t)
+	}
+	return timesheets, rows.Err()
+}
+
+// FindOne returns a timesheet along with its entries and totals.
+func (db *DB) FindOne(ctx context.Context, options FindOptions) (Timesheet, error) {
+	timesheets, err := db.Find(ctx, options)
+	if err != nil {
+		return Timesheet{}, err
+	}
+	if len(timesheets) == 0 {
+		return Timesheet{}, sql.ErrNoRows
+	}
+	t := timesheets[0]
+	if t.Entries, err = db.entries(ctx, t.UserID, t.WeekStart); err != nil {
+		return Timesheet{}, err
+	}
+	t.aggregate()
+	return t, nil
+}
+
+// Week returns the timesheet of userID for the week of day. Weeks nobody
+// logged hours for yet are returned as empty drafts, without saving them.
+func (db *DB) Week(ctx context.Context, userID int, day dates.Date) (Timesheet, error) {
+	weekStart := day.WeekStart()
+	t, err := db.FindOne(ctx, FindOptions{UserIDs: []int{userID}, From: weekStart.Time, To: weekStart.AddDate(0, 0, 1)})
+	if !errors.Is(err, sql.ErrNoRows) {
+		return t, err
+	}
+	t = Timesheet{UserID: userID, WeekStart: weekStart, Status: StatusDraft, Entries: []Entry{}}
+	if err := db.pg.QueryRowContext(ctx,
+		"SELECT username FROM auth.users WHERE id = $1", userID,
+	).Scan(&t.Username); err != nil {
+		return Timesheet{}, err
+	}
+	t.aggregate()
+	return t, nil
+}
+
+// SetEntry records the hours userID spent on a task on a day, replacing any
+// previous entry; zero hours removes it. Entries of submitted and approved
+// timesheets are locked, and the entries of a day add up to maxHoursPerDay at
+// most.
+func (db *DB) SetEntry(ctx context.Context, e Entry) (err error) {
+	if err := e.Validate(); err != nil {
+		return fmt.Errorf("invalid entry: %w", err)
+	}
+	tx, err := db.pg.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// locking the timesheet row keeps a concurrent submission, or entry of the
+	// same week, from slipping in between the checks and the write
+	weekStart := e.Day.WeekStart()
+	if err = db.ensure(ctx, tx, e.UserID, weekStart); err != nil {
+		return err
+	}
+	var status Status
+	if err = tx.QueryRowContext(ctx,
+		"SELECT status FROM api.timesheets WHERE user_id = $1 AND week_start = $2 FOR UPDATE",
+		e.UserID, weekStart.Time,
+	).Scan(&status); err != nil {
+		return err
+	}
+	if status.Locked() {
+		return ErrLocked
+	}
+	var others float64
+	if err = tx.QueryRowContext(ctx,
+		"SELECT COALESCE(SUM(hours), 0) FROM api.timesheet_entries WHERE user_id = $1 AND day = $2 AND task_id <> $3",
+		e.UserID, e.Day.Time, e.TaskID,
+	).Scan(&others); err != nil {
+		return err
+	}
+	if others+e.Hours > maxHoursPerDay {
+		return ErrDayFull
+	}
+
+	if e.Hours == 0 {
+		_, err = tx.ExecContext(ctx,
+			"DELETE FROM api.timesheet_entries WHERE user_id = $1 AND task_id = $2 AND day = $3",
+			e.UserID, e.TaskID, e.Day.Time,
+		)
+	} else {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO api.timesheet_entries (user_id,task_id,day,hours,note) VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (user_id, task_id, day) DO UPDATE SET hours = EXCLUDED.hours, note = EXCLUDED.note`,
+			e.UserID, e.TaskID, e.Day.Time, e.Hours, e.Note,
+		)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Submit sends the timesheet of userID for the week of day for approval.
+func (db *DB) Submit(ctx context.Context, userID int, day dates.Date) error {
+	weekStart := day.WeekStart()
+	if err := db.ensure(ctx, db.pg, userID, weekStart); err != nil {
+		return err
+	}
+	var id int
+	err := db.pg.QueryRowContext(ctx, `
+		UPDATE api.timesheets SET status = $1, submitted_at = NOW(), comment = ''
+		WHERE user_id = $2 AND week_start = $3 AND status = ANY($4)
+		RETURNING id`,
+		StatusSubmitted, userID, weekStart.Time, pq.Array(StatusSubmitted.from()),
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTransition
+	}
+	return err
+}
+
+// Review approves or rejects a submitted timesheet. Approved timesheets stay
+// locked for good.
+func (db *DB) Review(ctx context.Context, id, reviewerID int, status Status, comment string) error {
+	if status != StatusApproved && status != StatusRejected {
+		return ErrTransition
+	}
+	var reviewedID int
+	err := db.pg.QueryRowContext(ctx, `
+		UPDATE api.timesheets SET status = $1, reviewer_id = $2, reviewed_at = NOW(), comment = $3
+		WHERE id = $4 AND status = ANY($5)
+		RETURNING id`,
+		status, reviewerID, comment, id, pq.Array(status.from()),
+	).Scan(&reviewedID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	// tell a missing timesheet from one in the wrong status
+	var exists bool
+	if err := db.pg.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM api.timesheets WHERE id = $1)", id,
+	).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrTransition
+	}
+	return sql.ErrNoRows
+}
+
+// Export returns the entries of the approved timesheets of the weeks starting
+// within [from, to), in order.
+func (db *DB) Export(ctx context.Context, from, to time.Time) ([]ExportRow, error) {
+	rows, err := db.pg.QueryContext(ctx, `
+		SELECT timesheets.user_id, users.username, timesheets.week_start, entries.day,
+			entries.task_id, tasks.title, entries.hours
+		FROM api.timesheets
+		JOIN auth.users ON users.id = timesheets.user_id
+		JOIN api.timesheet_entries entries ON entries.user_id = timesheets.user_id
+			AND entries.day >= timesheets.week_start AND entries.day < timesheets.week_start + 7
+		JOIN api.tasks ON tasks.id = entries.task_id
+		WHERE timesheets.status = $1 AND timesheets.week_start >= $2 AND timesheets.week_start < $3
+		ORDER BY users.username, entries.day, entries.task_id`,
+		StatusApproved, from, to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var export []ExportRow
+	for rows.Next() {
+		var r ExportRow
+		if err := rows.Scan(
+			&r.UserID, &r.Username, &r.WeekStart.Time, &r.Day.Time, &r.TaskID, &r.TaskTitle, &r.Hours,
+		); err != nil {
+			return nil, err
+		}
+		export = append(export, r)
+	}
+	return export, rows.Err()
+}
+
+func (db *DB) entries(ctx context.Context, userID int, weekStart dates.Date) ([]Entry, error) {
+	rows, err := db.pg.QueryContext(ctx, `
+		SELECT entries.id, entries.user_id, entries.task_id, tasks.title, entries.day, entries.hours, entries.note
+		FROM api.timesheet_entries entries
+		JOIN api.tasks ON tasks.id = entries.task_id
+		WHERE entries.user_id = $1 AND entries.day >= $2 AND entries.day < $3
+		ORDER BY entries.day, entries.task_id`,
+		userID, weekStart.Time, weekStart.AddDate(0, 0, 7),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.ID, &e.UserID, &e.TaskID, &e.TaskTitle, &e.Day.Time, &e.Hours, &e.Note); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// ensure creates the draft timesheet of a week if there is none yet.
+func (db *DB) ensure(ctx context.Context, pg execer, userID int, weekStart dates.Date) error {
+	_, err := pg.ExecContext(ctx,
+		"INSERT INTO api.timesheets (user_id,week_start) VALUES ($1, $2) ON CONFLICT (user_id, week_start) DO NOTHING",
+		userID, weekStart.Time,
+	)
+	return err
+}
+
+func (opt FindOptions) buildQuery() (query string, args []interface{}) {
+	var clauses []string
+	args = make([]interface{}, 0)
+
+	if len(opt.IDs) > 0 {
+		args = append(args, pq.Array(opt.IDs))
+		clauses = append(clauses, fmt.Sprintf("timesheets.id = ANY($%d)", len(args)))
+	}
+	if len(opt.UserIDs) > 0 {
+		args = append(args, pq.Array(opt.UserIDs))
+		clauses = append(clauses, fmt.Sprintf("timesheets.user_id = ANY($%d)", len(args)))
+	}
+	if len(opt.Statuses) > 0 {
+		args = append(args, pq.Array(opt.Statuses))
+		clauses = append(clauses, fmt.Sprintf("timesheets.status = ANY($%d)", len(args)))
+	}
+	if !opt.From.IsZero() {
+		args = append(args, opt.From)
+		clauses = append(clauses, fmt.Sprintf("timesheets.week_start >= $%d", len(args)))
+	}
+	if !opt.To.IsZero() {
+		args = append(args, opt.To)
+		clauses = append(clauses, fmt.Sprintf("timesheets.week_start < $%d", len(args)))
+	}
+
+	stmt := fmt.Sprintf("SELECT %s FROM api.timesheets JOIN auth.users ON users.id = timesheets.user_id", selectColumns)
+	if len(clauses) > 0 {
+		stmt = fmt.Sprintf("%s WHERE %s", stmt, strings.Join(clauses, " AND "))
+	}
+	return stmt + " ORDER BY timesheets.week_start DESC, users.username ASC", args
+}
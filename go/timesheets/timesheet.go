@@ -0,0 +1,167 @@
+package timesheets
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"siransbach/taskmanagementapi/dates"
+)
+
+type (
+	// Timesheet gathers the hours a user spent on tasks during a week, starting
+	// on Monday, and goes through approval as a whole.
+	Timesheet struct {
+		ID          int        `json:"id"`
+		UserID      int        `json:"userId"`
+		Username    string     `json:"username"`
+		WeekStart   dates.Date `json:"weekStart"`
+		Status      Status     `json:"status"`
+		SubmittedAt *time.Time `json:"submittedAt,omitempty"`
+		ReviewerID  int        `json:"reviewerId,omitempty"`
+		ReviewedAt  *time.Time `json:"reviewedAt,omitempty"`
+		Comment     string     `json:"comment"`
+
+		Entries []Entry     `json:"entries,omitempty"`
+		Days    []DayTotal  `json:"days,omitempty"`
+		Tasks   []TaskTotal `json:"tasks,omitempty"`
+		Hours   float64     `json:"hours"`
+	}
+
+	// Entry is the hours a user spent on a task on a given day.
+	Entry struct {
+		ID        int        `json:"id"`
+		UserID    int        `json:"userId"`
+		TaskID    int        `json:"taskId"`
+		TaskTitle string     `json:"taskTitle"`
+		Day       dates.Date `json:"day"`
+		Hours     float64    `json:"hours"`
+		Note      string     `json:"note"`
+	}
+
+	DayTotal struct {
+		Day   dates.Date `json:"day"`
+		Hours float64    `json:"hours"`
+	}
+
+	// TaskTotal is the hours spent on a task during the week, per day.
+	TaskTotal struct {
+		TaskID    int                `json:"taskId"`
+		TaskTitle string             `json:"taskTitle"`
+		Hours     float64            `json:"hours"`
+		Days      map[string]float64 `json:"days"`
+	}
+
+	Status string
+)
+
+const (
+	StatusDraft     Status = "DRAFT"
+	StatusSubmitted Status = "SUBMITTED"
+	StatusApproved  Status = "APPROVED"
+	StatusRejected  Status = "REJECTED"
+)
+
+var Statuses = []Status{StatusDraft, StatusSubmitted, StatusApproved, StatusRejected}
+
+// transitions lists the statuses a timesheet can go to from each status. A
+// rejected timesheet goes back to its owner, who fixes and submits it again.
+var transitions = map[Status][]Status{
+	StatusDraft:     {StatusSubmitted},
+	StatusRejected:  {StatusSubmitted},
+	StatusSubmitted: {StatusApproved, StatusRejected},
+}
+
+var (
+	// ErrLocked is returned when changing the entries of a timesheet that is
+	// submitted or approved.
+	ErrLocked = errors.New("timesheet is locked")
+	// ErrTransition is returned when a timesheet cannot go to the requested
+	// status from its current one.
+	ErrTransition = errors.New("invalid timesheet status transition")
+	// ErrDayFull is returned when the entries of a day would add up to more
+	// than maxHoursPerDay.
+	ErrDayFull = fmt.Errorf("a day has at most %d hours", maxHoursPerDay)
+)
+
+// maxHoursPerDay is the most hours the entries of a day can add up to.
+const maxHoursPerDay = 24
+
+func ParseStatus(str string) (Status, error) {
+	for _, s := range Statuses {
+		if strings.EqualFold(str, string(s)) {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("invalid timesheet status: %s", str)
+}
+
+// Locked tells whether the entries of a timesheet in this status can no longer
+// change.
+func (s Status) Locked() bool {
+	return s == StatusSubmitted || s == StatusApproved
+}
+
+// from returns the statuses a timesheet can be in to go to s.
+func (s Status) from() []Status {
+	var from []Status
+	for _, status := range Statuses {
+		for _, to := range transitions[status] {
+			if to == s {
+				from = append(from, status)
+			}
+		}
+	}
+	return from
+}
+
+func (e Entry) Validate() error {
+	if e.UserID <= 0 {
+		return errors.New("invalid user")
+	}
+	if e.TaskID <= 0 {
+		return errors.New("invalid task")
+	}
+	if e.Day.IsZero() {
+		return errors.New("missing day")
+	}
+	if e.Hours < 0 || e.Hours > maxHoursPerDay {
+		return fmt.Errorf("hours must be between 0 and %d", maxHoursPerDay)
+	}
+	return nil
+}
+
+// aggregate computes the totals of the timesheet by day and by task from its
+// entries. Every day of the week is listed, worked or not.
+func (t *Timesheet) aggregate() {
+	byDay := make(map[string]float64)
+	byTask := make(map[int]*TaskTotal)
+	t.Hours = 0
+	for _, e := range t.Entries {
+		day := e.Day.String()
+		byDay[day] += e.Hours
+		total, ok := byTask[e.TaskID]
+		if !ok {
+			total = &TaskTotal{TaskID: e.TaskID, TaskTitle: e.TaskTitle, Days: make(map[string]float64)}
+			byTask[e.TaskID] = total
+		}
+		total.Days[day] += e.Hours
+		total.Hours += e.Hours
+		t.Hours += e.Hours
+	}
+
+	t.Days = make([]DayTotal, 0, 7)
+	for i := 0; i < 7; i++ {
+		day := dates.Date{Time: t.WeekStart.AddDate(0, 0, i)}
+		t.Days = append(t.Days, DayTotal{Day: day, Hours: byDay[day.String()]})
+	}
+	t.Tasks = make([]TaskTotal, 0, len(byTask))
+	for _, total := range byTask {
+		t.Tasks = append(t.Tasks, *total)
+	}
+	sort.Slice(t.Tasks, func(i, j int) bool {
+		return t.Tasks[i].TaskID < t.Tasks[j].TaskID
+	})
+}
@@ -0,0 +1,89 @@
+package timesheets
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"siransbach/taskmanagementapi/dates"
+)
+
+func mustDate(t *testing.T, str string) dates.Date {
+	t.Helper()
+	d, err := dates.Parse(str)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestEntry_JSON(t *testing.T) {
+	var e Entry
+	if err := json.Unmarshal([]byte(`{"day": "2024-03-06", "hours": 7.5}`), &e); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(DayTotal{Day: e.Day, Hours: e.Hours})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"day":"2024-03-06","hours":7.5}` {
+		t.Errorf("unexpected JSON %s", b)
+	}
+	if err := json.Unmarshal([]byte(`{"day": "06/03/2024"}`), &e); err == nil {
+		t.Error("Expected error for invalid date, got nil")
+	}
+}
+
+func TestStatus_From(t *testing.T) {
+	if from := StatusSubmitted.from(); !reflect.DeepEqual(from, []Status{StatusDraft, StatusRejected}) {
+		t.Errorf("unexpected statuses before submission: %v", from)
+	}
+	if from := StatusApproved.from(); !reflect.DeepEqual(from, []Status{StatusSubmitted}) {
+		t.Errorf("unexpected statuses before approval: %v", from)
+	}
+	if !StatusApproved.Locked() || !StatusSubmitted.Locked() || StatusRejected.Locked() || StatusDraft.Locked() {
+		t.Error("only submitted and approved timesheets are locked")
+	}
+}
+
+func TestTimesheet_Aggregate(t *testing.T) {
+	ts := Timesheet{
+		WeekStart: mustDate(t, "2024-03-04"),
+		Entries: []Entry{
+			{TaskID: 2, TaskTitle: "Review", Day: mustDate(t, "2024-03-04"), Hours: 1.5},
+			{TaskID: 1, TaskTitle: "Build", Day: mustDate(t, "2024-03-04"), Hours: 6},
+			{TaskID: 1, TaskTitle: "Build", Day: mustDate(t, "2024-03-06"), Hours: 8},
+		},
+	}
+	ts.aggregate()
+
+	if ts.Hours != 15.5 {
+		t.Errorf("expected 15.5 hours, got %v", ts.Hours)
+	}
+	if len(ts.Days) != 7 || ts.Days[0].Hours != 7.5 || ts.Days[1].Hours != 0 || ts.Days[2].Hours != 8 {
+		t.Errorf("unexpected day totals %v", ts.Days)
+	}
+	if ts.Days[6].Day.String() != "2024-03-10" {
+		t.Errorf("expected the week to end on 2024-03-10, got %s", ts.Days[6].Day)
+	}
+	expected := []TaskTotal{
+		{TaskID: 1, TaskTitle: "Build", Hours: 14, Days: map[string]float64{"2024-03-04": 6, "2024-03-06": 8}},
+		{TaskID: 2, TaskTitle: "Review", Hours: 1.5, Days: map[string]float64{"2024-03-04": 1.5}},
+	}
+	if !reflect.DeepEqual(ts.Tasks, expected) {
+		t.Errorf("expected task totals %v, got %v", expected, ts.Tasks)
+	}
+}
+
+func TestEntry_Validate(t *testing.T) {
+	day := mustDate(t, "2024-03-04")
+	if err := (Entry{UserID: 1, TaskID: 2, Day: day, Hours: 7.5}).Validate(); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if err := (Entry{UserID: 1, TaskID: 2, Day: day, Hours: 25}).Validate(); err == nil {
+		t.Error("Expected error for too many hours, got nil")
+	}
+	if err := (Entry{UserID: 1, TaskID: 2, Hours: 1}).Validate(); err == nil {
+		t.Error("Expected error for missing day, got nil")
+	}
+}
@@ -0,0 +1,177 @@
+package views
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+type (
+	DB struct {
+		pg *sql.DB
+	}
+
+	FindOptions struct {
+		IDs []int
+		// VisibleTo restricts the views to the ones owned by the user or shared.
+		VisibleTo int
+	}
+)
+
+// ErrViewExists is returned when the owner already has a view of the name.
+var ErrViewExists = errors.New("view already exists")
+
+const selectColumns = "saved_views.id,saved_views.owner_id,saved_views.name,saved_views.filter,saved_views.sort," +
+	"saved_views.columns,saved_views.shared,saved_views.created_at"
+
+func NewDB(pg *sql.DB) *DB {
+	return &DB{pg}
+}
+
+func (db *DB) Find(ctx context.Context, options FindOptions) ([]View, error) {
+	query, args := options.buildQuery()
+	return db.find(ctx, query, args...)
+}
+
+func (db *DB) FindOne(ctx context.Context, options FindOptions) (View, error) {
+	views, err := db.Find(ctx, options)
+	if err != nil {
+		return View{}, err
+	}
+	if len(views) == 0 {
+		return View{}, sql.ErrNoRows
+	}
+	return views[0], nil
+}
+
+func (db *DB) Insert(ctx context.Context, v View) (id int, err error) {
+	args, err := v.args()
+	if err != nil {
+		return 0, err
+	}
+	err = db.pg.QueryRowContext(ctx,
+		"INSERT INTO api.saved_views (owner_id,name,filter,sort,columns,shared) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+		args...,
+	).Scan(&id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return 0, ErrViewExists
+	}
+	return id, err
+}
+
+// Update changes the view, provided it is owned by its OwnerID.
+func (db *DB) Update(ctx context.Context, v View) error {
+	args, err := v.args()
+	if err != nil {
+		return err
+	}
+	var updatedID int
+	err = db.pg.QueryRowContext(ctx,
+		"UPDATE api.saved_views SET name = $2, filter = $3, sort = $4, columns = $5, shared = $6"+
+			" WHERE owner_id = $1 AND id = $7 RETURNING id",
+		append(args, v.ID)...,
+	).Scan(&updatedID)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrViewExists
+	}
+	return err
+}
+
+// Delete deletes the view, provided it is owned by ownerID.
+func (db *DB) Delete(ctx context.Context, id, ownerID int) error {
+	var deletedID int
+	return db.pg.QueryRowContext(ctx,
+		"DELETE FROM api.saved_views WHERE id = $1 AND owner_id = $2 RETURNING id", id, ownerID,
+	).Scan(&deletedID)
+}
+
+// Default returns the default view of the user, sql.ErrNoRows if the user has
+// none or it is no longer shared with them.
+func (db *DB) Default(ctx context.Context, userID int) (View, error) {
+	views, err := db.find(ctx, fmt.Sprintf("SELECT %s FROM api.saved_views"+
+		" JOIN api.default_views ON default_views.view_id = saved_views.id"+
+		" WHERE default_views.user_id = $1 AND (saved_views.owner_id = $1 OR saved_views.shared)", selectColumns),
+		userID,
+	)
+	if err != nil {
+		return View{}, err
+	}
+	if len(views) == 0 {
+		return View{}, sql.ErrNoRows
+	}
+	return views[0], nil
+}
+
+// SetDefault makes the view the default view of the user, provided they can
+// see it.
+func (db *DB) SetDefault(ctx context.Context, userID, viewID int) error {
+	var id int
+	return db.pg.QueryRowContext(ctx,
+		"INSERT INTO api.default_views (user_id,view_id)"+
+			" SELECT $1, id FROM api.saved_views WHERE id = $2 AND (owner_id = $1 OR shared)"+
+			" ON CONFLICT (user_id) DO UPDATE SET view_id = EXCLUDED.view_id RETURNING view_id",
+		userID, viewID,
+	).Scan(&id)
+}
+
+func (db *DB) ClearDefault(ctx context.Context, userID int) error {
+	_, err := db.pg.ExecContext(ctx, "DELETE FROM api.default_views WHERE user_id = $1", userID)
+	return err
+}
+
+func (db *DB) find(ctx context.Context, query string, args ...interface{}) ([]View, error) {
+	rows, err := db.pg.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var views []View
+	for rows.Next() {
+		var v View
+		if err := rows.Scan(
+			&v.ID, &v.OwnerID, &v.Name, &v.Filter, &v.Sort, pq.Array(&v.Columns), &v.Shared, &v.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		views = append(views, v)
+	}
+	return views, rows.Err()
+}
+
+func (v View) args() ([]interface{}, error) {
+	if err := v.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid view: %w", err)
+	}
+	columns := v.Columns
+	if columns == nil {
+		columns = []string{}
+	}
+	return []interface{}{v.OwnerID, strings.TrimSpace(v.Name), v.Filter, v.Sort, pq.Array(columns), v.Shared}, nil
+}
+
+func (opt FindOptions) buildQuery() (query string, args []interface{}) {
+	var clauses []string
+	args = make([]interface{}, 0)
+
+	if len(opt.IDs) > 0 {
+		args = append(args, pq.Array(opt.IDs))
+		clauses = append(clauses, fmt.Sprintf("saved_views.id = ANY($%d)", len(args)))
+	}
+	if opt.VisibleTo > 0 {
+		args = append(args, opt.VisibleTo)
+		clauses = append(clauses, fmt.Sprintf("(saved_views.owner_id = $%d OR saved_views.shared)", len(args)))
+	}
+
+	stmt := fmt.Sprintf("SELECT %s FROM api.saved_views", selectColumns)
+	if len(clauses) > 0 {
+		stmt = fmt.Sprintf("%s WHERE %s", stmt, strings.Join(clauses, " AND "))
+	}
+	return stmt + " ORDER BY saved_views.id ASC", args
+}
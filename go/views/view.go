@@ -0,0 +1,61 @@
+package views
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"siransbach/taskmanagementapi/tasks"
+)
+
+// View is a saved combination of a filter, a sort and columns of task
+// listings. Only its owner sees it, unless it is shared.
+type View struct {
+	ID        int       `json:"id"`
+	OwnerID   int       `json:"ownerId"`
+	Name      string    `json:"name"`
+	Filter    string    `json:"filter"`
+	Sort      string    `json:"sort"`
+	Columns   []string  `json:"columns"`
+	Shared    bool      `json:"shared"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (v View) Validate() error {
+	if strings.TrimSpace(v.Name) == "" {
+		return errors.New("missing name")
+	}
+	if v.OwnerID <= 0 {
+		return errors.New("invalid owner")
+	}
+	_, err := v.Apply(tasks.FindOptions{})
+	return err
+}
+
+// Apply narrows opts down to the tasks of the view, sorted by its sort and
+// loading its columns only.
+func (v View) Apply(opts tasks.FindOptions) (tasks.FindOptions, error) {
+	if strings.TrimSpace(v.Filter) != "" {
+		filter, err := tasks.ParseFilter(v.Filter)
+		if err != nil {
+			return opts, fmt.Errorf("invalid filter: %w", err)
+		}
+		opts.Filter = filter
+	}
+	if strings.TrimSpace(v.Sort) != "" {
+		sort, err := tasks.ParseSort(v.Sort)
+		if err != nil {
+			return opts, err
+		}
+		opts.Sort = sort
+	}
+	if len(v.Columns) > 0 {
+		fields, err := tasks.ParseFields(strings.Join(v.Columns, ","))
+		if err != nil {
+			return opts, err
+		}
+		opts.Fields = fields
+	}
+	return opts, nil
+}
@@ -0,0 +1,67 @@
+package views
+
+import (
+	"reflect"
+	"testing"
+
+	"siransbach/taskmanagementapi/tasks"
+)
+
+func TestView_Validate(t *testing.T) {
+	cases := []struct {
+		name string
+		view View
+		err  string
+	}{
+		{name: "valid", view: View{OwnerID: 3, Name: "Due soon", Filter: "due_date < now() + 3d", Sort: "-due_date,title", Columns: []string{"title", "dueDate"}}},
+		{name: "name only", view: View{OwnerID: 3, Name: "All"}},
+		{name: "missing name", view: View{OwnerID: 3, Name: " "}, err: "missing name"},
+		{name: "missing owner", view: View{Name: "All"}, err: "invalid owner"},
+		{name: "invalid filter", view: View{OwnerID: 3, Name: "All", Filter: "owner = 3"}, err: `invalid filter: unknown field "owner" at position 1`},
+		{name: "invalid sort", view: View{OwnerID: 3, Name: "All", Sort: "-owner"}, err: "invalid column: tasks.owner"},
+		{name: "invalid column", view: View{OwnerID: 3, Name: "All", Columns: []string{"owner"}}, err: "invalid field: owner"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.view.Validate()
+			if tc.err == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.err != "" && (err == nil || err.Error() != tc.err) {
+				t.Errorf("expected error %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestView_Apply(t *testing.T) {
+	v := View{OwnerID: 3, Name: "Mine", Filter: "status = PENDING", Sort: "-due_date", Columns: []string{"title"}}
+	opts, err := v.Apply(tasks.FindOptions{AnyAssigneeIDs: []int{3}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(opts.AnyAssigneeIDs, []int{3}) {
+		t.Errorf("expected the assignees to be kept, got %v", opts.AnyAssigneeIDs)
+	}
+	if opts.Filter == nil {
+		t.Error("expected a filter")
+	}
+	if expected := []tasks.SortKey{{Column: tasks.DueDateCol, Order: tasks.SortOrderDescending}}; !reflect.DeepEqual(opts.Sort, expected) {
+		t.Errorf("expected sort %v, got %v", expected, opts.Sort)
+	}
+	if !reflect.DeepEqual(opts.Fields, []string{"title"}) {
+		t.Errorf("expected fields [title], got %v", opts.Fields)
+	}
+}
+
+func TestFindOptions_BuildQuery(t *testing.T) {
+	query, args := FindOptions{IDs: []int{4}, VisibleTo: 3}.buildQuery()
+	expected := "SELECT " + selectColumns + " FROM api.saved_views" +
+		" WHERE saved_views.id = ANY($1) AND (saved_views.owner_id = $2 OR saved_views.shared) ORDER BY saved_views.id ASC"
+	if query != expected {
+		t.Errorf("expected query %q, got %q", expected, query)
+	}
+	if len(args) != 2 || args[1] != 3 {
+		t.Errorf("unexpected args %v", args)
+	}
+}
@@ -0,0 +1,299 @@
+package worklogs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"siransbach/taskmanagementapi/tasks"
+)
+
+type (
+	DB struct {
+		pg tasks.Session
+	}
+
+	FindOptions struct {
+		IDs     []int
+		TaskIDs []int
+		UserIDs []int
+		// From and To bound the start of the worklogs, when set
+		From, To time.Time
+		// Running only matches running timers
+		Running bool
+	}
+
+	TotalsOptions struct {
+		GroupBy     []GroupBy
+		Granularity tasks.Granularity
+		TaskIDs     []int
+		UserIDs     []int
+		ProjectIDs  []int
+		From, To    time.Time
+	}
+)
+
+var (
+	// ErrTimerRunning is returned by StartTimer when the user already has a
+	// running timer.
+	ErrTimerRunning = errors.New("a timer is already running")
+	// ErrNoTimer is returned by StopTimer when the user has no running timer.
+	ErrNoTimer = errors.New("no timer is running")
+)
+
+// minutesExpr is the duration of a worklog in minutes; running timers count
+// until now.
+const minutesExpr = "ROUND(EXTRACT(EPOCH FROM COALESCE(worklogs.ended_at, NOW()) - worklogs.started_at) / 60)::int"
+
+const selectColumns = "worklogs.id,worklogs.task_id,worklogs.user_id,users.username,worklogs.started_at,worklogs.ended_at," +
+	minutesExpr + ",worklogs.description,worklogs.created_at"
+
+func NewDB(pg tasks.Session) *DB {
+	return &DB{pg}
+}
+
+func (db *DB) Find(ctx context.Context, options FindOptions) ([]Worklog, error) {
+	query, args := options.buildQuery()
+	rows, err := db.pg.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var worklogs []Worklog
+	for rows.Next() {
+		var (
+			w       Worklog
+			endedAt sql.NullTime
+		)
+		if err := rows.Scan(
+			&w.ID, &w.TaskID, &w.UserID, &w.Username, &w.StartedAt, &endedAt, &w.Minutes, &w.Description, &w.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if endedAt.Valid {
+			w.EndedAt = &endedAt.Time
+		}
+		worklogs = append(worklogs, w)
+	}
+	return worklogs, rows.Err()
+}
+
+func (db *DB) FindOne(ctx context.Context, options FindOptions) (Worklog, error) {
+	worklogs, err := db.Find(ctx, options)
+	if err != nil {
+		return Worklog{}, err
+	}
+	if len(worklogs) == 0 {
+		return Worklog{}, sql.ErrNoRows
+	}
+	return worklogs[0], nil
+}
+
+// StartTimer starts logging time of userID on a task. A user has at most one
+// running timer, which the unique index on running worklogs enforces even
+// under concurrent requests.
+func (db *DB) StartTimer(ctx context.Context, taskID, userID int, description string) (id int, err error) {
+	err = db.pg.QueryRowContext(ctx,
+		"INSERT INTO api.worklogs (task_id,user_id,started_at,description) VALUES ($1, $2, NOW(), $3) RETURNING id",
+		taskID, userID, description,
+	).Scan(&id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return 0, ErrTimerRunning
+	}
+	return id, err
+}
+
+// StopTimer stops the running timer of userID, turning it into a worklog, and
+// takes the logged time off the remaining estimate of the task.
+func (db *DB) StopTimer(ctx context.Context, userID int) (Worklog, error) {
+	var id int
+	err := db.pg.QueryRowContext(ctx, `
+		WITH worklogs AS (
+			UPDATE api.worklogs SET ended_at = NOW()
+			WHERE user_id = $1 AND ended_at IS NULL
+			RETURNING id, task_id, started_at, ended_at
+		), estimate AS (
+			UPDATE api.tasks SET remaining_estimate = GREATEST(remaining_estimate - `+minutesExpr+`, 0)
+			FROM worklogs WHERE tasks.id = worklogs.task_id AND tasks.remaining_estimate IS NOT NULL
+		)
+		SELECT id FROM worklogs`,
+		userID,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Worklog{}, ErrNoTimer
+	}
+	if err != nil {
+		return Worklog{}, err
+	}
+	return db.FindOne(ctx, FindOptions{IDs: []int{id}})
+}
+
+// Insert logs time manually, taking it off the remaining estimate of the task.
+func (db *DB) Insert(ctx context.Context, w Worklog) (id int, err error) {
+	if err := w.Validate(); err != nil {
+		return 0, fmt.Errorf("invalid worklog: %w", err)
+	}
+	err = db.pg.QueryRowContext(ctx, `
+		WITH worklogs AS (
+			INSERT INTO api.worklogs (task_id,user_id,started_at,ended_at,description)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, task_id, started_at, ended_at
+		), estimate AS (
+			UPDATE api.tasks SET remaining_estimate = GREATEST(remaining_estimate - `+minutesExpr+`, 0)
+			FROM worklogs WHERE tasks.id = worklogs.task_id AND tasks.remaining_estimate IS NOT NULL
+		)
+		SELECT id FROM worklogs`,
+		w.TaskID, w.UserID, w.StartedAt, w.EndedAt, w.Description,
+	).Scan(&id)
+	return id, err
+}
+
+// Delete deletes a finished worklog of userID. The remaining estimate of the
+// task is left as is.
+func (db *DB) Delete(ctx context.Context, id, userID int) error {
+	var deletedID int
+	return db.pg.QueryRowContext(ctx,
+		"DELETE FROM api.worklogs WHERE id = $1 AND user_id = $2 AND ended_at IS NOT NULL RETURNING id", id, userID,
+	).Scan(&deletedID)
+}
+
+// Totals sums up the time logged by finished worklogs, grouped by task, user
+// and/or period.
+func (db *DB) Totals(ctx context.Context, options TotalsOptions) ([]Total, error) {
+	query, args := options.buildQuery()
+	rows, err := db.pg.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []Total
+	for rows.Next() {
+		var (
+			t      Total
+			taskID sql.NullInt64
+			userID sql.NullInt64
+			period sql.NullTime
+		)
+		if err := rows.Scan(&taskID, &userID, &period, &t.Minutes); err != nil {
+			return nil, err
+		}
+		t.TaskID = int(taskID.Int64)
+		t.UserID = int(userID.Int64)
+		if period.Valid {
+			t.Period = &period.Time
+		}
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
+}
+
+func (opt FindOptions) buildQuery() (query string, args []interface{}) {
+	var clauses []string
+	args = make([]interface{}, 0)
+
+	if len(opt.IDs) > 0 {
+		args = append(args, pq.Array(opt.IDs))
+		clauses = append(clauses, fmt.Sprintf("worklogs.id = ANY($%d)", len(args)))
+	}
+	if len(opt.TaskIDs) > 0 {
+		args = append(args, pq.Array(opt.TaskIDs))
+		clauses = append(clauses, fmt.Sprintf("worklogs.task_id = ANY($%d)", len(args)))
+	}
+	if len(opt.UserIDs) > 0 {
+		args = append(args, pq.Array(opt.UserIDs))
+		clauses = append(clauses, fmt.Sprintf("worklogs.user_id = ANY($%d)", len(args)))
+	}
+	if !opt.From.IsZero() {
+		args = append(args, opt.From)
+		clauses = append(clauses, fmt.Sprintf("worklogs.started_at >= $%d", len(args)))
+	}
+	if !opt.To.IsZero() {
+		args = append(args, opt.To)
+		clauses = append(clauses, fmt.Sprintf("worklogs.started_at < $%d", len(args)))
+	}
+	if opt.Running {
+		clauses = append(clauses, "worklogs.ended_at IS NULL")
+	}
+
+	stmt := fmt.Sprintf("SELECT %s FROM api.worklogs JOIN auth.users ON users.id = worklogs.user_id", selectColumns)
+	if len(clauses) > 0 {
+		stmt = fmt.Sprintf("%s WHERE %s", stmt, strings.Join(clauses, " AND "))
+	}
+	return stmt + " ORDER BY worklogs.started_at DESC, worklogs.id DESC", args
+}
+
+func (opt TotalsOptions) buildQuery() (query string, args []interface{}) {
+	clauses := []string{"worklogs.ended_at IS NOT NULL"}
+	args = make([]interface{}, 0)
+
+	if len(opt.TaskIDs) > 0 {
+		args = append(args, pq.Array(opt.TaskIDs))
+		clauses = append(clauses, fmt.Sprintf("worklogs.task_id = ANY($%d)", len(args)))
+	}
+	if len(opt.UserIDs) > 0 {
+		args = append(args, pq.Array(opt.UserIDs))
+		clauses = append(clauses, fmt.Sprintf("worklogs.user_id = ANY($%d)", len(args)))
+	}
+	if len(opt.ProjectIDs) > 0 {
+		args = append(args, pq.Array(opt.ProjectIDs))
+		clauses = append(clauses, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM api.tasks WHERE tasks.id = worklogs.task_id AND tasks.project_id = ANY($%d))", len(args)))
+	}
+	if !opt.From.IsZero() {
+		args = append(args, opt.From)
+		clauses = append(clauses, fmt.Sprintf("worklogs.started_at >= $%d", len(args)))
+	}
+	if !opt.To.IsZero() {
+		args = append(args, opt.To)
+		clauses = append(clauses, fmt.Sprintf("worklogs.started_at < $%d", len(args)))
+	}
+
+	// columns not grouped by are selected as NULL so that rows always scan the
+	// same way
+	cols := map[GroupBy]string{
+		GroupByTask:   "NULL::int",
+		GroupByUser:   "NULL::int",
+		GroupByPeriod: "NULL::timestamptz",
+	}
+	var groups []string
+	for _, g := range []GroupBy{GroupByTask, GroupByUser, GroupByPeriod} {
+		if !containsGroup(opt.GroupBy, g) {
+			continue
+		}
+		switch g {
+		case GroupByTask:
+			cols[g] = "worklogs.task_id"
+		case GroupByUser:
+			cols[g] = "worklogs.user_id"
+		case GroupByPeriod:
+			cols[g] = fmt.Sprintf("date_trunc('%s', worklogs.started_at)", periodTrunc(opt.Granularity))
+		}
+		groups = append(groups, cols[g])
+	}
+
+	stmt := fmt.Sprintf(
+		"SELECT %s, %s, %s, COALESCE(SUM(%s), 0)::int FROM api.worklogs WHERE %s",
+		cols[GroupByTask], cols[GroupByUser], cols[GroupByPeriod], minutesExpr, strings.Join(clauses, " AND "),
+	)
+	if len(groups) > 0 {
+		stmt = fmt.Sprintf("%s GROUP BY %s ORDER BY %s", stmt, strings.Join(groups, ", "), strings.Join(groups, ", "))
+	}
+	return stmt, args
+}
+
+func containsGroup(groups []GroupBy, g GroupBy) bool {
+	for _, group := range groups {
+		if group == g {
+			return true
+		}
+	}
+	return false
+}
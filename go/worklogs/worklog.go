@@ -0,0 +1,85 @@
+package worklogs
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"siransbach/taskmanagementapi/tasks"
+)
+
+type (
+	// Worklog is time spent by a user on a task, logged either with a timer or
+	// manually. A running timer has no EndedAt yet.
+	Worklog struct {
+		ID          int        `json:"id"`
+		TaskID      int        `json:"taskId"`
+		UserID      int        `json:"userId"`
+		Username    string     `json:"username"`
+		StartedAt   time.Time  `json:"startedAt"`
+		EndedAt     *time.Time `json:"endedAt,omitempty"`
+		Minutes     int        `json:"minutes"`
+		Description string     `json:"description"`
+		CreatedAt   time.Time  `json:"createdAt"`
+	}
+
+	// Total is the time logged by a group of worklogs, identified by whichever
+	// of TaskID, UserID and Period the worklogs were grouped by.
+	Total struct {
+		TaskID  int        `json:"taskId,omitempty"`
+		UserID  int        `json:"userId,omitempty"`
+		Period  *time.Time `json:"period,omitempty"`
+		Minutes int        `json:"minutes"`
+	}
+
+	GroupBy string
+)
+
+const (
+	GroupByTask   GroupBy = "task"
+	GroupByUser   GroupBy = "user"
+	GroupByPeriod GroupBy = "period"
+)
+
+// maxWorklog is the longest time a single worklog can cover.
+const maxWorklog = 24 * time.Hour
+
+func ParseGroupBy(str string) (GroupBy, error) {
+	for _, g := range []GroupBy{GroupByTask, GroupByUser, GroupByPeriod} {
+		if str == string(g) {
+			return g, nil
+		}
+	}
+	return "", fmt.Errorf("invalid groupBy: %s", str)
+}
+
+// Validate checks a manual worklog, which must be finished.
+func (w Worklog) Validate() error {
+	if w.TaskID <= 0 {
+		return errors.New("invalid task")
+	}
+	if w.UserID <= 0 {
+		return errors.New("invalid user")
+	}
+	if w.StartedAt.IsZero() {
+		return errors.New("missing start")
+	}
+	if w.EndedAt == nil || !w.EndedAt.After(w.StartedAt) {
+		return errors.New("worklog must end after it starts")
+	}
+	if w.EndedAt.Sub(w.StartedAt) > maxWorklog {
+		return fmt.Errorf("worklog cannot exceed %s", maxWorklog)
+	}
+	if w.EndedAt.After(time.Now()) {
+		return errors.New("worklog cannot end in the future")
+	}
+	return nil
+}
+
+// periodTrunc returns the date_trunc field of a granularity.
+func periodTrunc(g tasks.Granularity) string {
+	if g == tasks.GranularityWeek {
+		return "week"
+	}
+	return "day"
+}
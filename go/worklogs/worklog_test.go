@@ -0,0 +1,89 @@
+package worklogs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+
+	"siransbach/taskmanagementapi/tasks"
+)
+
+func TestWorklog_Validate(t *testing.T) {
+	now := time.Now()
+	hourAgo := now.Add(-time.Hour)
+	tomorrow := now.Add(24 * time.Hour)
+	twoDaysAgo := now.Add(-48 * time.Hour)
+
+	cases := []struct {
+		name    string
+		worklog Worklog
+		wantErr bool
+	}{
+		{name: "valid", worklog: Worklog{TaskID: 1, UserID: 2, StartedAt: hourAgo.Add(-time.Hour), EndedAt: &hourAgo}},
+		{name: "missing task", worklog: Worklog{UserID: 2, StartedAt: hourAgo.Add(-time.Hour), EndedAt: &hourAgo}, wantErr: true},
+		{name: "not ended", worklog: Worklog{TaskID: 1, UserID: 2, StartedAt: hourAgo}, wantErr: true},
+		{name: "ends before start", worklog: Worklog{TaskID: 1, UserID: 2, StartedAt: now, EndedAt: &hourAgo}, wantErr: true},
+		{name: "ends in the future", worklog: Worklog{TaskID: 1, UserID: 2, StartedAt: hourAgo, EndedAt: &tomorrow}, wantErr: true},
+		{name: "too long", worklog: Worklog{TaskID: 1, UserID: 2, StartedAt: twoDaysAgo, EndedAt: &hourAgo}, wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.worklog.Validate()
+			if tc.wantErr && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tc.wantErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestTotalsOptions_BuildQuery(t *testing.T) {
+	from := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name  string
+		opts  TotalsOptions
+		query string
+		args  []interface{}
+	}{
+		{
+			name: "overall",
+			opts: TotalsOptions{},
+			query: "SELECT NULL::int, NULL::int, NULL::timestamptz, COALESCE(SUM(" + minutesExpr + "), 0)::int" +
+				" FROM api.worklogs WHERE worklogs.ended_at IS NOT NULL",
+			args: []interface{}{},
+		},
+		{
+			name: "by user and week",
+			opts: TotalsOptions{
+				GroupBy:     []GroupBy{GroupByPeriod, GroupByUser},
+				Granularity: tasks.GranularityWeek,
+				ProjectIDs:  []int{2},
+				From:        from,
+			},
+			query: "SELECT NULL::int, worklogs.user_id, date_trunc('week', worklogs.started_at)," +
+				" COALESCE(SUM(" + minutesExpr + "), 0)::int FROM api.worklogs WHERE worklogs.ended_at IS NOT NULL" +
+				" AND EXISTS (SELECT 1 FROM api.tasks WHERE tasks.id = worklogs.task_id AND tasks.project_id = ANY($1))" +
+				" AND worklogs.started_at >= $2" +
+				" GROUP BY worklogs.user_id, date_trunc('week', worklogs.started_at)" +
+				" ORDER BY worklogs.user_id, date_trunc('week', worklogs.started_at)",
+			args: []interface{}{pq.Array([]int{2}), from},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, args := tc.opts.buildQuery()
+			if query != tc.query {
+				t.Errorf("expected query %q, got %q", tc.query, query)
+			}
+			if !reflect.DeepEqual(args, tc.args) {
+				t.Errorf("expected args %v, got %v", tc.args, args)
+			}
+		})
+	}
+}
@@ -3,18 +3,588 @@
 CREATE SCHEMA IF NOT EXISTS api;
 SET search_path TO api,public;
 
+-- trigram indexes back the case-insensitive text match on tasks
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
 CREATE TYPE api.task_status AS ENUM ('PENDING', 'IN_PROGRESS', 'COMPLETED');
+CREATE TYPE api.task_priority AS ENUM ('LOW', 'MEDIUM', 'HIGH', 'URGENT');
+
+CREATE TABLE IF NOT EXISTS api.recurring_tasks
+(
+    id                 SERIAL PRIMARY KEY,
+    title              VARCHAR(255) NOT NULL,
+    description        TEXT,
+    assigned_user_id   INT REFERENCES auth.users (id),
+    rrule              TEXT         NOT NULL,
+    dtstart            TIMESTAMPTZ  NOT NULL,
+    timezone           VARCHAR(64)  NOT NULL DEFAULT 'UTC',
+    created_at         TIMESTAMPTZ DEFAULT NOW(),
+    -- occurrences before it were materialized already, and are not recreated
+    -- once deleted
+    materialized_until TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS api.projects
+(
+    id          SERIAL PRIMARY KEY,
+    name        VARCHAR(255) NOT NULL,
+    description TEXT         NOT NULL DEFAULT '',
+    owner_id    INT          NOT NULL REFERENCES auth.users (id),
+    archived    BOOLEAN      NOT NULL DEFAULT FALSE,
+    created_at  TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS api.project_members
+(
+    project_id INT NOT NULL REFERENCES api.projects (id) ON DELETE CASCADE,
+    user_id    INT NOT NULL REFERENCES auth.users (id),
+    PRIMARY KEY (project_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_members_user_id ON api.project_members (user_id);
+
+-- new tasks go to the bottom of their board column
+CREATE SEQUENCE IF NOT EXISTS api.tasks_board_rank_seq;
 
 CREATE TABLE IF NOT EXISTS api.tasks
 (
-    id               SERIAL PRIMARY KEY,
-    title            VARCHAR(255) NOT NULL,
-    description      TEXT,
-    created_at       TIMESTAMPTZ     DEFAULT NOW(),
-    due_date         TIMESTAMPTZ,
-    status           api.task_status DEFAULT 'PENDING',
-    assigned_user_id INT REFERENCES auth.users (id)
+    id                 SERIAL PRIMARY KEY,
+    title              VARCHAR(255)      NOT NULL,
+    description        TEXT,
+    created_at         TIMESTAMPTZ       DEFAULT NOW(),
+    due_date           TIMESTAMPTZ,
+    status             api.task_status   DEFAULT 'PENDING',
+    priority           api.task_priority NOT NULL DEFAULT 'MEDIUM',
+    labels             TEXT[]            NOT NULL DEFAULT '{}',
+    assigned_user_id   INT REFERENCES auth.users (id),
+    project_id         INT REFERENCES api.projects (id),
+    board_rank         DOUBLE PRECISION  NOT NULL DEFAULT nextval('api.tasks_board_rank_seq') * 1024,
+    parent_task_id     INT REFERENCES api.tasks (id) ON DELETE CASCADE,
+    recurring_task_id  INT REFERENCES api.recurring_tasks (id) ON DELETE SET NULL,
+    occurrence_at      TIMESTAMPTZ,
+    -- in minutes; the remaining estimate goes down as time is logged
+    original_estimate  INT CHECK (original_estimate >= 0),
+    remaining_estimate INT CHECK (remaining_estimate >= 0),
+    -- maintained by the tasks_search_vector trigger
+    search_vector      TSVECTOR,
+    -- maintained by the tasks_completed_at trigger
+    completed_at       TIMESTAMPTZ
 );
 
 CREATE INDEX IF NOT EXISTS idx_tasks_status ON api.tasks (status);
-CREATE INDEX IF NOT EXISTS idx_tasks_assigned_user_id ON api.tasks (assigned_user_id);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_tasks_assigned_user_id ON api.tasks (assigned_user_id);
+CREATE INDEX IF NOT EXISTS idx_tasks_board ON api.tasks (project_id, status, board_rank);
+CREATE INDEX IF NOT EXISTS idx_tasks_due_date ON api.tasks (due_date);
+CREATE INDEX IF NOT EXISTS idx_tasks_created_at ON api.tasks (created_at);
+-- overdue and due-soon tasks are the open ones
+CREATE INDEX IF NOT EXISTS idx_tasks_open_due_date ON api.tasks (due_date) WHERE status <> 'COMPLETED';
+CREATE INDEX IF NOT EXISTS idx_tasks_title_trgm ON api.tasks USING gin (title gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_tasks_description_trgm ON api.tasks USING gin (description gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_tasks_search_vector ON api.tasks USING gin (search_vector);
+CREATE INDEX IF NOT EXISTS idx_tasks_completed_at ON api.tasks (completed_at) WHERE completed_at IS NOT NULL;
+
+-- one task per occurrence of a recurring task, which makes materialization idempotent
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_occurrence ON api.tasks (recurring_task_id, occurrence_at);
+CREATE INDEX IF NOT EXISTS idx_tasks_parent_task_id ON api.tasks (parent_task_id);
+
+CREATE TABLE IF NOT EXISTS api.task_checklist_items
+(
+    id       SERIAL PRIMARY KEY,
+    task_id  INT     NOT NULL REFERENCES api.tasks (id) ON DELETE CASCADE,
+    position INT     NOT NULL,
+    text     TEXT    NOT NULL,
+    done     BOOLEAN NOT NULL DEFAULT FALSE,
+    done_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_checklist_items_task_id ON api.task_checklist_items (task_id, position);
+
+CREATE TABLE IF NOT EXISTS api.task_templates
+(
+    id               SERIAL PRIMARY KEY,
+    name             VARCHAR(255)      NOT NULL UNIQUE,
+    title            VARCHAR(255)      NOT NULL,
+    description      TEXT              NOT NULL DEFAULT '',
+    due_offset_hours INT               NOT NULL,
+    priority         api.task_priority NOT NULL DEFAULT 'MEDIUM',
+    labels           TEXT[]            NOT NULL DEFAULT '{}',
+    checklist        TEXT[]            NOT NULL DEFAULT '{}',
+    -- [{"title": "...", "description": "...", "dueOffsetHours": 24, "checklist": ["..."]}]
+    subtasks         JSONB             NOT NULL DEFAULT '[]',
+    created_at       TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- every assignee of a task, including the primary one mirrored from tasks.assigned_user_id
+CREATE TABLE IF NOT EXISTS api.task_assignees
+(
+    task_id    INT     NOT NULL REFERENCES api.tasks (id) ON DELETE CASCADE,
+    user_id    INT     NOT NULL REFERENCES auth.users (id),
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (task_id, user_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_task_assignees_primary ON api.task_assignees (task_id) WHERE is_primary;
+CREATE INDEX IF NOT EXISTS idx_task_assignees_user_id ON api.task_assignees (user_id);
+
+CREATE TABLE IF NOT EXISTS api.task_watchers
+(
+    task_id INT NOT NULL REFERENCES api.tasks (id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES auth.users (id),
+    PRIMARY KEY (task_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_watchers_user_id ON api.task_watchers (user_id);
+
+-- keeps the primary row of api.task_assignees in sync with tasks.assigned_user_id
+CREATE OR REPLACE FUNCTION api.sync_primary_assignee() RETURNS TRIGGER AS
+$$
+BEGIN
+    DELETE
+    FROM api.task_assignees
+    WHERE task_id = NEW.id
+      AND is_primary
+      AND user_id IS DISTINCT FROM NEW.assigned_user_id;
+    IF NEW.assigned_user_id IS NOT NULL THEN
+        INSERT INTO api.task_assignees (task_id, user_id, is_primary)
+        VALUES (NEW.id, NEW.assigned_user_id, TRUE)
+        ON CONFLICT (task_id, user_id) DO UPDATE SET is_primary = TRUE;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tasks_sync_primary_assignee
+    AFTER INSERT OR UPDATE OF assigned_user_id
+    ON api.tasks
+    FOR EACH ROW
+EXECUTE FUNCTION api.sync_primary_assignee();
+
+-- every change of tasks.assigned_user_id, NULL meaning the task went (back) to the backlog
+CREATE TABLE IF NOT EXISTS api.task_assignment_history
+(
+    id         SERIAL PRIMARY KEY,
+    task_id    INT         NOT NULL REFERENCES api.tasks (id) ON DELETE CASCADE,
+    user_id    INT REFERENCES auth.users (id),
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_assignment_history_task_id ON api.task_assignment_history (task_id, changed_at);
+CREATE INDEX IF NOT EXISTS idx_tasks_unassigned ON api.tasks (created_at) WHERE assigned_user_id IS NULL;
+
+CREATE OR REPLACE FUNCTION api.record_assignment() RETURNS TRIGGER AS
+$$
+BEGIN
+    IF TG_OP = 'INSERT' OR NEW.assigned_user_id IS DISTINCT FROM OLD.assigned_user_id THEN
+        INSERT INTO api.task_assignment_history (task_id, user_id, changed_at)
+        VALUES (NEW.id, NEW.assigned_user_id, CASE WHEN TG_OP = 'INSERT' THEN NEW.created_at ELSE NOW() END);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tasks_record_assignment
+    AFTER INSERT OR UPDATE OF assigned_user_id
+    ON api.tasks
+    FOR EACH ROW
+EXECUTE FUNCTION api.record_assignment();
+
+-- the text search configuration of the full-text search, english unless set otherwise with e.g.
+--   ALTER DATABASE task_management SET api.search_language = 'german';
+-- existing tasks are indexed again with UPDATE api.tasks SET title = title
+CREATE OR REPLACE FUNCTION api.search_language() RETURNS regconfig AS
+$$
+SELECT COALESCE(NULLIF(current_setting('api.search_language', TRUE), ''), 'english')::regconfig;
+$$ LANGUAGE sql STABLE;
+
+-- titles weigh more than descriptions in the ranking of search results
+CREATE OR REPLACE FUNCTION api.update_search_vector() RETURNS TRIGGER AS
+$$
+BEGIN
+    NEW.search_vector := setweight(to_tsvector(api.search_language(), NEW.title), 'A') ||
+                         setweight(to_tsvector(api.search_language(), COALESCE(NEW.description, '')), 'B');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tasks_search_vector
+    BEFORE INSERT OR UPDATE OF title, description
+    ON api.tasks
+    FOR EACH ROW
+EXECUTE FUNCTION api.update_search_vector();
+
+-- completed_at is when a task was last completed, the end of its cycle time
+CREATE OR REPLACE FUNCTION api.set_completed_at() RETURNS TRIGGER AS
+$$
+BEGIN
+    IF NEW.status IS DISTINCT FROM 'COMPLETED' THEN
+        NEW.completed_at := NULL;
+    ELSIF TG_OP = 'INSERT' THEN
+        NEW.completed_at := COALESCE(NEW.completed_at, NOW());
+    ELSIF OLD.status IS DISTINCT FROM 'COMPLETED' THEN
+        NEW.completed_at := NOW();
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tasks_completed_at
+    BEFORE INSERT OR UPDATE OF status
+    ON api.tasks
+    FOR EACH ROW
+EXECUTE FUNCTION api.set_completed_at();
+
+CREATE TYPE api.custom_field_type AS ENUM ('TEXT', 'NUMBER', 'DATE', 'SINGLE_SELECT', 'MULTI_SELECT', 'USER');
+
+CREATE TABLE IF NOT EXISTS api.custom_fields
+(
+    id         SERIAL PRIMARY KEY,
+    project_id INT                   NOT NULL REFERENCES api.projects (id) ON DELETE CASCADE,
+    key        VARCHAR(63)           NOT NULL,
+    name       VARCHAR(255)          NOT NULL,
+    type       api.custom_field_type NOT NULL,
+    options    TEXT[]                NOT NULL DEFAULT '{}',
+    required   BOOLEAN               NOT NULL DEFAULT FALSE,
+    UNIQUE (project_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_custom_fields_key ON api.custom_fields (key);
+
+-- values are JSON scalars (strings, numbers, YYYY-MM-DD dates, user IDs) or arrays of strings for multi select fields
+CREATE TABLE IF NOT EXISTS api.task_custom_values
+(
+    task_id  INT   NOT NULL REFERENCES api.tasks (id) ON DELETE CASCADE,
+    field_id INT   NOT NULL REFERENCES api.custom_fields (id) ON DELETE CASCADE,
+    value    JSONB NOT NULL,
+    PRIMARY KEY (task_id, field_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_custom_values_field_id ON api.task_custom_values (field_id);
+
+-- time logged on tasks, either with a timer or manually; a running timer has no ended_at yet
+CREATE TABLE IF NOT EXISTS api.worklogs
+(
+    id          SERIAL PRIMARY KEY,
+    task_id     INT         NOT NULL REFERENCES api.tasks (id) ON DELETE CASCADE,
+    user_id     INT         NOT NULL REFERENCES auth.users (id),
+    started_at  TIMESTAMPTZ NOT NULL,
+    ended_at    TIMESTAMPTZ CHECK (ended_at >= started_at),
+    description TEXT        NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_worklogs_task_id ON api.worklogs (task_id);
+CREATE INDEX IF NOT EXISTS idx_worklogs_user_id ON api.worklogs (user_id, started_at);
+-- a user has at most one running timer
+CREATE UNIQUE INDEX IF NOT EXISTS idx_worklogs_running ON api.worklogs (user_id) WHERE ended_at IS NULL;
+
+CREATE TYPE api.timesheet_status AS ENUM ('DRAFT', 'SUBMITTED', 'APPROVED', 'REJECTED');
+
+-- weekly timesheets, weeks starting on Monday; entries of submitted and approved weeks are locked
+CREATE TABLE IF NOT EXISTS api.timesheets
+(
+    id           SERIAL PRIMARY KEY,
+    user_id      INT                  NOT NULL REFERENCES auth.users (id),
+    week_start   DATE                 NOT NULL CHECK (EXTRACT(ISODOW FROM week_start) = 1),
+    status       api.timesheet_status NOT NULL DEFAULT 'DRAFT',
+    submitted_at TIMESTAMPTZ,
+    reviewer_id  INT REFERENCES auth.users (id),
+    reviewed_at  TIMESTAMPTZ,
+    comment      TEXT                 NOT NULL DEFAULT '',
+    UNIQUE (user_id, week_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_timesheets_status ON api.timesheets (status, week_start);
+
+CREATE TABLE IF NOT EXISTS api.timesheet_entries
+(
+    id      SERIAL PRIMARY KEY,
+    user_id INT           NOT NULL REFERENCES auth.users (id),
+    task_id INT           NOT NULL REFERENCES api.tasks (id) ON DELETE CASCADE,
+    day     DATE          NOT NULL,
+    hours   NUMERIC(4, 2) NOT NULL CHECK (hours > 0 AND hours <= 24),
+    note    TEXT          NOT NULL DEFAULT '',
+    UNIQUE (user_id, task_id, day)
+);
+
+CREATE INDEX IF NOT EXISTS idx_timesheet_entries_user_day ON api.timesheet_entries (user_id, day);
+
+CREATE TABLE IF NOT EXISTS api.saved_views
+(
+    id         SERIAL PRIMARY KEY,
+    owner_id   INT          NOT NULL REFERENCES auth.users (id),
+    name       VARCHAR(255) NOT NULL,
+    -- filter expression, e.g. status = PENDING and due_date < now() + 3d
+    filter     TEXT         NOT NULL DEFAULT '',
+    -- e.g. -due_date,title
+    sort       TEXT         NOT NULL DEFAULT '',
+    columns    TEXT[]       NOT NULL DEFAULT '{}',
+    shared     BOOLEAN      NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (owner_id, name)
+);
+
+-- the view applied to the task listings of a user called without parameters
+CREATE TABLE IF NOT EXISTS api.default_views
+(
+    user_id INT PRIMARY KEY REFERENCES auth.users (id),
+    view_id INT NOT NULL REFERENCES api.saved_views (id) ON DELETE CASCADE
+);
+
+-- the number of tasks of every status per primary assignee at the end of each day, taken by the snapshot job
+CREATE TABLE IF NOT EXISTS api.task_status_snapshots
+(
+    day     DATE            NOT NULL,
+    -- NULL for the backlog
+    user_id INT REFERENCES auth.users (id),
+    status  api.task_status NOT NULL,
+    count   INT             NOT NULL,
+    UNIQUE NULLS NOT DISTINCT (day, user_id, status)
+);
+
+-- the read model of /employer/tasks/summary: the counts of Summarize per assignee and project, kept up to date by the
+-- triggers below and rebuilt from scratch by the reconcile-summaries command
+CREATE TABLE IF NOT EXISTS api.task_summaries
+(
+    user_id          INT NOT NULL REFERENCES auth.users (id),
+    -- NULL for the tasks without a project
+    project_id       INT REFERENCES api.projects (id),
+    assigned         INT NOT NULL,
+    completed        INT NOT NULL,
+    primary_assigned INT NOT NULL,
+    shared           INT NOT NULL,
+    UNIQUE NULLS NOT DISTINCT (user_id, project_id)
+);
+
+-- counts the tasks of the users, of all users when NULL
+CREATE OR REPLACE FUNCTION api.compute_task_summaries(user_ids INT[])
+    RETURNS TABLE
+            (
+                user_id          INT,
+                project_id       INT,
+                assigned         INT,
+                completed        INT,
+                primary_assigned INT,
+                shared           INT
+            )
+AS
+$$
+SELECT assignees.user_id,
+       tasks.project_id,
+       COUNT(*)::int,
+       (COUNT(*) FILTER (WHERE tasks.status = 'COMPLETED'))::int,
+       (COUNT(*) FILTER (WHERE assignees.is_primary))::int,
+       (COUNT(*) FILTER (WHERE assignees.assignee_count > 1))::int
+FROM (SELECT all_assignees.task_id,
+             all_assignees.user_id,
+             all_assignees.is_primary,
+             COUNT(*) OVER (PARTITION BY all_assignees.task_id) AS assignee_count
+      FROM api.task_assignees all_assignees
+      WHERE user_ids IS NULL
+         OR all_assignees.task_id IN (SELECT user_tasks.task_id
+                                      FROM api.task_assignees user_tasks
+                                      WHERE user_tasks.user_id = ANY (user_ids))) assignees
+         JOIN api.tasks ON tasks.id = assignees.task_id
+WHERE user_ids IS NULL
+   OR assignees.user_id = ANY (user_ids)
+GROUP BY assignees.user_id, tasks.project_id;
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION api.refresh_task_summaries(user_ids INT[]) RETURNS VOID AS
+$$
+DECLARE
+    uid INT;
+BEGIN
+    -- serializes the refreshes of a user until commit, so that the counts of the last one include the changes of the
+    -- others instead of overwriting them
+    FOR uid IN SELECT DISTINCT unnest(user_ids) ORDER BY 1
+        LOOP
+            PERFORM pg_advisory_xact_lock(hashtext('api.task_summaries'), uid);
+        END LOOP;
+
+    INSERT INTO api.task_summaries (user_id, project_id, assigned, completed, primary_assigned, shared)
+    SELECT * FROM api.compute_task_summaries(user_ids)
+    ON CONFLICT (user_id, project_id) DO UPDATE SET assigned         = EXCLUDED.assigned,
+                                                    completed        = EXCLUDED.completed,
+                                                    primary_assigned = EXCLUDED.primary_assigned,
+                                                    shared           = EXCLUDED.shared;
+
+    DELETE
+    FROM api.task_summaries summaries
+    WHERE summaries.user_id = ANY (user_ids)
+      AND NOT EXISTS (SELECT 1
+                      FROM api.task_assignees
+                               JOIN api.tasks ON tasks.id = task_assignees.task_id
+                      WHERE task_assignees.user_id = summaries.user_id
+                        AND tasks.project_id IS NOT DISTINCT FROM summaries.project_id);
+END;
+$$ LANGUAGE plpgsql;
+
+-- the users whose summaries a transaction changed, refreshed all at once when it commits: taking the advisory locks of
+-- refresh_task_summaries in a single sorted call rules out deadlocks between transactions that would otherwise lock
+-- their users in the order of their statements
+CREATE UNLOGGED TABLE IF NOT EXISTS api.pending_task_summaries
+(
+    transaction_id XID8 NOT NULL DEFAULT pg_current_xact_id(),
+    user_id        INT  NOT NULL,
+    PRIMARY KEY (transaction_id, user_id)
+);
+
+CREATE OR REPLACE FUNCTION api.queue_task_summaries(user_ids INT[]) RETURNS VOID AS
+$$
+INSERT INTO api.pending_task_summaries (user_id)
+SELECT DISTINCT unnest(user_ids)
+ON CONFLICT DO NOTHING;
+$$ LANGUAGE sql;
+
+-- the first pending row of the transaction to be checked refreshes the users of all of them, the others find nothing
+-- left to refresh
+CREATE OR REPLACE FUNCTION api.refresh_pending_task_summaries() RETURNS TRIGGER AS
+$$
+DECLARE
+    user_ids INT[];
+BEGIN
+    WITH pending AS (
+        DELETE FROM api.pending_task_summaries
+            WHERE transaction_id = pg_current_xact_id()
+            RETURNING user_id)
+    SELECT array_agg(user_id)
+    INTO user_ids
+    FROM pending;
+    IF user_ids IS NOT NULL THEN
+        PERFORM api.refresh_task_summaries(user_ids);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER pending_task_summaries_refresh
+    AFTER INSERT
+    ON api.pending_task_summaries
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW
+EXECUTE FUNCTION api.refresh_pending_task_summaries();
+
+-- queues the users whose assignments changed along with the other assignees of their tasks, which may have become
+-- shared or no longer be
+CREATE OR REPLACE FUNCTION api.refresh_assignee_task_summaries() RETURNS TRIGGER AS
+$$
+DECLARE
+    task_ids INT[] := '{}';
+    user_ids INT[] := '{}';
+BEGIN
+    IF TG_OP <> 'DELETE' THEN
+        SELECT task_ids || array_agg(task_id), user_ids || array_agg(user_id)
+        INTO task_ids, user_ids
+        FROM new_assignees;
+    END IF;
+    IF TG_OP <> 'INSERT' THEN
+        SELECT task_ids || array_agg(task_id), user_ids || array_agg(user_id)
+        INTO task_ids, user_ids
+        FROM old_assignees;
+    END IF;
+    PERFORM api.queue_task_summaries(user_ids || ARRAY(SELECT user_id
+                                                       FROM api.task_assignees
+                                                       WHERE task_id = ANY (task_ids)));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER task_assignees_insert_summaries
+    AFTER INSERT
+    ON api.task_assignees
+    REFERENCING NEW TABLE AS new_assignees
+    FOR EACH STATEMENT
+EXECUTE FUNCTION api.refresh_assignee_task_summaries();
+
+CREATE TRIGGER task_assignees_update_summaries
+    AFTER UPDATE
+    ON api.task_assignees
+    REFERENCING OLD TABLE AS old_assignees NEW TABLE AS new_assignees
+    FOR EACH STATEMENT
+EXECUTE FUNCTION api.refresh_assignee_task_summaries();
+
+CREATE TRIGGER task_assignees_delete_summaries
+    AFTER DELETE
+    ON api.task_assignees
+    REFERENCING OLD TABLE AS old_assignees
+    FOR EACH STATEMENT
+EXECUTE FUNCTION api.refresh_assignee_task_summaries();
+
+-- queues the assignees of a task that was completed, reopened or moved to another project
+CREATE OR REPLACE FUNCTION api.refresh_task_task_summaries() RETURNS TRIGGER AS
+$$
+BEGIN
+    PERFORM api.queue_task_summaries(ARRAY(SELECT user_id FROM api.task_assignees WHERE task_id = NEW.id));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- the other updates of tasks, such as edits of the title or reassignments handled by the task_assignees triggers,
+-- do not fire it
+CREATE TRIGGER tasks_update_summaries
+    AFTER UPDATE OF status, project_id
+    ON api.tasks
+    FOR EACH ROW
+    WHEN (NEW.status IS DISTINCT FROM OLD.status OR NEW.project_id IS DISTINCT FROM OLD.project_id)
+EXECUTE FUNCTION api.refresh_task_task_summaries();
+
+-- the skills of employees, matched against task labels when assigning tasks automatically
+CREATE TABLE IF NOT EXISTS api.user_skills
+(
+    user_id INT  NOT NULL REFERENCES auth.users (id) ON DELETE CASCADE,
+    skill   TEXT NOT NULL,
+    PRIMARY KEY (user_id, skill)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_skills_skill ON api.user_skills (lower(skill));
+
+-- why tasks created with automatic assignment went to their assignee
+CREATE TABLE IF NOT EXISTS api.task_auto_assignments
+(
+    task_id    INT         PRIMARY KEY REFERENCES api.tasks (id) ON DELETE CASCADE,
+    user_id    INT         NOT NULL REFERENCES auth.users (id),
+    strategy   TEXT        NOT NULL,
+    reason     TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- periods employees are away, every day from starts_on to ends_on, or only the weekdays of part-time absences
+CREATE TABLE IF NOT EXISTS api.user_absences
+(
+    id             SERIAL PRIMARY KEY,
+    user_id        INT         NOT NULL REFERENCES auth.users (id) ON DELETE CASCADE,
+    kind           TEXT        NOT NULL CHECK (kind IN ('VACATION', 'SICK', 'PART_TIME')),
+    starts_on      DATE        NOT NULL,
+    ends_on        DATE        NOT NULL CHECK (ends_on >= starts_on),
+    -- days off of part-time absences, 0 being Sunday
+    weekdays       INT[]       NOT NULL DEFAULT '{}',
+    -- who takes the tasks of the user during the absence
+    backup_user_id INT REFERENCES auth.users (id) ON DELETE SET NULL,
+    note           TEXT        NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_absences_user_id ON api.user_absences (user_id, starts_on, ends_on);
+
+-- working hours and holidays that relative due dates and overdue tasks are computed with, in the time zone of the calendar
+CREATE TABLE IF NOT EXISTS api.work_calendars
+(
+    id         SERIAL PRIMARY KEY,
+    name       TEXT    NOT NULL UNIQUE,
+    time_zone  TEXT    NOT NULL DEFAULT 'UTC',
+    -- 0 being Sunday
+    workdays   INT[]   NOT NULL DEFAULT '{1,2,3,4,5}',
+    work_start TIME    NOT NULL DEFAULT '09:00',
+    work_end   TIME    NOT NULL DEFAULT '17:00' CHECK (work_end > work_start),
+    is_default BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_work_calendars_default ON api.work_calendars (is_default) WHERE is_default;
+
+CREATE TABLE IF NOT EXISTS api.holidays
+(
+    calendar_id INT  NOT NULL REFERENCES api.work_calendars (id) ON DELETE CASCADE,
+    day         DATE NOT NULL,
+    name        TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (calendar_id, day)
+);
@@ -11,8 +11,18 @@ CREATE TABLE IF NOT EXISTS users
     username   VARCHAR(255) NOT NULL UNIQUE,
     password   VARCHAR(255) NOT NULL,
     created_at TIMESTAMP DEFAULT NOW(),
-    role       auth.role    NOT NULL
+    role       auth.role    NOT NULL,
+    -- inactive users, e.g. employees who left, cannot sign in nor be assigned tasks
+    active     BOOLEAN      NOT NULL DEFAULT TRUE
 );
 
 CREATE INDEX IF NOT EXISTS idx_users_username ON users (username);
 CREATE INDEX IF NOT EXISTS idx_users_role ON users (role);
+
+-- a secret token per user for subscribing to their calendar feed without credentials, stored hashed
+CREATE TABLE IF NOT EXISTS calendar_tokens
+(
+    user_id    INT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    token_hash BYTEA       NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);